@@ -0,0 +1,81 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/wait"
+	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/component-base/cli/globalflag"
+
+	cloud_provider "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/cloud-provider"
+	"icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/webhook"
+)
+
+// newWebhookCommand returns the command serving the admission webhook of Services
+func newWebhookCommand() *cobra.Command {
+	var (
+		cloudConfig   string
+		bindAddress   string
+		port          int
+		certFile      string
+		keyFile       string
+		writeDefaults bool
+	)
+	command := &cobra.Command{
+		Use:   "webhook",
+		Short: "Serve the admission webhook validating annotations of Services",
+		Long: `webhook serves the validating and mutating admission webhooks of Services of type LoadBalancer.
+Services with invalid annotations are rejected by the checks of EnsureLoadBalancer, and the defaulted
+annotations are written back if --write-defaults is set.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cloud, err := cloudprovider.InitCloudProvider(cloud_provider.ProviderName, cloudConfig)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "init cloud provider failed: %v\n", err)
+				os.Exit(1)
+			}
+			admitter, ok := cloud.(webhook.Admitter)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "cloud provider %s does not support admission\n", cloud_provider.ProviderName)
+				os.Exit(1)
+			}
+			server := webhook.NewServer(admitter, writeDefaults)
+			if err := server.Run(net.JoinHostPort(bindAddress, strconv.Itoa(port)), certFile, keyFile, wait.NeverStop); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	fs := command.Flags()
+	fs.StringVar(&cloudConfig, "cloud-config", cloudConfig, "The path to the cloud provider configuration file, the same as the one of cloud controller manager.")
+	fs.StringVar(&bindAddress, "bind-address", "0.0.0.0", "The IP address on which to serve the webhook.")
+	fs.IntVar(&port, "port", 9443, "The port on which to serve the webhook by HTTPS.")
+	fs.StringVar(&certFile, "tls-cert-file", certFile, "File containing the x509 certificate for HTTPS.")
+	fs.StringVar(&keyFile, "tls-private-key-file", keyFile, "File containing the x509 private key matching --tls-cert-file.")
+	fs.BoolVar(&writeDefaults, "write-defaults", false, "Write annotations absent from Services with their default values, so the effective config is visible.")
+	globalflag.AddGlobalFlags(fs, command.Name())
+	command.MarkFlagRequired("cloud-config")
+	command.MarkFlagRequired("tls-cert-file")
+	command.MarkFlagRequired("tls-private-key-file")
+	return command
+}
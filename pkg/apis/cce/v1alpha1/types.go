@@ -0,0 +1,163 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// LoadBalancerConfig is the config of the BLB and EIP of services referring to it
+// by annotation service.beta.kubernetes.io/cce-load-balancer-config in the same namespace
+type LoadBalancerConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec LoadBalancerConfigSpec `json:"spec"`
+}
+
+// LoadBalancerConfigSpec is the spec of LoadBalancerConfig, settings not set fall back to annotations of service
+type LoadBalancerConfigSpec struct {
+	// Scheduler of TCP and UDP listeners: RoundRobin, LeastConnection or Hash
+	Scheduler string `json:"scheduler,omitempty"`
+	// HealthCheck of TCP and UDP listeners, replaces all health check annotations if set
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
+	// Listeners override settings of listeners by port
+	Listeners []Listener `json:"listeners,omitempty"`
+	// ElasticIP is the EIP created for the BLB, replaces all EIP annotations if set
+	ElasticIP *ElasticIP `json:"elasticIP,omitempty"`
+	// Backend is how backends of the BLB are selected, replaces all backend annotations if set
+	Backend *Backend `json:"backend,omitempty"`
+}
+
+// Listener overrides settings of the listeners of a port
+type Listener struct {
+	Port int32 `json:"port"`
+	// Protocol of the listener: TCP, UDP, HTTP or HTTPS, it must fit the protocol of the service port
+	Protocol string `json:"protocol,omitempty"`
+	// CertID is the certificate used by HTTPS listener
+	CertID string `json:"certID,omitempty"`
+	// Scheduler of TCP and UDP listener: RoundRobin, LeastConnection or Hash
+	Scheduler string `json:"scheduler,omitempty"`
+	// HealthCheck of TCP and UDP listener
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
+}
+
+// HealthCheck is the health check of TCP and UDP listeners
+type HealthCheck struct {
+	// TimeoutInSecond is in [1, 60], default 3
+	TimeoutInSecond int32 `json:"timeoutInSecond,omitempty"`
+	// IntervalInSecond is in [1, 10], default 3
+	IntervalInSecond int32 `json:"intervalInSecond,omitempty"`
+	// UnhealthyThreshold is in [2, 5], default 3
+	UnhealthyThreshold int32 `json:"unhealthyThreshold,omitempty"`
+	// HealthyThreshold is in [2, 5], default 3
+	HealthyThreshold int32 `json:"healthyThreshold,omitempty"`
+	// HealthCheckString is only used by UDP listener
+	HealthCheckString string `json:"healthCheckString,omitempty"`
+}
+
+// ElasticIP is the EIP created for the BLB
+type ElasticIP struct {
+	Name string `json:"name,omitempty"`
+	// PaymentTiming is Postpaid (default) or Prepaid
+	PaymentTiming string `json:"paymentTiming,omitempty"`
+	// BillingMethod of Postpaid EIP is ByTraffic (default) or ByBandwidth
+	BillingMethod     string `json:"billingMethod,omitempty"`
+	BandwidthInMbps   int32  `json:"bandwidthInMbps,omitempty"`
+	ReservationLength int32  `json:"reservationLength,omitempty"`
+	// BillingChangePolicy is how a change of payment timing or billing method is applied: reject (default), recreate or in-place
+	BillingChangePolicy string `json:"billingChangePolicy,omitempty"`
+	// Group is the name of EIP group sharing bandwidth, which the EIP is moved into
+	Group string `json:"group,omitempty"`
+	// RouteType is the line type of EIP, BGP (default) or BGP_S
+	RouteType string `json:"routeType,omitempty"`
+	// AutoRenewLength is the months a Prepaid EIP is renewed automatically by, 0 (default) is not renewed automatically
+	AutoRenewLength int32 `json:"autoRenewLength,omitempty"`
+	// Tags are the tags of EIP
+	Tags map[string]string `json:"tags,omitempty"`
+	// DriftPolicy is what to do when the EIP drifts from service out of band: report, rebind (default) or repair
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+}
+
+// Backend is how backends of the BLB are selected
+type Backend struct {
+	// Type is node (default) or pod
+	Type string `json:"type,omitempty"`
+	// NodeSelector restricts the nodes used as backends
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+	// RsMaxNum is the max num of backends, in (0, 50]
+	RsMaxNum int32 `json:"rsMaxNum,omitempty"`
+	// DrainPeriodInSecond is how long a backend leaving the BLB keeps weight 0 before removed, in [0, 3600]
+	DrainPeriodInSecond int32 `json:"drainPeriodInSecond,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// LoadBalancerConfigList is a list of LoadBalancerConfig
+type LoadBalancerConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []LoadBalancerConfig `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// EIPPool is a cluster-scoped pool of pre-purchased EIPs, services claim an EIP of it
+// by annotation service.beta.kubernetes.io/cce-elastic-ip-pool
+type EIPPool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   EIPPoolSpec   `json:"spec"`
+	Status EIPPoolStatus `json:"status,omitempty"`
+}
+
+// EIPPoolSpec is the spec of EIPPool
+type EIPPoolSpec struct {
+	// EIPs are the addresses of the EIPs in pool, which are neither created nor released by the cluster
+	EIPs []string `json:"eips"`
+}
+
+// EIPPoolStatus is the status of EIPPool
+type EIPPoolStatus struct {
+	// Claims are the EIPs in use by services, EIPs not claimed are free
+	Claims []EIPPoolClaim `json:"claims,omitempty"`
+}
+
+// EIPPoolClaim is an EIP claimed by a service
+type EIPPoolClaim struct {
+	EIP              string `json:"eip"`
+	ServiceNamespace string `json:"serviceNamespace"`
+	ServiceName      string `json:"serviceName"`
+	ServiceUID       string `json:"serviceUID"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// EIPPoolList is a list of EIPPool
+type EIPPoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []EIPPool `json:"items"`
+}
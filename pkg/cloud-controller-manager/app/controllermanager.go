@@ -269,7 +269,10 @@ func KnownControllers() []string {
 }
 
 // ControllersDisabledByDefault is the controller disabled default when starting cloud-controller managers.
-var ControllersDisabledByDefault = sets.NewString()
+// loadbalancer-gc deletes cloud resources, so it must be enabled explicitly.
+var ControllersDisabledByDefault = sets.NewString(
+	"loadbalancer-gc",
+)
 
 // newControllerInitializers is a private map of named controller groups (you can start more than one in an init func)
 // paired to their initFunc.  This allows for structured downstream composition and subdivision.
@@ -279,5 +282,6 @@ func newControllerInitializers() map[string]initFunc {
 	controllers["cloud-node-lifecycle"] = startCloudNodeLifecycleController
 	controllers["service"] = startServiceController
 	controllers["route"] = startRouteController
+	controllers["loadbalancer-gc"] = startLoadBalancerGCController
 	return controllers
 }
@@ -38,6 +38,7 @@ import (
 	kubefeatures "k8s.io/kubernetes/pkg/features"
 
 	cloudcontrollers "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/cloud"
+	gccontroller "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/gc"
 	routecontroller "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/route"
 	servicecontroller "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/service"
 )
@@ -141,6 +142,26 @@ func startRouteController(ctx *cloudcontrollerconfig.CompletedConfig, cloud clou
 	return nil, true, nil
 }
 
+func startLoadBalancerGCController(ctx *cloudcontrollerconfig.CompletedConfig, cloud cloudprovider.Interface, stopCh <-chan struct{}) (http.Handler, bool, error) {
+	gcCloud, ok := cloud.(gccontroller.Cloud)
+	if !ok {
+		klog.Warning("loadbalancer-gc is enabled, but cloud provider does not support collecting orphaned load balancer resources.")
+		return nil, false, nil
+	}
+
+	gc, err := gccontroller.New(
+		gcCloud,
+		ctx.ClientBuilder.ClientOrDie("loadbalancer-gc-controller"),
+		ctx.SharedInformers.Core().V1().Services(),
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	go gc.Run(stopCh)
+
+	return nil, true, nil
+}
+
 // processCIDRs is a helper function that works on a comma separated cidrs and returns
 // a list of typed cidrs
 // a flag if cidrs represents a dual stack
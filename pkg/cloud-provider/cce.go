@@ -22,17 +22,22 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"reflect"
+	"sync"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
@@ -44,7 +49,11 @@ import (
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/vpc"
+	cce_v1alpha1 "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/apis/cce/v1alpha1"
+	tempblb "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
 	cce "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-cce"
+	tempeip "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-eip"
+	tempvpc "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-vpc"
 )
 
 // ProviderName is the name of this cloud provider.
@@ -69,10 +78,21 @@ type Baiducloud struct {
 	CloudConfig
 	clientSet        *ClientSet
 	kubeClient       kubernetes.Interface
+	dynamicClient    dynamic.Interface
 	eventBroadcaster record.EventBroadcaster
 	eventRecorder    record.EventRecorder
 	// services that need to be synced
 	svcQueue workqueue.RateLimitingInterface
+	// lister of services, set by SetInformers
+	serviceLister corelisters.ServiceLister
+	// lister of endpoints of services with pod backends, set by SetInformers
+	endpointsLister corelisters.EndpointsLister
+	// lister of LoadBalancerConfigs, set by Initialize
+	loadBalancerConfigLister cache.GenericLister
+	// keys of services whose EIP drift waits to be repaired by svcQueue
+	eipDriftServices sync.Map
+	// serializes creating EIP groups, whose names are unique among services
+	eipGroupLock sync.Mutex
 }
 
 // CloudConfig is the cloud config
@@ -88,6 +108,17 @@ type CloudConfig struct {
 	Endpoint        string `json:"Endpoint"`
 	NodeName        string `json:"NodeName"`
 	Debug           bool   `json:"Debug"`
+	// LoadBalancerGCMode is the mode of loadbalancer-gc controller: report-only (default), dry-run or delete
+	LoadBalancerGCMode string `json:"LoadBalancerGCMode"`
+	// LoadBalancerGCGracePeriodInSecond is how long a BLB or EIP stays orphaned before it is deleted, default is 3600
+	LoadBalancerGCGracePeriodInSecond int `json:"LoadBalancerGCGracePeriodInSecond"`
+	// LoadBalancerGCCollectBLBs makes loadbalancer-gc collect BLBs too, BLBs kept by reserve-lb before their desc
+	// was changed can not be told from orphaned ones, so only EIPs are collected by default
+	LoadBalancerGCCollectBLBs bool `json:"LoadBalancerGCCollectBLBs"`
+	// ReservedEIPTTLInSecond is how long an EIP kept by reserve-eip stays unbound before it is released, default is 604800
+	ReservedEIPTTLInSecond int `json:"ReservedEIPTTLInSecond"`
+	// EIPDriftCheckPeriodInSecond is the interval of checking EIPs of services drifted out of band, default is 300
+	EIPDriftCheckPeriodInSecond int `json:"EIPDriftCheckPeriodInSecond"`
 }
 
 // CCMVersion is the version of CCM
@@ -134,12 +165,18 @@ func (bc *Baiducloud) ProviderName() string {
 // to perform housekeeping activities within the cloud provider.
 func (bc *Baiducloud) Initialize(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
 	bc.kubeClient = clientBuilder.ClientOrDie(ProviderName)
+	bc.dynamicClient = dynamic.NewForConfigOrDie(clientBuilder.ConfigOrDie(ProviderName))
 	bc.eventBroadcaster = record.NewBroadcaster()
 	bc.eventBroadcaster.StartLogging(klog.Infof)
 	bc.eventBroadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: bc.kubeClient.CoreV1().Events("")})
 	bc.eventRecorder = bc.eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "CCM"})
 	bc.svcQueue = workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(minRetryDelay, maxRetryDelay), "endpoints")
 	bc.runServiceWorker()
+	dynamicInformerFactory := dynamicinformer.NewDynamicSharedInformerFactory(bc.dynamicClient, 0)
+	bc.setLoadBalancerConfigInformer(dynamicInformerFactory.ForResource(cce_v1alpha1.LoadBalancerConfigResource))
+	dynamicInformerFactory.Start(stop)
+	go wait.Until(bc.releaseExpiredReservedEIPs, reservedEIPReleasePeriod, stop)
+	go wait.Until(bc.reconcileEIPDrift, bc.eipDriftCheckPeriod(), stop)
 }
 
 // SetInformers sets the informer on the cloud object.
@@ -183,72 +220,76 @@ func (bc *Baiducloud) SetInformers(informerFactory informers.SharedInformerFacto
 		},
 	})
 
-	// endpoints
-	podInformer := informerFactory.Core().V1().Pods().Informer()
-	podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+	// endpoints of services with pod backends
+	bc.serviceLister = informerFactory.Core().V1().Services().Lister()
+	bc.endpointsLister = informerFactory.Core().V1().Endpoints().Lister()
+	endpointsInformer := informerFactory.Core().V1().Endpoints().Informer()
+	endpointsInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			bc.enqueuePodBackendService(bc.serviceLister, obj.(*v1.Endpoints))
+		},
 		UpdateFunc: func(oldObj, newObj interface{}) {
-			pod := newObj.(*v1.Pod)
-
-			if len(pod.Status.PodIP) == 0 {
-				return
-			}
-
-			svcs, err := bc.kubeClient.CoreV1().Services(pod.Namespace).List(metav1.ListOptions{})
-			if err != nil {
-				klog.Errorf("podInformer failed to get service of pod %s/%s: %s", pod.Namespace, pod.Name, err)
+			oldEp, newEp := oldObj.(*v1.Endpoints), newObj.(*v1.Endpoints)
+			if reflect.DeepEqual(oldEp.Subsets, newEp.Subsets) {
 				return
 			}
-			for _, svc := range svcs.Items {
-				if svc.Spec.Selector == nil {
-					continue
-				}
-				if svc.Spec.ExternalTrafficPolicy != v1.ServiceExternalTrafficPolicyTypeLocal {
-					klog.Infof("Service %s/%s is not externalTrafficPolicy Local, skipping update rs", svc.Namespace, svc.Name)
-					continue
-				}
-				selector := labels.Set(svc.Spec.Selector).AsSelectorPreValidated()
-				if selector.Matches(labels.Set(pod.Labels)) {
-					key := fmt.Sprintf("%s/%s", svc.Namespace, svc.Name)
-					bc.svcQueue.AddRateLimited(key)
-				}
-			}
+			bc.enqueuePodBackendService(bc.serviceLister, newEp)
 		},
 		DeleteFunc: func(obj interface{}) {
-			pod := obj.(*v1.Pod)
-
-			if len(pod.Status.PodIP) == 0 {
-				return
-			}
-
-			svcs, err := bc.kubeClient.CoreV1().Services(pod.Namespace).List(metav1.ListOptions{})
-			if err != nil {
-				klog.Errorf("podInformer failed to get service of pod %s/%s: %s", pod.Namespace, pod.Name, err)
-				return
-			}
-			for _, svc := range svcs.Items {
-				if svc.Spec.Selector == nil {
-					continue
-				}
-				if svc.Spec.ExternalTrafficPolicy != v1.ServiceExternalTrafficPolicyTypeLocal {
-					klog.Infof("Service %s/%s is not externalTrafficPolicy Local, skipping update rs", svc.Namespace, svc.Name)
-					continue
+			ep, ok := obj.(*v1.Endpoints)
+			if !ok {
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					return
 				}
-				selector := labels.Set(svc.Spec.Selector).AsSelectorPreValidated()
-				if selector.Matches(labels.Set(pod.Labels)) {
-					key := fmt.Sprintf("%s/%s", svc.Namespace, svc.Name)
-					bc.svcQueue.AddRateLimited(key)
+				if ep, ok = tombstone.Obj.(*v1.Endpoints); !ok {
+					return
 				}
 			}
+			bc.enqueuePodBackendService(bc.serviceLister, ep)
 		},
 	})
 }
 
+// enqueuePodBackendService queues the service of ep if it uses pods as backends
+func (bc *Baiducloud) enqueuePodBackendService(serviceLister corelisters.ServiceLister, ep *v1.Endpoints) {
+	svc, err := serviceLister.Services(ep.Namespace).Get(ep.Name)
+	if errors.IsNotFound(err) {
+		return
+	}
+	if err != nil {
+		klog.Errorf("endpointsInformer failed to get service of endpoints %s/%s: %s", ep.Namespace, ep.Name, err)
+		return
+	}
+	if svc.Spec.Type != v1.ServiceTypeLoadBalancer || !bc.isPodBackend(svc) {
+		return
+	}
+	key := fmt.Sprintf("%s/%s", svc.Namespace, svc.Name)
+	bc.svcQueue.AddRateLimited(key)
+}
+
 // ClientSet contains all the bce product client
 type ClientSet struct {
 	BLBClient blb.Interface
 	EIPClient eip.Interface
 	CCEClient cce.Interface
 	VPCClient vpc.Interface
+	// EIPGroupClient manages EIP groups sharing bandwidth, which are not supported by EIPClient
+	EIPGroupClient tempeip.Interface
+	// EIPTagClient manages tags of EIPs, which are not supported by EIPClient
+	EIPTagClient tempeip.TagInterface
+	// EIPOptionClient manages route type, auto-renew and billing of EIPs, which are not supported by EIPClient
+	EIPOptionClient tempeip.OptionInterface
+	// BLBAddressClient creates BLBs with a specified private address and describes BLBs with their VPC, which are not supported by BLBClient
+	BLBAddressClient tempblb.Interface
+	// BLBListenerClient manages HTTP and HTTPS listeners checking health of a specified port, which are not supported by BLBClient
+	BLBListenerClient tempblb.ListenerInterface
+	// BLBSecurityGroupClient binds security groups to BLBs, which is not supported by BLBClient
+	BLBSecurityGroupClient tempblb.SecurityGroupInterface
+	// BLBBackendIPClient registers pod ips to listeners of BLBs, which is not supported by BLBClient
+	BLBBackendIPClient tempblb.BackendIPInterface
+	// VPCSecurityGroupClient manages security groups and their rules, which are not supported by VPCClient
+	VPCSecurityGroupClient tempvpc.SecurityGroupInterface
 }
 
 func newClientSet(config *CloudConfig) (*ClientSet, error) {
@@ -273,6 +314,22 @@ func newClientSet(config *CloudConfig) (*ClientSet, error) {
 	})
 	clientset.BLBClient = lbClient
 
+	// BLBAddressClient, BLBListenerClient, BLBSecurityGroupClient and BLBBackendIPClient
+	tempLbClient := tempblb.NewClient(&tempblb.Config{
+		Config: &bcesdk.Config{
+			Credentials: bcesdk.NewCredentials(config.AccessKeyID, config.SecretAccessKey),
+			Checksum:    true,
+			Timeout:     30 * time.Second,
+			Region:      config.Region,
+			Endpoint:    blb.Endpoint[config.Region],
+			UserAgent:   fmt.Sprintf("%s:%s", CCEUserAgent, config.ClusterID),
+		},
+	})
+	clientset.BLBAddressClient = tempLbClient
+	clientset.BLBListenerClient = tempLbClient
+	clientset.BLBSecurityGroupClient = tempLbClient
+	clientset.BLBBackendIPClient = tempLbClient
+
 	// EIPClient
 	eipClient := eip.NewClient(&eip.Config{
 		Config: &bcesdk.Config{
@@ -287,6 +344,22 @@ func newClientSet(config *CloudConfig) (*ClientSet, error) {
 	})
 	clientset.EIPClient = eipClient
 
+	// EIPGroupClient
+	eipGroupClient := tempeip.NewClient(&tempeip.Config{
+		Config: &bcesdk.Config{
+			Credentials: bcesdk.NewCredentials(config.AccessKeyID, config.SecretAccessKey),
+			Checksum:    true,
+			Timeout:     30 * time.Second,
+			Region:      config.Region,
+			Endpoint:    eip.Endpoint[config.Region],
+			ProxyHost:   proxyHost,
+			ProxyPort:   proxyPort,
+		},
+	})
+	clientset.EIPGroupClient = eipGroupClient
+	clientset.EIPTagClient = eipGroupClient
+	clientset.EIPOptionClient = eipGroupClient
+
 	// CCEClient request Internal API
 	cceClient := cce.NewClient(&cce.Config{
 		Config: &bcesdk.Config{
@@ -316,15 +389,32 @@ func newClientSet(config *CloudConfig) (*ClientSet, error) {
 	})
 	clientset.VPCClient = vpcClient
 
+	// VPCSecurityGroupClient
+	sgClient := tempvpc.NewClient(&tempvpc.Config{
+		Config: &bcesdk.Config{
+			Credentials: bcesdk.NewCredentials(config.AccessKeyID, config.SecretAccessKey),
+			Checksum:    true,
+			Timeout:     30 * time.Second,
+			Region:      config.Region,
+			Endpoint:    tempvpc.Endpoint[config.Region],
+			ProxyHost:   proxyHost,
+			ProxyPort:   proxyPort,
+		},
+	})
+	clientset.VPCSecurityGroupClient = sgClient
+
 	// Set Debug
 	config.Debug = true
 	if config.Debug == true {
 		klog.Info("cce-ingresss-controller set debug = true")
 	}
 	lbClient.SetDebug(config.Debug)
+	tempLbClient.SetDebug(config.Debug)
 	eipClient.SetDebug(config.Debug)
+	eipGroupClient.SetDebug(config.Debug)
 	cceClient.SetDebug(config.Debug)
 	vpcClient.SetDebug(config.Debug)
+	sgClient.SetDebug(config.Debug)
 
 	return clientset, nil
 }
@@ -377,7 +467,7 @@ func (bc *Baiducloud) processNextService() bool {
 		return false
 	}
 	defer bc.svcQueue.Done(key)
-	klog.Infof(Message(ctx, fmt.Sprintf("Pod changed, begin reconcile backend server for service %s", key)))
+	klog.Infof(Message(ctx, fmt.Sprintf("Begin reconcile backend server for service %s", key)))
 
 	err := func() error {
 		namespace, name, err := cache.SplitMetaNamespaceKey(key.(string))
@@ -386,9 +476,20 @@ func (bc *Baiducloud) processNextService() bool {
 			return err
 		}
 		service, err := bc.kubeClient.CoreV1().Services(namespace).Get(name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			bc.eipDriftServices.Delete(key)
+			return nil
+		}
 		if err != nil {
 			return err
 		}
+		if err := bc.repairEIPDrift(ctx, service); err != nil {
+			return err
+		}
+		// services without pod backends are only queued to finish draining rs, their nodes are synced by service controller
+		if !bc.isPodBackend(service) {
+			return bc.finishDrainingBackendServers(ctx, bc.ClusterName, service)
+		}
 		nodes := make([]*v1.Node, 0)
 		return bc.reconcileBackendServers(ctx, bc.ClusterName, service, nodes)
 	}()
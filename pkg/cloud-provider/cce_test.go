@@ -5,15 +5,27 @@ import (
 )
 
 func NewFakeCloud(clusterID string) *Baiducloud {
+	eipClient := fake.NewEipFakeClient()
+	eipTagClient := fake.NewEipTagFakeClient(eipClient)
+	blbClient := fake.NewBlbFakeClient()
+	vpcClient := fake.NewVpcFakeClient()
 	return &Baiducloud{
 		CloudConfig: CloudConfig{
 			ClusterID: clusterID,
 		},
 		clientSet: &ClientSet{
-			BLBClient: fake.NewBlbFakeClient(),
-			VPCClient: fake.NewVpcFakeClient(),
-			CCEClient: fake.NewCceFakeClient(),
-			EIPClient: fake.NewEipFakeClient(),
+			BLBClient:              blbClient,
+			VPCClient:              vpcClient,
+			CCEClient:              fake.NewCceFakeClient(),
+			EIPClient:              eipClient,
+			EIPGroupClient:         fake.NewEipGroupFakeClient(eipClient),
+			EIPTagClient:           eipTagClient,
+			EIPOptionClient:        fake.NewEipOptionFakeClient(eipClient, eipTagClient),
+			BLBAddressClient:       fake.NewBlbAddressFakeClient(blbClient),
+			BLBListenerClient:      fake.NewBlbListenerFakeClient(blbClient),
+			BLBSecurityGroupClient: fake.NewBlbSecurityGroupFakeClient(blbClient),
+			BLBBackendIPClient:     fake.NewBlbBackendIPFakeClient(blbClient),
+			VPCSecurityGroupClient: fake.NewVpcSecurityGroupFakeClient(vpcClient),
 		},
 	}
 }
@@ -190,6 +190,19 @@ func Dec(IP net.IP) net.IP {
 	return decIP
 }
 
+// ParseIPv4CIDR parses s as an IPv4 CIDR and returns the network it denotes,
+// e.g. "10.0.0.1/8" becomes 10.0.0.0/8 .
+func ParseIPv4CIDR(s string) (*net.IPNet, error) {
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(checkIPv4(network.IP)) != net.IPv4len {
+		return nil, fmt.Errorf("%s is not an IPv4 CIDR", s)
+	}
+	return network, nil
+}
+
 func checkIPv4(ip net.IP) net.IP {
 	// Go for some reason allocs IPv6len for IPv4 so we have to correct it
 	if v4 := ip.To4(); v4 != nil {
@@ -41,3 +41,18 @@ func TestInsertNumIntoIP(t *testing.T) {
 		t.Error("err")
 	}
 }
+
+func TestParseIPv4CIDR(t *testing.T) {
+	network, err := ParseIPv4CIDR("10.0.0.1/8")
+	if err != nil {
+		t.Error(err)
+	}
+	if network.String() != "10.0.0.0/8" {
+		t.Errorf("ParseIPv4CIDR should normalize network, get %s", network.String())
+	}
+	for _, s := range []string{"10.0.0.1", "10.0.0.0/33", "abc", "fd00::/64"} {
+		if _, err := ParseIPv4CIDR(s); err == nil {
+			t.Errorf("ParseIPv4CIDR %s should fail", s)
+		}
+	}
+}
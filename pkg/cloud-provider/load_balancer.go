@@ -25,6 +25,8 @@ import (
 	"k8s.io/klog"
 	v1 "k8s.io/api/core/v1"
 	cloudprovider "k8s.io/cloud-provider"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
 )
 
 // LoadBalancer returns a balancer interface. Also returns true if the interface is supported, false otherwise.
@@ -48,14 +50,24 @@ func (bc *Baiducloud) GetLoadBalancer(ctx context.Context, clusterName string, s
 	}
 
 	var ip string
-	if internalVpc, ok := service.Annotations[ServiceAnnotationLoadBalancerInternalVpc]; ok && internalVpc == "true" {
+	if isInternalVpcExposure(service, lb) {
 		ip = lb.Address // internal vpc ip
 	} else {
 		ip = lb.PublicIp // EIP
 	}
 	klog.V(3).Infof("[%v %v] GetLoadBalancer ip: %s", service.Namespace, service.Name, ip)
 
-	return &v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: ip}}}, true, nil
+	return getLoadBalancerStatus(service, lb, ip), true, nil
+}
+
+// getLoadBalancerStatus returns the status with ip. The VPC address of lb is published after the EIP if
+// publish-vpc-address is set, so Ingress[0] is always the address used before.
+func getLoadBalancerStatus(service *v1.Service, lb *blb.LoadBalancer, ip string) *v1.LoadBalancerStatus {
+	ingress := []v1.LoadBalancerIngress{{IP: ip}}
+	if service.Annotations[ServiceAnnotationLoadBalancerPublishVpcAddress] == "true" && len(lb.Address) != 0 && lb.Address != ip {
+		ingress = append(ingress, v1.LoadBalancerIngress{IP: lb.Address})
+	}
+	return &v1.LoadBalancerStatus{Ingress: ingress}
 }
 
 // GetLoadBalancerName returns the name of the load balancer. Implementations must treat the
@@ -79,11 +91,20 @@ func (bc *Baiducloud) EnsureLoadBalancer(ctx context.Context, clusterName string
 	ctx = context.WithValue(ctx, RequestID, GetRandom())
 	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
 	klog.Infof(Message(ctx, fmt.Sprintf("EnsureLoadBalancer for service %s", serviceKey)))
+	// annotations are validated before any cloud resource is changed
 	err := bc.validateService(service)
 	if err != nil {
 		return nil, err
 	}
 
+	status, err := bc.ensureLoadBalancer(ctx, clusterName, service, nodes)
+	if err != nil && shouldRollback(service, err) {
+		bc.rollbackLoadBalancer(ctx, clusterName, service, err)
+	}
+	return status, err
+}
+
+func (bc *Baiducloud) ensureLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) (*v1.LoadBalancerStatus, error) {
 	// ensure BLB
 	lb, err := bc.ensureBLB(ctx, clusterName, service)
 	if err != nil {
@@ -100,9 +121,24 @@ func (bc *Baiducloud) EnsureLoadBalancer(ctx context.Context, clusterName string
 		return nil, err
 	}
 
+	err = bc.reconcileSourceRanges(ctx, service, lb)
+	if err != nil {
+		return nil, err
+	}
+
+	// EIP is released or ensured when internal-vpc is changed
+	err = bc.ensureExposureMode(ctx, service, lb)
+	if err != nil {
+		return nil, err
+	}
+
 	var pubIP string
-	if internalIP, ok := service.Annotations[ServiceAnnotationLoadBalancerInternalVpc]; ok && internalIP == "true" {
+	if isInternalVpcService(service) {
 		pubIP = lb.Address
+		// VPC address of an existing BLB can not be changed
+		if vip := getInternalVIP(service); len(vip) != 0 && vip != pubIP {
+			bc.eventRecorder.Eventf(service, v1.EventTypeWarning, "InternalVIPMismatch", "BLB %s uses VPC address %s instead of %s, recreate Service to change it", lb.BlbId, pubIP, vip)
+		}
 		klog.Infof(Message(ctx, fmt.Sprintf("EnsureLoadBalancer for service %s/%s: use LoadBalancerInternalVpc ip %s", service.Namespace, service.Name, pubIP)))
 	} else {
 		// ensure EIP
@@ -116,7 +152,10 @@ func (bc *Baiducloud) EnsureLoadBalancer(ctx context.Context, clusterName string
 		service.Annotations[ServiceAnnotationCceAutoAddEip] = pubIP
 		klog.Infof(Message(ctx, fmt.Sprintf("EnsureLoadBalancer for service %s/%s: use EIP %s", service.Namespace, service.Name, pubIP)))
 	}
-	return &v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: pubIP}}}, nil
+	if len(lb.Address) != 0 {
+		service.Annotations[ServiceAnnotationCceAutoAddVpcAddress] = lb.Address
+	}
+	return getLoadBalancerStatus(service, lb, pubIP), nil
 }
 
 // UpdateLoadBalancer updates hosts under the specified load balancer.
@@ -158,7 +197,8 @@ func (bc *Baiducloud) EnsureLoadBalancerDeleted(ctx context.Context, clusterName
 		klog.Info(Message(ctx, msg))
 	}
 
-	if internalIP, ok := service.Annotations[ServiceAnnotationLoadBalancerInternalVpc]; !ok || internalIP != "true" {
+	// EIP is deleted by the exposure mode provisioned, the annotation may be changed before deletion
+	if !isInternalVpcExposure(service, lb) {
 		err = bc.ensureEipDeleted(ctx, service, lb)
 		if err != nil {
 			return err
@@ -166,10 +206,19 @@ func (bc *Baiducloud) EnsureLoadBalancerDeleted(ctx context.Context, clusterName
 	}
 
 	if reserveLB, ok := service.Annotations[ServiceAnnotationLoadBalancerReserveLB]; !ok || reserveLB != "true" {
+		err = bc.ensureSourceRangesDeleted(ctx, service, lb)
+		if err != nil {
+			return err
+		}
 		err = bc.ensureBLBDeleted(ctx, lb)
 		if err != nil {
 			return err
 		}
+	} else if exist {
+		err = bc.markBLBReserved(ctx, lb)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -0,0 +1,160 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+
+	"icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/webhook"
+)
+
+var _ webhook.Admitter = &Baiducloud{}
+
+// ValidateService validates annotations of service at admission with the checks of EnsureLoadBalancer.
+// A service referring to LoadBalancerConfig only gets annotations checked one by one,
+// since the config may be created after the service and it is validated by its CRD.
+// Services being deleted and updates changing neither annotations nor spec are not validated,
+// so that e.g. finalizers and status of a service admitted before are never blocked.
+func (bc *Baiducloud) ValidateService(ctx context.Context, service, oldService *v1.Service) error {
+	if service.Spec.Type != v1.ServiceTypeLoadBalancer || service.DeletionTimestamp != nil {
+		return nil
+	}
+	if oldService != nil && reflect.DeepEqual(service.Annotations, oldService.Annotations) && reflect.DeepEqual(service.Spec, oldService.Spec) {
+		return nil
+	}
+	ctx = context.WithValue(ctx, RequestID, GetRandom())
+	anno, err := ExtractServiceAnnotation(service)
+	if err != nil {
+		return err
+	}
+	if service.Annotations[ServiceAnnotationLoadBalancerConfig] != "" {
+		return nil
+	}
+	if err := bc.validateServiceAnnotation(service, anno); err != nil {
+		return err
+	}
+	if anno.LoadBalancerSubnetID != "" {
+		subnetIsTypeBCC, err := bc.subnetIsTypeBCC(ctx, anno.LoadBalancerSubnetID)
+		if err != nil {
+			// services are not blocked by errors of BCE, EnsureLoadBalancer checks the subnet again
+			klog.Warningf(Message(ctx, fmt.Sprintf("check subnet %s of service %s/%s failed: %v", anno.LoadBalancerSubnetID, service.Namespace, service.Name, err)))
+			return nil
+		}
+		if !subnetIsTypeBCC {
+			return fmt.Errorf("SubnetId %v in annotation %s is not type BCC", anno.LoadBalancerSubnetID, ServiceAnnotationLoadBalancerSubnetID)
+		}
+	}
+	return nil
+}
+
+// DefaultService returns annotations of service with the absent ones written with default values, and
+// their keys recorded in annotation ServiceAnnotationLoadBalancerDefaulted. Annotations defaulted before
+// are defaulted again unless they are changed by user, e.g. bandwidth follows billing method.
+func (bc *Baiducloud) DefaultService(service, oldService *v1.Service) (map[string]string, error) {
+	// defaults of a service referring to LoadBalancerConfig depend on the config
+	if service.Spec.Type != v1.ServiceTypeLoadBalancer || service.Annotations[ServiceAnnotationLoadBalancerConfig] != "" {
+		return nil, nil
+	}
+	var oldAnnotations map[string]string
+	if oldService != nil {
+		oldAnnotations = oldService.Annotations
+	}
+	annotations := make(map[string]string, len(service.Annotations))
+	for k, v := range service.Annotations {
+		annotations[k] = v
+	}
+	for _, key := range strings.Split(annotations[ServiceAnnotationLoadBalancerDefaulted], ",") {
+		if value, ok := annotations[key]; ok && value == oldAnnotations[key] {
+			delete(annotations, key)
+		}
+	}
+	delete(annotations, ServiceAnnotationLoadBalancerDefaulted)
+
+	svc := service.DeepCopy()
+	svc.Annotations = annotations
+	defaults, err := bc.getDefaultAnnotations(svc)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for k, v := range defaults {
+		annotations[k] = v
+		keys = append(keys, k)
+	}
+	if len(keys) != 0 {
+		sort.Strings(keys)
+		annotations[ServiceAnnotationLoadBalancerDefaulted] = strings.Join(keys, ",")
+	}
+	if reflect.DeepEqual(annotations, service.Annotations) || (len(annotations) == 0 && len(service.Annotations) == 0) {
+		return nil, nil
+	}
+	return annotations, nil
+}
+
+// getDefaultAnnotations returns the default values of annotations absent from service
+func (bc *Baiducloud) getDefaultAnnotations(service *v1.Service) (map[string]string, error) {
+	anno, err := ExtractServiceAnnotation(service)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string)
+	setDefault := func(key, value string) {
+		if _, ok := service.Annotations[key]; !ok {
+			result[key] = value
+		}
+	}
+	setDefault(ServiceAnnotationLoadBalancerBackendType, BackendTypeNode)
+	setDefault(ServiceAnnotationLoadBalancerRollbackPolicy, RollbackPolicyRetain)
+	// ClientIP session affinity uses Hash
+	if service.Spec.SessionAffinity != v1.ServiceAffinityClientIP {
+		setDefault(ServiceAnnotationLoadBalancerScheduler, defaultBLBScheduler)
+	}
+	setDefault(ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond, strconv.Itoa(defaultBLBHealthCheckTimeoutInSecond))
+	setDefault(ServiceAnnotationLoadBalancerHealthCheckInterval, strconv.Itoa(defaultBLBHealthCheckInterval))
+	setDefault(ServiceAnnotationLoadBalancerUnhealthyThreshold, strconv.Itoa(defaultBLBUnhealthyThreshold))
+	setDefault(ServiceAnnotationLoadBalancerHealthyThreshold, strconv.Itoa(defaultBLBHealthyThreshold))
+	for _, port := range service.Spec.Ports {
+		if port.Protocol == v1.ProtocolUDP {
+			setDefault(ServiceAnnotationLoadBalancerHealthCheckString, defaultBLBHealthCheckString)
+		}
+	}
+
+	// EIP is created by annotations only if it is not internal, loadBalancerIP and EIP pool are not set
+	// billing of EIP in group is that of the group, the defaults of standalone EIP do not fit
+	if anno.LoadBalancerInternalVpc != "true" && service.Spec.LoadBalancerIP == "" && anno.ElasticIPPool == "" && anno.ElasticIPGroup == "" {
+		args, err := bc.getEipArgsFromAnnotation(anno)
+		if err != nil {
+			return nil, err
+		}
+		setDefault(ServiceAnnotationElasticIPPaymentTiming, args.Billing.PaymentTiming)
+		// Prepaid EIP does not accept billing method
+		if args.Billing.BillingMethod != "" {
+			setDefault(ServiceAnnotationElasticIPBillingMethod, args.Billing.BillingMethod)
+		}
+		setDefault(ServiceAnnotationElasticIPBandwidthInMbps, strconv.Itoa(args.BandwidthInMbps))
+		setDefault(ServiceAnnotationElasticIPRouteType, EIPRouteTypeBGP)
+	}
+	return result, nil
+}
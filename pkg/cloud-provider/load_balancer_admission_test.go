@@ -0,0 +1,139 @@
+package cloud_provider
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/vpc"
+	api "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateServiceAtAdmission(t *testing.T) {
+	ctx := context.Background()
+	cloud := NewFakeCloud("c-admission")
+	bccSubnet, _ := cloud.clientSet.VPCClient.CreateSubnet(ctx, &vpc.CreateSubnetArgs{Name: "bcc", SubnetType: "BCC"}, nil)
+	bbcSubnet, _ := cloud.clientSet.VPCClient.CreateSubnet(ctx, &vpc.CreateSubnetArgs{Name: "bbc", SubnetType: "BBC"}, nil)
+
+	cases := []struct {
+		name        string
+		serviceType api.ServiceType
+		annotations map[string]string
+		valid       bool
+	}{
+		{"default", api.ServiceTypeLoadBalancer, nil, true},
+		{"not LoadBalancer", api.ServiceTypeClusterIP, map[string]string{ServiceAnnotationLoadBalancerRsMaxNum: "100"}, true},
+		{"bad rs-max-num", api.ServiceTypeLoadBalancer, map[string]string{ServiceAnnotationLoadBalancerRsMaxNum: "100"}, false},
+		{"Prepaid with billing method", api.ServiceTypeLoadBalancer, map[string]string{
+			ServiceAnnotationElasticIPPaymentTiming:     "Prepaid",
+			ServiceAnnotationElasticIPBillingMethod:     "ByTraffic",
+			ServiceAnnotationElasticIPReservationLength: "1",
+		}, false},
+		{"BCC subnet", api.ServiceTypeLoadBalancer, map[string]string{ServiceAnnotationLoadBalancerSubnetID: bccSubnet}, true},
+		{"BBC subnet", api.ServiceTypeLoadBalancer, map[string]string{ServiceAnnotationLoadBalancerSubnetID: bbcSubnet}, false},
+		// EnsureLoadBalancer checks the subnet again
+		{"unknown subnet", api.ServiceTypeLoadBalancer, map[string]string{ServiceAnnotationLoadBalancerSubnetID: "sbn-unknown"}, true},
+		{"HTTPS with cert in LoadBalancerConfig", api.ServiceTypeLoadBalancer, map[string]string{
+			ServiceAnnotationLoadBalancerConfig:           "lbc",
+			ServiceAnnotationLoadBalancerListenerProtocol: "80:HTTPS",
+		}, true},
+	}
+	for _, c := range cases {
+		svc := buildService()
+		svc.Spec.Type = c.serviceType
+		svc.Spec.Ports = []api.ServicePort{{Port: 80, Protocol: api.ProtocolTCP}}
+		svc.Annotations = c.annotations
+		err := cloud.ValidateService(ctx, svc, nil)
+		if c.valid && err != nil {
+			t.Errorf("%s: expected valid, got %v", c.name, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("%s: expected invalid", c.name)
+		}
+	}
+
+	// services admitted before are not blocked by validation added later
+	svc := buildService()
+	svc.Spec.Type = api.ServiceTypeLoadBalancer
+	svc.Annotations = map[string]string{ServiceAnnotationLoadBalancerRsMaxNum: "100"}
+	old := svc.DeepCopy()
+	svc.Finalizers = []string{"service.kubernetes.io/load-balancer-cleanup"}
+	if err := cloud.ValidateService(ctx, svc, old); err != nil {
+		t.Errorf("update changing neither annotations nor spec should be allowed, got %v", err)
+	}
+	svc.Spec.Ports = []api.ServicePort{{Port: 443, Protocol: api.ProtocolTCP}}
+	if err := cloud.ValidateService(ctx, svc, old); err == nil {
+		t.Errorf("update changing spec should be validated")
+	}
+	now := meta_v1.Now()
+	svc.DeletionTimestamp = &now
+	if err := cloud.ValidateService(ctx, svc, old); err != nil {
+		t.Errorf("service being deleted should be allowed, got %v", err)
+	}
+}
+
+func TestDefaultService(t *testing.T) {
+	cloud := NewFakeCloud("c-admission")
+	svc := buildService()
+	svc.Spec.Type = api.ServiceTypeLoadBalancer
+	svc.Spec.Ports = []api.ServicePort{{Port: 53, Protocol: api.ProtocolUDP}}
+	svc.Annotations = map[string]string{ServiceAnnotationLoadBalancerScheduler: "Hash"}
+
+	annotations, err := cloud.DefaultService(svc, nil)
+	if err != nil {
+		t.Fatalf("DefaultService failed: %v", err)
+	}
+	expected := map[string]string{
+		ServiceAnnotationLoadBalancerScheduler:                  "Hash",
+		ServiceAnnotationLoadBalancerBackendType:                BackendTypeNode,
+		ServiceAnnotationLoadBalancerRollbackPolicy:             RollbackPolicyRetain,
+		ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond: "3",
+		ServiceAnnotationLoadBalancerHealthCheckInterval:        "3",
+		ServiceAnnotationLoadBalancerUnhealthyThreshold:         "3",
+		ServiceAnnotationLoadBalancerHealthyThreshold:           "3",
+		ServiceAnnotationLoadBalancerHealthCheckString:          "HealthCheck",
+		ServiceAnnotationElasticIPPaymentTiming:                 "Postpaid",
+		ServiceAnnotationElasticIPBillingMethod:                 "ByTraffic",
+		ServiceAnnotationElasticIPBandwidthInMbps:               "1000",
+		ServiceAnnotationElasticIPRouteType:                     EIPRouteTypeBGP,
+		ServiceAnnotationLoadBalancerDefaulted: ServiceAnnotationElasticIPBandwidthInMbps + "," + ServiceAnnotationElasticIPBillingMethod + "," +
+			ServiceAnnotationElasticIPPaymentTiming + "," + ServiceAnnotationElasticIPRouteType + "," + ServiceAnnotationLoadBalancerBackendType + "," +
+			ServiceAnnotationLoadBalancerHealthCheckInterval + "," + ServiceAnnotationLoadBalancerHealthCheckString + "," +
+			ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond + "," + ServiceAnnotationLoadBalancerHealthyThreshold + "," +
+			ServiceAnnotationLoadBalancerRollbackPolicy + "," + ServiceAnnotationLoadBalancerUnhealthyThreshold,
+	}
+	if !reflect.DeepEqual(annotations, expected) {
+		t.Fatalf("expected annotations %v, got %v", expected, annotations)
+	}
+
+	// defaulted again
+	old := svc.DeepCopy()
+	old.Annotations = annotations
+	svc = old.DeepCopy()
+	if annotations, err = cloud.DefaultService(svc, old); err != nil || annotations != nil {
+		t.Errorf("nothing should change, got %v, %v", annotations, err)
+	}
+
+	// bandwidth defaulted before follows the billing method changed by user
+	svc.Annotations[ServiceAnnotationElasticIPBillingMethod] = "ByBandwidth"
+	annotations, err = cloud.DefaultService(svc, old)
+	if err != nil {
+		t.Fatalf("DefaultService failed: %v", err)
+	}
+	if annotations[ServiceAnnotationElasticIPBandwidthInMbps] != "200" || annotations[ServiceAnnotationElasticIPBillingMethod] != "ByBandwidth" {
+		t.Errorf("expected ByBandwidth 200, got %v", annotations)
+	}
+	svc.Annotations = annotations
+	if err := cloud.ValidateService(context.Background(), svc, nil); err != nil {
+		t.Errorf("defaulted service should be valid: %v", err)
+	}
+
+	// defaults of services referring to LoadBalancerConfig come from the config
+	svc = buildService()
+	svc.Spec.Type = api.ServiceTypeLoadBalancer
+	svc.Annotations = map[string]string{ServiceAnnotationLoadBalancerConfig: "lbc"}
+	if annotations, err = cloud.DefaultService(svc, nil); err != nil || annotations != nil {
+		t.Errorf("service referring to LoadBalancerConfig should not be defaulted, got %v, %v", annotations, err)
+	}
+}
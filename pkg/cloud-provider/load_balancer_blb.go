@@ -2,6 +2,7 @@ package cloud_provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,6 +10,8 @@ import (
 	"k8s.io/klog"
 
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+
+	tempblb "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
 )
 
 func (bc *Baiducloud) ensureBLB(ctx context.Context, clusterName string, service *v1.Service) (*blb.LoadBalancer, error) {
@@ -24,6 +27,9 @@ func (bc *Baiducloud) ensureBLB(ctx context.Context, clusterName string, service
 	if exist && lb != nil {
 		msg := fmt.Sprintf("BLB for service %s already exist", serviceKey)
 		klog.Info(Message(ctx, msg))
+		if lb.Status == blbStatusCreating {
+			return bc.waitForBLBAvailable(ctx, lb.BlbId)
+		}
 		return lb, nil
 	}
 
@@ -35,12 +41,11 @@ func (bc *Baiducloud) ensureBLB(ctx context.Context, clusterName string, service
 		return nil, fmt.Errorf(msg)
 	}
 
-	time.Sleep(6 * time.Second)
-	lb, exist, err = bc.getBLBByID(ctx, lbId)
-	if err != nil || !exist {
-		msg := fmt.Sprintf("check blb %s for service %s exist failed, exist is %v, err is %v", lbId, serviceKey, exist, err)
-		klog.Error(Message(ctx, msg))
-		return nil, fmt.Errorf(msg)
+	// a BLB still creating is found by name and waited for again when the timeout is requeued
+	lb, err = bc.waitForBLBAvailable(ctx, lbId)
+	if err != nil {
+		klog.Error(Message(ctx, fmt.Sprintf("wait blb %s for service %s available failed: %v", lbId, serviceKey, err)))
+		return nil, fmt.Errorf("wait blb %s for service %s available failed: %w", lbId, serviceKey, err)
 	}
 
 	if service.Annotations == nil {
@@ -59,7 +64,11 @@ func (bc *Baiducloud) createBLB(ctx context.Context, service *v1.Service) (strin
 	}()
 	vpcID, subnetID, err := bc.getVpcInfoForBLB(ctx, service)
 	if err != nil {
-		return "", fmt.Errorf(" Can't get VPC info for BLB: %v\n ", err)
+		var vErr *validationError
+		if vip := getInternalVIP(service); len(vip) != 0 && errors.As(err, &vErr) {
+			bc.eventRecorder.Eventf(service, v1.EventTypeWarning, "InvalidInternalVIP", "VPC address %s can not be used by BLB: %v", vip, err)
+		}
+		return "", fmt.Errorf(" Can't get VPC info for BLB: %w\n ", err)
 	}
 
 	allocateVip := false
@@ -67,13 +76,42 @@ func (bc *Baiducloud) createBLB(ctx context.Context, service *v1.Service) (strin
 		allocateVip = true
 		klog.Infof(Message(ctx, fmt.Sprintf("allocateVip for service %s", serviceKey)))
 	}
+	desc := blbDescPrefix + bc.ClusterID
+	if isInternalVpcService(service) {
+		desc = internalVpcBLBDescPrefix + desc
+	}
 	blbName := getBlbName(bc.ClusterID, service)
 	args := blb.CreateLoadBalancerArgs{
 		Name:        blbName,
 		VpcID:       vpcID,
 		SubnetID:    subnetID,
-		Desc:        "auto generated by cce:" + bc.ClusterID,
+		Desc:        desc,
 		AllocateVIP: allocateVip,
+		ClientToken: getClientToken(bc.ClusterID, service, bc.clientTokenKind(service, clientTokenKindBLB)),
+	}
+	// the VPC address of internal-vpc service is requested by BLBAddressClient
+	if vip := getInternalVIP(service); len(vip) != 0 {
+		err = bc.validateInternalVIP(ctx, vpcID, subnetID, vip)
+		if err != nil {
+			bc.eventRecorder.Eventf(service, v1.EventTypeWarning, "InvalidInternalVIP", "VPC address %s can not be used by BLB: %v", vip, err)
+			return "", err
+		}
+		addressArgs := tempblb.CreateLoadBalancerArgs{
+			Name:        args.Name,
+			Desc:        args.Desc,
+			VpcID:       args.VpcID,
+			SubnetID:    args.SubnetID,
+			Address:     vip,
+			AllocateVIP: args.AllocateVIP,
+			ClientToken: args.ClientToken,
+		}
+		klog.Infof(Message(ctx, fmt.Sprintf("create blb for service %s args: %v", serviceKey, addressArgs)))
+		resp, err := bc.clientSet.BLBAddressClient.CreateLoadBalancer(ctx, &addressArgs, bc.getSignOption(ctx))
+		if err != nil {
+			return "", err
+		}
+		klog.Infof(Message(ctx, fmt.Sprintf("create blb for service %s success, BLB name: %s, BLB id: %s, BLB address: %s.", serviceKey, resp.Name, resp.LoadBalancerId, resp.Address)))
+		return resp.LoadBalancerId, nil
 	}
 	klog.Infof(Message(ctx, fmt.Sprintf("create blb for service %s args: %v", serviceKey, args)))
 	resp, err := bc.clientSet.BLBClient.CreateLoadBalancer(ctx, &args, bc.getSignOption(ctx))
@@ -0,0 +1,218 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	tempblb "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
+)
+
+const (
+	// BackendTypeNode registers nodes and NodePorts as backends of BLB
+	BackendTypeNode = "node"
+	// BackendTypePod registers pod ips and target ports as backends of BLB, which requires VPC-native pod networking
+	BackendTypePod = "pod"
+)
+
+// isPodBackend returns whether the BLB of service uses pods as backends, the backend type may be set by LoadBalancerConfig
+func (bc *Baiducloud) isPodBackend(service *v1.Service) bool {
+	if service.Annotations[ServiceAnnotationLoadBalancerConfig] == "" {
+		return service.Annotations[ServiceAnnotationLoadBalancerBackendType] == BackendTypePod
+	}
+	anno, err := bc.extractServiceAnnotation(service)
+	if err != nil {
+		klog.Warningf("failed to get backend type of service %s/%s: %v", service.Namespace, service.Name, err)
+		return service.Annotations[ServiceAnnotationLoadBalancerBackendType] == BackendTypePod
+	}
+	return anno.LoadBalancerBackendType == BackendTypePod
+}
+
+// reconcilePodBackends makes the backends of each listener match the ready addresses of Endpoints of service,
+// nodes registered before are removed from BLB
+func (bc *Baiducloud) reconcilePodBackends(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer, anno *ServiceAnnotation) error {
+	startTime := time.Now()
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	defer func() {
+		klog.V(4).Infof(Message(ctx, fmt.Sprintf("Finished reconcilePodBackends for service %q (%v)", serviceKey, time.Since(startTime))))
+	}()
+	ep, err := bc.endpointsLister.Endpoints(service.Namespace).Get(service.Name)
+	if errors.IsNotFound(err) {
+		ep = &v1.Endpoints{}
+	} else if err != nil {
+		return err
+	}
+
+	for _, servicePort := range service.Spec.Ports {
+		listenerType := getListenerProtocol(anno, servicePort)
+		expected := getPodBackendsOfPort(ep, servicePort)
+		args := tempblb.DescribeBackendIPsArgs{
+			LoadBalancerId: lb.BlbId,
+			ListenerPort:   int(servicePort.Port),
+			ListenerType:   listenerType,
+		}
+		existing, err := bc.clientSet.BLBBackendIPClient.DescribeBackendIPs(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+		var ipsToDel []tempblb.BackendIP
+		for _, ip := range existing {
+			key := fmt.Sprintf("%s:%d", ip.Ip, ip.Port)
+			if _, ok := expected[key]; ok {
+				delete(expected, key)
+				continue
+			}
+			ipsToDel = append(ipsToDel, ip)
+		}
+		var ipsToAdd []tempblb.BackendIP
+		for _, ip := range expected {
+			ipsToAdd = append(ipsToAdd, ip)
+		}
+
+		if len(ipsToAdd) > 0 {
+			klog.Infof(Message(ctx, fmt.Sprintf("add pods %v to listener %s:%d of BLB %s for service %s", ipsToAdd, listenerType, servicePort.Port, lb.BlbId, serviceKey)))
+			args := tempblb.BackendIPsArgs{
+				LoadBalancerId: lb.BlbId,
+				ListenerPort:   int(servicePort.Port),
+				ListenerType:   listenerType,
+				BackendIPList:  ipsToAdd,
+			}
+			err = bc.clientSet.BLBBackendIPClient.AddBackendIPs(ctx, &args, bc.getSignOption(ctx))
+			if err != nil {
+				return err
+			}
+		}
+		if len(ipsToDel) > 0 {
+			klog.Infof(Message(ctx, fmt.Sprintf("remove pods %v from listener %s:%d of BLB %s for service %s", ipsToDel, listenerType, servicePort.Port, lb.BlbId, serviceKey)))
+			args := tempblb.BackendIPsArgs{
+				LoadBalancerId: lb.BlbId,
+				ListenerPort:   int(servicePort.Port),
+				ListenerType:   listenerType,
+				BackendIPList:  ipsToDel,
+			}
+			err = bc.clientSet.BLBBackendIPClient.RemoveBackendIPs(ctx, &args, bc.getSignOption(ctx))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if getRecordedBackendType(service) == BackendTypePod {
+		return nil
+	}
+	// nodes are left when the backend type changed from node to pod
+	nodeBackends, err := bc.getAllBackendServer(ctx, lb)
+	if err != nil {
+		return err
+	}
+	if len(nodeBackends) != 0 {
+		existingWeights := make(map[string]int, len(nodeBackends))
+		for _, rs := range nodeBackends {
+			existingWeights[rs.InstanceId] = rs.Weight
+		}
+		drainPeriod := time.Duration(anno.LoadBalancerRsDrainPeriodInSecond) * time.Second
+		err = bc.drainBackendServers(ctx, service, lb, nodeBackends, existingWeights, drainPeriod)
+		if err != nil {
+			return err
+		}
+	}
+	// nodes still draining are removed by the next syncs before pod backends are recorded
+	if len(getDrainingBackends(service)) != 0 {
+		return nil
+	}
+	return bc.recordBackendType(service, BackendTypePod)
+}
+
+// getRecordedBackendType returns the backend type registered to the BLB of service
+func getRecordedBackendType(service *v1.Service) string {
+	if backendType, ok := service.Annotations[ServiceAnnotationCceBackendType]; ok {
+		return backendType
+	}
+	return BackendTypeNode
+}
+
+// recordBackendType records backendType as registered to the BLB of service
+func (bc *Baiducloud) recordBackendType(service *v1.Service, backendType string) error {
+	if getRecordedBackendType(service) == backendType {
+		return nil
+	}
+	return bc.updateServiceAnnotation(service, ServiceAnnotationCceBackendType, backendType)
+}
+
+// getPodBackendsOfPort returns the ready addresses of ep serving servicePort, keyed by ip:port
+func getPodBackendsOfPort(ep *v1.Endpoints, servicePort v1.ServicePort) map[string]tempblb.BackendIP {
+	result := make(map[string]tempblb.BackendIP)
+	for _, subset := range ep.Subsets {
+		for _, port := range subset.Ports {
+			// ports of endpoints have the same names as ports of service
+			if port.Name != servicePort.Name || port.Protocol != servicePort.Protocol {
+				continue
+			}
+			for _, addr := range subset.Addresses {
+				result[fmt.Sprintf("%s:%d", addr.IP, port.Port)] = tempblb.BackendIP{
+					Ip:     addr.IP,
+					Port:   int(port.Port),
+					Weight: defaultBLBRSWeight,
+				}
+			}
+		}
+	}
+	return result
+}
+
+// ensurePodBackendsDeleted removes the pod ips registered to the listeners of service,
+// which are left when the backend type changed from pod to node
+func (bc *Baiducloud) ensurePodBackendsDeleted(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer, anno *ServiceAnnotation) error {
+	if getRecordedBackendType(service) != BackendTypePod {
+		return nil
+	}
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	for _, servicePort := range service.Spec.Ports {
+		listenerType := getListenerProtocol(anno, servicePort)
+		args := tempblb.DescribeBackendIPsArgs{
+			LoadBalancerId: lb.BlbId,
+			ListenerPort:   int(servicePort.Port),
+			ListenerType:   listenerType,
+		}
+		existing, err := bc.clientSet.BLBBackendIPClient.DescribeBackendIPs(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+		if len(existing) == 0 {
+			continue
+		}
+		klog.Infof(Message(ctx, fmt.Sprintf("remove pods %v from listener %s:%d of BLB %s for service %s, backend type is node", existing, listenerType, servicePort.Port, lb.BlbId, serviceKey)))
+		removeArgs := tempblb.BackendIPsArgs{
+			LoadBalancerId: lb.BlbId,
+			ListenerPort:   int(servicePort.Port),
+			ListenerType:   listenerType,
+			BackendIPList:  existing,
+		}
+		err = bc.clientSet.BLBBackendIPClient.RemoveBackendIPs(ctx, &removeArgs, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+	}
+	return bc.recordBackendType(service, BackendTypeNode)
+}
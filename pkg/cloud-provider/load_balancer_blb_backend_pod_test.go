@@ -0,0 +1,153 @@
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	api "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	tempblb "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
+)
+
+func TestReconcilePodBackends(t *testing.T) {
+	cloud, nodesRes, _, err := beforeTestBackend()
+	if err != nil {
+		t.Errorf("beforeTestBackend err, err: %v", err)
+	}
+	ctx := context.Background()
+	svc := buildService()
+	svc.Annotations = map[string]string{
+		ServiceAnnotationLoadBalancerBackendType:      BackendTypePod,
+		ServiceAnnotationLoadBalancerListenerProtocol: "80:HTTP",
+	}
+	svc.Spec.Ports = []api.ServicePort{
+		{Name: "http", Port: 80, Protocol: "TCP", NodePort: 30080},
+		{Name: "dns", Port: 53, Protocol: "UDP", NodePort: 30053},
+	}
+	ep := &api.Endpoints{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      svc.Name,
+			Namespace: svc.Namespace,
+		},
+		Subsets: []api.EndpointSubset{
+			{
+				Addresses:         []api.EndpointAddress{{IP: "172.16.0.1"}, {IP: "172.16.0.2"}},
+				NotReadyAddresses: []api.EndpointAddress{{IP: "172.16.0.3"}},
+				Ports: []api.EndpointPort{
+					{Name: "http", Port: 8080, Protocol: "TCP"},
+					{Name: "dns", Port: 5353, Protocol: "UDP"},
+				},
+			},
+		},
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := indexer.Add(ep); err != nil {
+		t.Fatalf("add Endpoints err, err: %v", err)
+	}
+	cloud.endpointsLister = corelisters.NewEndpointsLister(indexer)
+	lb, _, err := cloud.getServiceAssociatedBLB(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Errorf("getServiceAssociatedBLB err, err: %v", err)
+	}
+	// nodes registered before are removed
+	args := blb.AddBackendServersArgs{
+		LoadBalancerId:    lb.BlbId,
+		BackendServerList: []blb.BackendServer{{InstanceId: nodesRes.Nodes[0].InstanceID, Weight: defaultBLBRSWeight}},
+	}
+	err = cloud.clientSet.BLBClient.AddBackendServers(ctx, &args, cloud.getSignOption(ctx))
+	if err != nil {
+		t.Errorf("AddBackendServers err, err: %v", err)
+	}
+	getBackendIPs := func(port int, listenerType string) []string {
+		args := tempblb.DescribeBackendIPsArgs{
+			LoadBalancerId: lb.BlbId,
+			ListenerPort:   port,
+			ListenerType:   listenerType,
+		}
+		ips, err := cloud.clientSet.BLBBackendIPClient.DescribeBackendIPs(ctx, &args, cloud.getSignOption(ctx))
+		if err != nil {
+			t.Errorf("DescribeBackendIPs err, err: %v", err)
+		}
+		var result []string
+		for _, ip := range ips {
+			result = append(result, fmt.Sprintf("%s:%d", ip.Ip, ip.Port))
+		}
+		sort.Strings(result)
+		return result
+	}
+
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nil)
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+	if ips := getBackendIPs(80, "HTTP"); !reflect.DeepEqual(ips, []string{"172.16.0.1:8080", "172.16.0.2:8080"}) {
+		t.Errorf("backends of listener HTTP:80 get %v", ips)
+	}
+	if ips := getBackendIPs(53, "UDP"); !reflect.DeepEqual(ips, []string{"172.16.0.1:5353", "172.16.0.2:5353"}) {
+		t.Errorf("backends of listener UDP:53 get %v", ips)
+	}
+	if bs, _ := cloud.getAllBackendServer(ctx, lb); len(bs) != 0 {
+		t.Errorf("node backends should be removed but get %v", bs)
+	}
+	if backendType := getRecordedBackendType(svc); backendType != BackendTypePod {
+		t.Errorf("recorded backend type should be %s but get %s", BackendTypePod, backendType)
+	}
+
+	// pod goes away
+	ep.Subsets[0].Addresses = ep.Subsets[0].Addresses[:1]
+	if err := indexer.Update(ep); err != nil {
+		t.Errorf("update Endpoints err, err: %v", err)
+	}
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nil)
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+	if ips := getBackendIPs(80, "HTTP"); !reflect.DeepEqual(ips, []string{"172.16.0.1:8080"}) {
+		t.Errorf("backends of listener HTTP:80 get %v", ips)
+	}
+	if ips := getBackendIPs(53, "UDP"); !reflect.DeepEqual(ips, []string{"172.16.0.1:5353"}) {
+		t.Errorf("backends of listener UDP:53 get %v", ips)
+	}
+
+	// pods are removed when backend type changed to node
+	svc.Annotations[ServiceAnnotationLoadBalancerBackendType] = BackendTypeNode
+	nodes := []*api.Node{{
+		ObjectMeta: meta_v1.ObjectMeta{Name: nodesRes.Nodes[0].InstanceID},
+		Spec:       api.NodeSpec{ProviderID: "cce://" + nodesRes.Nodes[0].InstanceID},
+	}}
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes)
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+	if ips := getBackendIPs(80, "HTTP"); len(ips) != 0 {
+		t.Errorf("pod backends of listener HTTP:80 should be removed but get %v", ips)
+	}
+	if ips := getBackendIPs(53, "UDP"); len(ips) != 0 {
+		t.Errorf("pod backends of listener UDP:53 should be removed but get %v", ips)
+	}
+	if bs, _ := cloud.getAllBackendServer(ctx, lb); len(bs) != 1 {
+		t.Errorf("node backends should be added but get %v", bs)
+	}
+	if backendType := getRecordedBackendType(svc); backendType != BackendTypeNode {
+		t.Errorf("recorded backend type should be %s but get %s", BackendTypeNode, backendType)
+	}
+
+	// ip groups are not described for services which never used pod backends
+	cloud.clientSet.BLBBackendIPClient = nil
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes)
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+
+	svc.Annotations[ServiceAnnotationLoadBalancerBackendType] = "eni"
+	if _, err := ExtractServiceAnnotation(svc); err == nil {
+		t.Errorf("ExtractServiceAnnotation should fail with invalid backend type")
+	}
+}
@@ -18,12 +18,19 @@ package cloud_provider
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/klog"
 
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
@@ -32,6 +39,15 @@ import (
 const blbMaxRSNum int = 50
 const defaultBLBRSWeight int = 100
 
+const (
+	// labelNodeRoleMaster specifies that a node is a master
+	labelNodeRoleMaster = "node-role.kubernetes.io/master"
+	// labelNodeRoleExcludeBalancer specifies that the node should not be a rs of BLB
+	labelNodeRoleExcludeBalancer = "node.kubernetes.io/exclude-from-external-load-balancers"
+	// labelAlphaNodeRoleExcludeBalancer is the deprecated version of labelNodeRoleExcludeBalancer
+	labelAlphaNodeRoleExcludeBalancer = "alpha.service-controller.kubernetes.io/exclude-balancer"
+)
+
 func (bc *Baiducloud) reconcileBackendServers(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) error {
 	startTime := time.Now()
 	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
@@ -46,23 +62,23 @@ func (bc *Baiducloud) reconcileBackendServers(ctx context.Context, clusterName s
 		return fmt.Errorf("failed to reconcileBackendServers: lb not exist")
 	}
 
-	if service.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal {
-		nodes, err = bc.getServiceAssociatedNodes(ctx, service)
-		if err != nil {
-			return err
-		}
-		if len(nodes) == 0 {
-			klog.Infof(Message(ctx, fmt.Sprintf("service %s has no nodes to add to lb, maybe has no pod, do nothing", serviceKey)))
-			return nil
-		}
-		klog.Infof(Message(ctx, fmt.Sprintf("externalTrafficPolicy of service %s is Local, nodes is %+v", serviceKey, nodes)))
-	}
-
 	// extract annotation
-	anno, err := ExtractServiceAnnotation(service)
+	anno, err := bc.extractServiceAnnotation(service)
 	if err != nil {
 		return fmt.Errorf("failed to ExtractServiceAnnotation %s, err: %v", service.Name, err)
 	}
+	if anno.LoadBalancerBackendType == BackendTypePod {
+		return bc.reconcilePodBackends(ctx, service, lb, anno)
+	}
+
+	// all eligible nodes are kept for Local services, nodes without local endpoints fail the health check of BLB
+	nodes = filterBackendNodes(nodes, anno.LoadBalancerNodeSelector)
+	if len(nodes) == 0 {
+		klog.Infof(Message(ctx, fmt.Sprintf("service %s has no nodes to add to lb, maybe has no pod, do nothing", serviceKey)))
+		bc.eventRecorder.Eventf(service, v1.EventTypeWarning, "NoBackendNodes",
+			"No nodes to add to BLB %s, check labels of nodes and annotation %s or LoadBalancerConfig", lb.BlbId, ServiceAnnotationLoadBalancerNodeSelector)
+		return nil
+	}
 	// default rs num of a blb is 50
 	targetRsNum := blbMaxRSNum
 	if anno.LoadBalancerRsMaxNum > 0 {
@@ -79,8 +95,15 @@ func (bc *Baiducloud) reconcileBackendServers(ctx context.Context, clusterName s
 			continue
 		}
 		name := splitted[1]
+		weight, err := getNodeBackendWeight(node)
+		if err != nil {
+			msg := fmt.Sprintf("node %s has invalid weight, use default weight %d: %v", node.Name, defaultBLBRSWeight, err)
+			bc.eventRecorder.Eventf(node, v1.EventTypeWarning, "InvalidBLBRsWeight", msg)
+			klog.Warningf(Message(ctx, msg))
+		}
 		candidateBackends = append(candidateBackends, blb.BackendServer{
 			InstanceId: name,
+			Weight:     weight,
 		})
 	}
 	if len(candidateBackends) < targetRsNum {
@@ -93,7 +116,25 @@ func (bc *Baiducloud) reconcileBackendServers(ctx context.Context, clusterName s
 		return err
 	}
 
-	rsToAdd, rsToDel, err := mergeBackend(candidateBackends, existingBackends, targetRsNum)
+	// zones are only needed to choose part of the nodes
+	var zones map[string]string
+	if targetRsNum < len(candidateBackends) {
+		zones, err = bc.getBackendZones(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// nodes hosting endpoints of Local services are kept first, the others fail the health check of BLB
+	var preferred map[string]bool
+	if targetRsNum < len(candidateBackends) && service.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal {
+		preferred, err = bc.getEndpointBackends(service, nodes)
+		if err != nil {
+			return err
+		}
+	}
+
+	rsToAdd, rsToDel, err := mergeBackend(candidateBackends, existingBackends, targetRsNum, zones, preferred, serviceKey)
 	if err != nil {
 		return err
 	}
@@ -111,56 +152,256 @@ func (bc *Baiducloud) reconcileBackendServers(ctx context.Context, clusterName s
 		}
 	}
 
-	if len(rsToDel) > 0 {
-		var delList []string
-		for _, rs := range rsToDel {
+	// pods are left when the backend type changed from pod to node, they are removed after nodes are added
+	err = bc.ensurePodBackendsDeleted(ctx, service, lb, anno)
+	if err != nil {
+		return err
+	}
+
+	existingWeights := make(map[string]int, len(existingBackends))
+	for _, rs := range existingBackends {
+		existingWeights[rs.InstanceId] = rs.Weight
+	}
+	delSet := make(map[string]bool, len(rsToDel))
+	for _, rs := range rsToDel {
+		delSet[rs.InstanceId] = true
+	}
+	// update weight of kept rs in place, which also brings back draining rs
+	var rsToUpdate []blb.BackendServer
+	for _, rs := range candidateBackends {
+		weight, exist := existingWeights[rs.InstanceId]
+		if !exist || delSet[rs.InstanceId] || weight == rs.Weight {
+			continue
+		}
+		rsToUpdate = append(rsToUpdate, rs)
+	}
+	if len(rsToUpdate) > 0 {
+		klog.Infof(Message(ctx, fmt.Sprintf("find nodes %v to update weight in BLB %s for service %s", rsToUpdate, lb.BlbId, serviceKey)))
+		args := blb.UpdateBackendServersArgs{
+			LoadBalancerId:    lb.BlbId,
+			BackendServerList: rsToUpdate,
+		}
+		err = bc.clientSet.BLBClient.UpdateBackendServers(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+	}
+
+	drainPeriod := time.Duration(anno.LoadBalancerRsDrainPeriodInSecond) * time.Second
+	return bc.drainBackendServers(ctx, service, lb, rsToDel, existingWeights, drainPeriod)
+}
+
+// finishDrainingBackendServers removes the rs of service which have drained for the drain period,
+// it is called when a draining service is requeued, nodes of service are not needed
+func (bc *Baiducloud) finishDrainingBackendServers(ctx context.Context, clusterName string, service *v1.Service) error {
+	lb, exist, err := bc.getServiceAssociatedBLB(ctx, clusterName, service)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return nil
+	}
+	anno, err := bc.extractServiceAnnotation(service)
+	if err != nil {
+		return fmt.Errorf("failed to ExtractServiceAnnotation %s, err: %v", service.Name, err)
+	}
+	existingBackends, err := bc.getAllBackendServer(ctx, lb)
+	if err != nil {
+		return err
+	}
+	drainStarts := getDrainingBackends(service)
+	var draining []blb.BackendServer
+	existingWeights := make(map[string]int, len(existingBackends))
+	for _, rs := range existingBackends {
+		existingWeights[rs.InstanceId] = rs.Weight
+		if _, ok := drainStarts[rs.InstanceId]; ok && rs.Weight == 0 {
+			draining = append(draining, rs)
+		}
+	}
+	drainPeriod := time.Duration(anno.LoadBalancerRsDrainPeriodInSecond) * time.Second
+	return bc.drainBackendServers(ctx, service, lb, draining, existingWeights, drainPeriod)
+}
+
+// drainBackendServers sets weight of rs to 0 and removes them from BLB after drainPeriod,
+// so that in-flight connections are not dropped. The service is requeued to remove rs still draining.
+// When rs started draining is recorded in annotation of service, rs not in backends are no longer draining.
+func (bc *Baiducloud) drainBackendServers(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer,
+	backends []blb.BackendServer, existingWeights map[string]int, drainPeriod time.Duration) error {
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	now := time.Now()
+	drainStarts := getDrainingBackends(service)
+	stillDraining := make(map[string]int64, len(backends))
+	var rsToDrain []blb.BackendServer
+	var delList []string
+	var requeueAfter time.Duration
+	for _, rs := range backends {
+		if drainPeriod <= 0 {
+			delList = append(delList, rs.InstanceId)
+			continue
+		}
+		start := now
+		if existingWeights[rs.InstanceId] != 0 {
+			rsToDrain = append(rsToDrain, blb.BackendServer{InstanceId: rs.InstanceId, Weight: 0})
+		} else if unix, ok := drainStarts[rs.InstanceId]; ok {
+			start = time.Unix(unix, 0)
+		}
+		left := drainPeriod - now.Sub(start)
+		if left <= 0 {
 			delList = append(delList, rs.InstanceId)
+			continue
 		}
+		stillDraining[rs.InstanceId] = start.Unix()
+		if requeueAfter == 0 || left < requeueAfter {
+			requeueAfter = left
+		}
+	}
+
+	if len(rsToDrain) > 0 {
+		klog.Infof(Message(ctx, fmt.Sprintf("drain nodes %v of BLB %s for service %s in %v", rsToDrain, lb.BlbId, serviceKey, drainPeriod)))
+		args := blb.UpdateBackendServersArgs{
+			LoadBalancerId:    lb.BlbId,
+			BackendServerList: rsToDrain,
+		}
+		err := bc.clientSet.BLBClient.UpdateBackendServers(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(delList) > 0 {
+		klog.Infof(Message(ctx, fmt.Sprintf("remove nodes %v from BLB %s for service %s", delList, lb.BlbId, serviceKey)))
 		args := blb.RemoveBackendServersArgs{
 			LoadBalancerId:    lb.BlbId,
 			BackendServerList: delList,
 		}
-		err = bc.clientSet.BLBClient.RemoveBackendServers(ctx, &args, bc.getSignOption(ctx))
+		err := bc.clientSet.BLBClient.RemoveBackendServers(ctx, &args, bc.getSignOption(ctx))
 		if err != nil {
 			return err
 		}
 	}
 
+	if !reflect.DeepEqual(drainStarts, stillDraining) {
+		if err := bc.setDrainingBackends(service, stillDraining); err != nil {
+			return err
+		}
+	}
+
+	if requeueAfter > 0 && bc.svcQueue != nil {
+		bc.svcQueue.AddAfter(serviceKey, requeueAfter)
+	}
 	return nil
 }
 
-func (bc *Baiducloud) getServiceAssociatedNodes(ctx context.Context, service *v1.Service) ([]*v1.Node, error) {
-	ep, err := bc.kubeClient.CoreV1().Endpoints(service.Namespace).Get(service.Name, metav1.GetOptions{})
+// getDrainingBackends returns when the draining rs of service started draining in unix seconds, keyed by instance id
+func getDrainingBackends(service *v1.Service) map[string]int64 {
+	result := make(map[string]int64)
+	value, ok := service.Annotations[ServiceAnnotationCceDrainingBackends]
+	if !ok {
+		return result
+	}
+	if err := json.Unmarshal([]byte(value), &result); err != nil {
+		klog.Warningf("service %s/%s has invalid annotation %s, draining rs start over: %v", service.Namespace, service.Name, ServiceAnnotationCceDrainingBackends, err)
+		return make(map[string]int64)
+	}
+	return result
+}
+
+// setDrainingBackends records drainStarts in annotation of service, the annotation is removed if no rs is draining
+func (bc *Baiducloud) setDrainingBackends(service *v1.Service, drainStarts map[string]int64) error {
+	if len(drainStarts) == 0 {
+		return bc.removeServiceAnnotation(service, ServiceAnnotationCceDrainingBackends)
+	}
+	value, err := json.Marshal(drainStarts)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if len(ep.Subsets) == 0 {
-		klog.Infof(Message(ctx, fmt.Sprintf("Endpoints %s/%s has no subsets", ep.Namespace, ep.Name)))
-		return nil, nil
+	return bc.updateServiceAnnotation(service, ServiceAnnotationCceDrainingBackends, string(value))
+}
+
+// filterBackendNodes returns the nodes which can be rs of BLB, masters and nodes labeled to be excluded
+// from load balancers are filtered out, and the rest must match selector if it is not nil
+func filterBackendNodes(nodes []*v1.Node, selector labels.Selector) []*v1.Node {
+	result := make([]*v1.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if _, ok := node.Labels[labelNodeRoleMaster]; ok {
+			continue
+		}
+		if _, ok := node.Labels[labelNodeRoleExcludeBalancer]; ok {
+			continue
+		}
+		if _, ok := node.Labels[labelAlphaNodeRoleExcludeBalancer]; ok {
+			continue
+		}
+		if selector != nil && !selector.Matches(labels.Set(node.Labels)) {
+			continue
+		}
+		result = append(result, node)
 	}
-	nodeMap := make(map[string]string, 0)
-	for _, addr := range ep.Subsets[0].Addresses {
-		nodeMap[*addr.NodeName] = *addr.NodeName
+	return result
+}
+
+// getNodeBackendWeight returns the weight of node as BLB rs, which is set by annotation NodeAnnotationBLBRsWeight
+func getNodeBackendWeight(node *v1.Node) (int, error) {
+	value, ok := node.Annotations[NodeAnnotationBLBRsWeight]
+	if !ok {
+		return defaultBLBRSWeight, nil
+	}
+	weight, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultBLBRSWeight, fmt.Errorf("NodeAnnotationBLBRsWeight must be int, err: %v", err)
 	}
+	if weight < 1 || weight > 100 {
+		return defaultBLBRSWeight, fmt.Errorf("NodeAnnotationBLBRsWeight must be in [1, 100]")
+	}
+	return weight, nil
+}
 
-	for _, addr := range ep.Subsets[0].NotReadyAddresses {
-		nodeMap[*addr.NodeName] = *addr.NodeName
+// getBackendZones returns the available zone of each instance in cluster
+func (bc *Baiducloud) getBackendZones(ctx context.Context) (map[string]string, error) {
+	instanceResponse, err := bc.clientSet.CCEClient.ListClusterNodes(ctx, bc.ClusterID, bc.getSignOption(ctx))
+	if err != nil {
+		return nil, err
+	}
+	zones := make(map[string]string, len(instanceResponse.Nodes))
+	for _, ins := range instanceResponse.Nodes {
+		zones[ins.InstanceID] = ins.AvailableZone
 	}
+	return zones, nil
+}
 
-	all_nodes, err := bc.kubeClient.CoreV1().Nodes().List(metav1.ListOptions{})
+// getEndpointBackends returns the instance ids of nodes hosting ready endpoints of service
+func (bc *Baiducloud) getEndpointBackends(service *v1.Service, nodes []*v1.Node) (map[string]bool, error) {
+	ep, err := bc.kubeClient.CoreV1().Endpoints(service.Namespace).Get(service.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	result := make([]*v1.Node, 0)
-	for _, node := range all_nodes.Items {
-		if _, exist := nodeMap[node.Name]; exist {
-			n := node.DeepCopy()
-			klog.Infof(Message(ctx, fmt.Sprintf("Node is %s", n.Name)))
-			result = append(result, n)
+	nodeNames := make(map[string]bool)
+	for _, subset := range ep.Subsets {
+		for _, addr := range subset.Addresses {
+			if addr.NodeName != nil {
+				nodeNames[*addr.NodeName] = true
+			}
+		}
+	}
+	backends := make(map[string]bool)
+	for _, node := range nodes {
+		splitted := strings.Split(node.Spec.ProviderID, "//")
+		if nodeNames[node.Name] && len(splitted) == 2 {
+			backends[splitted[1]] = true
 		}
 	}
+	return backends, nil
+}
 
-	return result, nil
+// backendRank ranks instance for a service by rendezvous hashing, so that each service prefers
+// a stable subset of nodes and different services spread over different nodes
+func backendRank(seed, instanceID string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(seed + "/" + instanceID))
+	return h.Sum32()
 }
 
 /*
@@ -178,66 +419,148 @@ rsToAdd: ["1", "2"]  rsToDel: ["5"]
 */
 // candidateBackends contains all ready kubernetes nodes
 // existingBackends is real rss(nodes) bound to BLB
-func mergeBackend(candidateBackends, existingBackends []blb.BackendServer, targetBackendsNum int) (
-	[]blb.BackendServer, []blb.BackendServer, error) {
+// zones maps instance id to its available zone, backends are spread evenly across zones
+// preferred backends are kept and added before the others, even if the others exist in BLB
+// seed makes the choice of backends deterministic for a service
+// An existing backend is only replaced when it leaves candidateBackends, targetBackendsNum decreases,
+// or a preferred backend needs its place.
+func mergeBackend(candidateBackends, existingBackends []blb.BackendServer, targetBackendsNum int,
+	zones map[string]string, preferred map[string]bool, seed string) ([]blb.BackendServer, []blb.BackendServer, error) {
 
 	if targetBackendsNum > len(candidateBackends) || targetBackendsNum <= 0 {
 		return nil, nil, fmt.Errorf("targetBackendsNum %d is invalid", targetBackendsNum)
 	}
-	// turn existingBackends to map
-	existingBackendsMap := make(map[string]int)
-	for _, backend := range existingBackends {
-		existingBackendsMap[backend.InstanceId] = 0
-	}
-
 	// turn candidateBackends to map
-	candidateBackendsMap := make(map[string]int)
+	candidateBackendsMap := make(map[string]bool)
+	candidateWeights := make(map[string]int)
 	for _, backend := range candidateBackends {
-		candidateBackendsMap[backend.InstanceId] = 0
+		candidateBackendsMap[backend.InstanceId] = true
+		candidateWeights[backend.InstanceId] = backend.Weight
 	}
 
-	// find rs to delete
 	var rsToAdd, rsToDel []blb.BackendServer
+	// kept and free backends grouped by zone, keptOthers and freeOthers are the ones not preferred
+	kept := make(map[string][]string)
+	keptOthers := make(map[string][]string)
+	keptSet := make(map[string]bool)
+	keptNum, keptOthersNum := 0, 0
 	// first find rs that is not in kubernetes to delete from blb
-	for insID := range existingBackendsMap {
-		_, exist := candidateBackendsMap[insID]
-		if !exist {
-			rsToDel = append(rsToDel, blb.BackendServer{
-				InstanceId: insID,
-			})
-			delete(existingBackendsMap, insID)
+	for _, backend := range existingBackends {
+		insID := backend.InstanceId
+		if !candidateBackendsMap[insID] {
+			rsToDel = append(rsToDel, blb.BackendServer{InstanceId: insID})
+			continue
+		}
+		if keptSet[insID] {
+			continue
+		}
+		keptSet[insID] = true
+		kept[zones[insID]] = append(kept[zones[insID]], insID)
+		keptNum++
+		if !preferred[insID] {
+			keptOthers[zones[insID]] = append(keptOthers[zones[insID]], insID)
+			keptOthersNum++
 		}
 	}
-
-	// then, if number of rs in BLB still > targetBackendsNum, random choose rs in blb to delete
-	numToDel := len(existingBackendsMap) - targetBackendsNum
-	for insID := range existingBackendsMap {
-		if numToDel > 0 {
-			rsToDel = append(rsToDel, blb.BackendServer{InstanceId: insID})
-			delete(existingBackendsMap, insID)
-			numToDel = numToDel - 1
+	freePreferred := make(map[string][]string)
+	freeOthers := make(map[string][]string)
+	freePreferredNum := 0
+	for _, backend := range candidateBackends {
+		insID := backend.InstanceId
+		if keptSet[insID] {
+			continue
 		}
+		keptSet[insID] = true
+		if preferred[insID] {
+			freePreferred[zones[insID]] = append(freePreferred[zones[insID]], insID)
+			freePreferredNum++
+			continue
+		}
+		freeOthers[zones[insID]] = append(freeOthers[zones[insID]], insID)
 	}
-
-	// find rs to add
-	if len(existingBackendsMap) < targetBackendsNum {
-		numToAdd := targetBackendsNum - len(existingBackendsMap)
-		for insID := range candidateBackendsMap {
-			if numToAdd == 0 {
-				break
+	// preferred backends rank before the others
+	byRank := func(ids []string) {
+		sort.Slice(ids, func(i, j int) bool {
+			if preferred[ids[i]] != preferred[ids[j]] {
+				return preferred[ids[i]]
 			}
-			if _, exist := existingBackendsMap[insID]; !exist {
-				rsToAdd = append(rsToAdd, blb.BackendServer{
-					InstanceId: insID,
-					Weight:     defaultBLBRSWeight,
-				})
-				numToAdd = numToAdd - 1
+			ri, rj := backendRank(seed, ids[i]), backendRank(seed, ids[j])
+			if ri != rj {
+				return ri < rj
 			}
+			return ids[i] < ids[j]
+		})
+	}
+	for _, group := range []map[string][]string{kept, keptOthers, freePreferred, freeOthers} {
+		for _, ids := range group {
+			byRank(ids)
 		}
 	}
+
+	// then, make room for free preferred rs by deleting the worst ranked other rs of the most crowded zone
+	othersLimit := targetBackendsNum - (keptNum - keptOthersNum) - freePreferredNum
+	for keptOthersNum > othersLimit && keptOthersNum > 0 {
+		zone := pickZone(keptOthers, kept, func(a, b int) bool { return a > b })
+		ids := keptOthers[zone]
+		rsToDel = append(rsToDel, blb.BackendServer{InstanceId: ids[len(ids)-1]})
+		keptOthers[zone] = ids[:len(ids)-1]
+		// others rank last, so the worst ranked other rs is the last of its zone
+		kept[zone] = kept[zone][:len(kept[zone])-1]
+		keptOthersNum--
+		keptNum--
+	}
+	// if number of rs in BLB still > targetBackendsNum, delete the worst ranked rs of the most crowded zone
+	for keptNum > targetBackendsNum {
+		zone := pickZone(kept, kept, func(a, b int) bool { return a > b })
+		ids := kept[zone]
+		rsToDel = append(rsToDel, blb.BackendServer{InstanceId: ids[len(ids)-1]})
+		kept[zone] = ids[:len(ids)-1]
+		keptNum--
+	}
+
+	// find rs to add, add the best ranked rs of the least crowded zone, preferred rs first
+	for keptNum < targetBackendsNum {
+		free := freeOthers
+		if freePreferredNum > 0 {
+			free = freePreferred
+			freePreferredNum--
+		}
+		zone := pickZone(free, kept, func(a, b int) bool { return a < b })
+		insID := free[zone][0]
+		free[zone] = free[zone][1:]
+		kept[zone] = append(kept[zone], insID)
+		weight := candidateWeights[insID]
+		if weight <= 0 {
+			weight = defaultBLBRSWeight
+		}
+		rsToAdd = append(rsToAdd, blb.BackendServer{
+			InstanceId: insID,
+			Weight:     weight,
+		})
+		keptNum++
+	}
 	return rsToAdd, rsToDel, nil
 }
 
+// pickZone returns the zone which has backends in from and whose number of backends in counted is preferred by less,
+// ties are broken by zone name to be deterministic
+func pickZone(from, counted map[string][]string, less func(a, b int) bool) string {
+	var zoneNames []string
+	for zone, ids := range from {
+		if len(ids) > 0 {
+			zoneNames = append(zoneNames, zone)
+		}
+	}
+	sort.Strings(zoneNames)
+	picked := zoneNames[0]
+	for _, zone := range zoneNames[1:] {
+		if less(len(counted[zone]), len(counted[picked])) {
+			picked = zone
+		}
+	}
+	return picked
+}
+
 func (bc *Baiducloud) getAllBackendServer(ctx context.Context, lb *blb.LoadBalancer) ([]blb.BackendServer, error) {
 	args := blb.DescribeBackendServersArgs{
 		LoadBalancerId: lb.BlbId,
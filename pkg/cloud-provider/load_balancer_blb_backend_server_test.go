@@ -2,12 +2,19 @@ package cloud_provider
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
 	cce "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-cce"
 	api "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 )
 
 func beforeTestBackend() (*Baiducloud, *cce.ListClusterNodesResponse, *blb.CreateLoadBalancerResponse, error) {
@@ -159,7 +166,7 @@ func TestMergeBackend(t *testing.T) {
 			InstanceId: "5",
 		},
 	}
-	rsToAdd, rsToDel, err := mergeBackend(candidateBackends, existingBackends, 1)
+	rsToAdd, rsToDel, err := mergeBackend(candidateBackends, existingBackends, 1, nil, nil, "")
 	if err != nil {
 		t.Errorf("mergeBackend err, err: %v", err)
 	}
@@ -191,7 +198,7 @@ func TestMergeBackend(t *testing.T) {
 			InstanceId: "5",
 		},
 	}
-	rsToAdd, rsToDel, err = mergeBackend(candidateBackends, existingBackends, 1)
+	rsToAdd, rsToDel, err = mergeBackend(candidateBackends, existingBackends, 1, nil, nil, "")
 	if err != nil {
 		t.Errorf("mergeBackend err, err: %v", err)
 	}
@@ -222,7 +229,7 @@ func TestMergeBackend(t *testing.T) {
 			InstanceId: "5",
 		},
 	}
-	rsToAdd, rsToDel, err = mergeBackend(candidateBackends, existingBackends, 3)
+	rsToAdd, rsToDel, err = mergeBackend(candidateBackends, existingBackends, 3, nil, nil, "")
 	if err != nil {
 		t.Errorf("mergeBackend err, err: %v", err)
 	}
@@ -233,6 +240,102 @@ func TestMergeBackend(t *testing.T) {
 	}
 }
 
+func TestMergeBackendZoneBalanced(t *testing.T) {
+	// zone-a has 6 nodes, zone-b has 2 nodes, zone-c has 1 node
+	zones := map[string]string{}
+	var candidateBackends []blb.BackendServer
+	for i := 0; i < 9; i++ {
+		insID := fmt.Sprintf("i-%d", i)
+		switch {
+		case i < 6:
+			zones[insID] = "zone-a"
+		case i < 8:
+			zones[insID] = "zone-b"
+		default:
+			zones[insID] = "zone-c"
+		}
+		candidateBackends = append(candidateBackends, blb.BackendServer{InstanceId: insID})
+	}
+	countZones := func(backends []blb.BackendServer) map[string]int {
+		result := map[string]int{}
+		for _, b := range backends {
+			result[zones[b.InstanceId]]++
+		}
+		return result
+	}
+
+	rsToAdd, rsToDel, err := mergeBackend(candidateBackends, nil, 5, zones, nil, "default/foo")
+	if err != nil {
+		t.Errorf("mergeBackend err, err: %v", err)
+	}
+	if len(rsToAdd) != 5 || len(rsToDel) != 0 {
+		t.Errorf("mergeBackend err, want 5 | 0 get %v | %v", rsToAdd, rsToDel)
+	}
+	if count := countZones(rsToAdd); count["zone-a"] != 2 || count["zone-b"] != 2 || count["zone-c"] != 1 {
+		t.Errorf("mergeBackend err, backends not balanced across zones: %v", count)
+	}
+
+	// deterministic
+	for i := 0; i < 10; i++ {
+		again, _, _ := mergeBackend(candidateBackends, nil, 5, zones, nil, "default/foo")
+		if !reflect.DeepEqual(rsToAdd, again) {
+			t.Errorf("mergeBackend err, not deterministic: %v vs %v", rsToAdd, again)
+		}
+	}
+
+	// stable, nothing changes when candidates are unchanged
+	existingBackends := rsToAdd
+	rsToAdd, rsToDel, err = mergeBackend(candidateBackends, existingBackends, 5, zones, nil, "default/foo")
+	if err != nil || len(rsToAdd) != 0 || len(rsToDel) != 0 {
+		t.Errorf("mergeBackend err, want no change get %v | %v, err: %v", rsToAdd, rsToDel, err)
+	}
+
+	// only the backend leaving candidates is replaced, zone-b and zone-c have no more nodes, so by one of zone-a
+	left := existingBackends[0].InstanceId
+	var newCandidates []blb.BackendServer
+	for _, b := range candidateBackends {
+		if b.InstanceId != left {
+			newCandidates = append(newCandidates, b)
+		}
+	}
+	rsToAdd, rsToDel, err = mergeBackend(newCandidates, existingBackends, 5, zones, nil, "default/foo")
+	if err != nil {
+		t.Errorf("mergeBackend err, err: %v", err)
+	}
+	if len(rsToDel) != 1 || rsToDel[0].InstanceId != left || len(rsToAdd) != 1 {
+		t.Errorf("mergeBackend err, want replace %s get %v | %v", left, rsToAdd, rsToDel)
+	} else if zones[rsToAdd[0].InstanceId] != "zone-a" {
+		t.Errorf("mergeBackend err, %s should be replaced in zone-a, get %v", left, rsToAdd)
+	}
+}
+
+func TestMergeBackendPreferred(t *testing.T) {
+	var candidateBackends []blb.BackendServer
+	for i := 0; i < 6; i++ {
+		candidateBackends = append(candidateBackends, blb.BackendServer{InstanceId: fmt.Sprintf("i-%d", i)})
+	}
+	// i-0, i-1 and i-2 are in BLB, endpoints of the Local service are on i-4 and i-5
+	existingBackends := candidateBackends[:3]
+	preferred := map[string]bool{"i-4": true, "i-5": true}
+	rsToAdd, rsToDel, err := mergeBackend(candidateBackends, existingBackends, 3, nil, preferred, "default/foo")
+	if err != nil {
+		t.Errorf("mergeBackend err, err: %v", err)
+	}
+	if len(rsToAdd) != 2 || !arraysHelp(rsToAdd, "i-4", "i-5") || len(rsToDel) != 2 {
+		t.Errorf("mergeBackend err, want i-4, i-5 replace 2 of i-0, i-1, i-2 get %v | %v", rsToAdd, rsToDel)
+	}
+
+	// preferred rs are kept when targetBackendsNum decreases
+	existingBackends = []blb.BackendServer{{InstanceId: "i-0"}, {InstanceId: "i-4"}, {InstanceId: "i-5"}}
+	rsToAdd, rsToDel, err = mergeBackend(candidateBackends, existingBackends, 1, nil, preferred, "default/foo")
+	if err != nil {
+		t.Errorf("mergeBackend err, err: %v", err)
+	}
+	if len(rsToAdd) != 0 || len(rsToDel) != 2 || !arraysHelp(rsToDel, "i-0") || arraysHelp(rsToDel, "i-4", "i-5") {
+		t.Errorf("mergeBackend err, want i-0 and one of i-4, i-5 deleted get %v | %v", rsToAdd, rsToDel)
+	}
+}
+
 // case1: expected input, get the right output
 // case2: nodes or lb is nil
 func TestReconcileBackendServers(t *testing.T) {
@@ -276,4 +379,184 @@ func TestReconcileBackendServers(t *testing.T) {
 	if err != nil {
 		t.Errorf("reconcileBackendServers err, err: %v", err)
 	}
+	// case3: all nodes are filtered out, nothing to do but an event
+	recorder := record.NewFakeRecorder(10)
+	cloud.eventRecorder = recorder
+	nodes = []*api.Node{
+		&api.Node{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Labels: map[string]string{labelNodeRoleMaster: ""},
+			},
+			Spec: api.NodeSpec{
+				ProviderID: "test//" + nodesRes.Nodes[0].InstanceID,
+			},
+		},
+	}
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes)
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "NoBackendNodes") {
+			t.Errorf("expected NoBackendNodes event, got %s", event)
+		}
+	default:
+		t.Errorf("expected NoBackendNodes event")
+	}
+}
+
+func TestReconcileBackendServersWeightAndDrain(t *testing.T) {
+	cloud, nodesRes, _, err := beforeTestBackend()
+	if err != nil {
+		t.Errorf("beforeTestBackend err, err: %v", err)
+	}
+	ctx := context.Background()
+	svc := buildService()
+	cloud.eventRecorder = record.NewFakeRecorder(10)
+	svc.Annotations = map[string]string{
+		ServiceAnnotationLoadBalancerRsDrainPeriodInSecond: "60",
+	}
+	cloud.kubeClient = fake.NewSimpleClientset(svc.DeepCopy())
+	buildNode := func(instanceID, weight string) *api.Node {
+		node := &api.Node{
+			Spec: api.NodeSpec{
+				ProviderID: "test//" + instanceID,
+			},
+		}
+		if weight != "" {
+			node.Annotations = map[string]string{NodeAnnotationBLBRsWeight: weight}
+		}
+		return node
+	}
+	getWeights := func() map[string]int {
+		lb, _, err := cloud.getServiceAssociatedBLB(ctx, cloud.ClusterName, svc)
+		if err != nil {
+			t.Errorf("getServiceAssociatedBLB err, err: %v", err)
+		}
+		bs, err := cloud.getAllBackendServer(ctx, lb)
+		if err != nil {
+			t.Errorf("getAllBackendServer err, err: %v", err)
+		}
+		weights := make(map[string]int)
+		for _, rs := range bs {
+			weights[rs.InstanceId] = rs.Weight
+		}
+		return weights
+	}
+	ins0, ins1 := nodesRes.Nodes[0].InstanceID, nodesRes.Nodes[1].InstanceID
+
+	// add with weight of annotation or default weight
+	nodes := []*api.Node{buildNode(ins0, "50"), buildNode(ins1, "")}
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes)
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+	expected := map[string]int{ins0: 50, ins1: defaultBLBRSWeight}
+	if weights := getWeights(); !reflect.DeepEqual(weights, expected) {
+		t.Errorf("weights should be %v but get %v", expected, weights)
+	}
+
+	// update weight in place, invalid weight falls back to default
+	nodes = []*api.Node{buildNode(ins0, "abc"), buildNode(ins1, "20")}
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes)
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+	expected = map[string]int{ins0: defaultBLBRSWeight, ins1: 20}
+	if weights := getWeights(); !reflect.DeepEqual(weights, expected) {
+		t.Errorf("weights should be %v but get %v", expected, weights)
+	}
+
+	// leaving rs is drained with weight 0 first
+	nodes = []*api.Node{buildNode(ins0, "")}
+	err = cloud.reconcileBackendServers(ctx, cloud.ClusterName, svc, nodes)
+	if err != nil {
+		t.Errorf("reconcileBackendServers err, err: %v", err)
+	}
+	expected = map[string]int{ins0: defaultBLBRSWeight, ins1: 0}
+	if weights := getWeights(); !reflect.DeepEqual(weights, expected) {
+		t.Errorf("weights should be %v but get %v", expected, weights)
+	}
+	err = cloud.finishDrainingBackendServers(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Errorf("finishDrainingBackendServers err, err: %v", err)
+	}
+	if weights := getWeights(); !reflect.DeepEqual(weights, expected) {
+		t.Errorf("rs should not be removed before drain period, weights: %v", weights)
+	}
+
+	// rs is removed after drain period
+	// drain start is persisted, so that it is kept across restarts
+	persisted, err := cloud.kubeClient.CoreV1().Services(svc.Namespace).Get(svc.Name, meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get service err, err: %v", err)
+	}
+	drainStarts := getDrainingBackends(persisted)
+	if _, ok := drainStarts[ins1]; !ok {
+		t.Fatalf("drain start of %s should be recorded, annotation: %s", ins1, persisted.Annotations[ServiceAnnotationCceDrainingBackends])
+	}
+	drainStarts[ins1] = time.Now().Add(-time.Minute).Unix()
+	if err := cloud.setDrainingBackends(svc, drainStarts); err != nil {
+		t.Fatalf("setDrainingBackends err, err: %v", err)
+	}
+	err = cloud.finishDrainingBackendServers(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Errorf("finishDrainingBackendServers err, err: %v", err)
+	}
+	expected = map[string]int{ins0: defaultBLBRSWeight}
+	if weights := getWeights(); !reflect.DeepEqual(weights, expected) {
+		t.Errorf("weights should be %v but get %v", expected, weights)
+	}
+	if _, ok := svc.Annotations[ServiceAnnotationCceDrainingBackends]; ok {
+		t.Errorf("annotation %s should be removed after rs are removed", ServiceAnnotationCceDrainingBackends)
+	}
+}
+
+func TestFilterBackendNodes(t *testing.T) {
+	buildNode := func(name string, labels map[string]string) *api.Node {
+		return &api.Node{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Name:   name,
+				Labels: labels,
+			},
+		}
+	}
+	nodes := []*api.Node{
+		buildNode("master", map[string]string{labelNodeRoleMaster: ""}),
+		buildNode("excluded", map[string]string{labelNodeRoleExcludeBalancer: "true"}),
+		buildNode("alpha-excluded", map[string]string{labelAlphaNodeRoleExcludeBalancer: "true"}),
+		buildNode("ingress", map[string]string{"pool": "ingress"}),
+		buildNode("default", nil),
+	}
+	names := func(nodes []*api.Node) []string {
+		var result []string
+		for _, node := range nodes {
+			result = append(result, node.Name)
+		}
+		return result
+	}
+
+	result := filterBackendNodes(nodes, nil)
+	if !reflect.DeepEqual(names(result), []string{"ingress", "default"}) {
+		t.Errorf("filterBackendNodes without selector get %v", names(result))
+	}
+
+	svc := buildService()
+	svc.Annotations = map[string]string{
+		ServiceAnnotationLoadBalancerNodeSelector: "pool=ingress",
+	}
+	anno, err := ExtractServiceAnnotation(svc)
+	if err != nil {
+		t.Errorf("ExtractServiceAnnotation err, err: %v", err)
+	}
+	result = filterBackendNodes(nodes, anno.LoadBalancerNodeSelector)
+	if !reflect.DeepEqual(names(result), []string{"ingress"}) {
+		t.Errorf("filterBackendNodes with selector get %v", names(result))
+	}
+
+	svc.Annotations[ServiceAnnotationLoadBalancerNodeSelector] = "pool in (ingress"
+	if _, err := ExtractServiceAnnotation(svc); err == nil {
+		t.Errorf("ExtractServiceAnnotation should fail with invalid node selector")
+	}
 }
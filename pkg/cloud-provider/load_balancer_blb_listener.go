@@ -24,6 +24,21 @@ import (
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/klog"
+
+	tempblb "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
+)
+
+const (
+	defaultBLBScheduler                  = "RoundRobin"
+	defaultBLBHealthCheckTimeoutInSecond = 3
+	defaultBLBHealthCheckInterval        = 3
+	defaultBLBUnhealthyThreshold         = 3
+	defaultBLBHealthyThreshold           = 3
+	defaultBLBHealthCheckString          = "HealthCheck"
+
+	// kube-proxy serves the number of local endpoints on HealthCheckNodePort of Local services
+	localHealthCheckType = "HTTP"
+	localHealthCheckURI  = "/healthz"
 )
 
 // PortListener describe listener port
@@ -31,6 +46,81 @@ type PortListener struct {
 	Port     int
 	Protocol string
 	NodePort int32
+	CertID   string
+
+	// Scheduler and health check config, only used by TCP and UDP listeners
+	Scheduler                  string
+	HealthCheckTimeoutInSecond int
+	HealthCheckInterval        int
+	UnhealthyThreshold         int
+	HealthyThreshold           int
+	// HealthCheckString is only used by UDP listeners
+	HealthCheckString string
+
+	// session persistence config, only used by HTTP and HTTPS listeners
+	KeepSession         bool
+	KeepSessionType     string
+	KeepSessionDuration int
+
+	// health check on a port other than NodePort, only used by Local services to check kube-proxy
+	HealthCheckType string
+	HealthCheckPort int
+	HealthCheckURI  string
+}
+
+// listenerKey identifies a listener of BLB, TCP and UDP listeners can share the same port
+type listenerKey struct {
+	Port     int
+	Protocol string
+}
+
+func (pl PortListener) key() listenerKey {
+	return listenerKey{Port: pl.Port, Protocol: pl.Protocol}
+}
+
+// listenerProtocolFitPort returns whether a listener of protocol can serve the service port of portProtocol
+func listenerProtocolFitPort(protocol string, portProtocol v1.Protocol) bool {
+	switch protocol {
+	case "TCP", "HTTP", "HTTPS":
+		return portProtocol == v1.ProtocolTCP
+	case "UDP":
+		return portProtocol == v1.ProtocolUDP
+	}
+	return false
+}
+
+// getListenerProtocol returns the protocol of listener serving servicePort, which may be overridden by annotation
+func getListenerProtocol(anno *ServiceAnnotation, servicePort v1.ServicePort) string {
+	if protocol, ok := anno.LoadBalancerListenerProtocol[int(servicePort.Port)]; ok && listenerProtocolFitPort(protocol, servicePort.Protocol) {
+		return protocol
+	}
+	return string(servicePort.Protocol)
+}
+
+// withListenerDefaults fills the unset scheduler and health check config of TCP and UDP listeners with BLB defaults
+func withListenerDefaults(pl PortListener) PortListener {
+	if pl.Protocol != "TCP" && pl.Protocol != "UDP" {
+		return pl
+	}
+	if pl.Scheduler == "" {
+		pl.Scheduler = defaultBLBScheduler
+	}
+	if pl.HealthCheckTimeoutInSecond == 0 {
+		pl.HealthCheckTimeoutInSecond = defaultBLBHealthCheckTimeoutInSecond
+	}
+	if pl.HealthCheckInterval == 0 {
+		pl.HealthCheckInterval = defaultBLBHealthCheckInterval
+	}
+	if pl.UnhealthyThreshold == 0 {
+		pl.UnhealthyThreshold = defaultBLBUnhealthyThreshold
+	}
+	if pl.HealthyThreshold == 0 {
+		pl.HealthyThreshold = defaultBLBHealthyThreshold
+	}
+	if pl.Protocol == "UDP" && pl.HealthCheckString == "" {
+		pl.HealthCheckString = defaultBLBHealthCheckString
+	}
+	return pl
 }
 
 func (bc *Baiducloud) reconcileListeners(ctx context.Context, clusterName string, service *v1.Service) error {
@@ -39,14 +129,56 @@ func (bc *Baiducloud) reconcileListeners(ctx context.Context, clusterName string
 	defer func() {
 		klog.V(4).Infof(Message(ctx, fmt.Sprintf("Finished reconcileListeners for service %q (%v)", serviceKey, time.Since(startTime))))
 	}()
+	anno, err := bc.extractServiceAnnotation(service)
+	if err != nil {
+		return fmt.Errorf("failed to ExtractServiceAnnotation %s, err: %v", service.Name, err)
+	}
+	clientIPAffinity := service.Spec.SessionAffinity == v1.ServiceAffinityClientIP
+	// BLB checks kube-proxy on HealthCheckNodePort, which fails on nodes without local endpoints,
+	// pods are checked directly when they are backends
+	localHealthCheck := service.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal &&
+		service.Spec.HealthCheckNodePort != 0 && anno.LoadBalancerBackendType != BackendTypePod
 	// add expected ports
-	expected := make(map[int]PortListener)
+	expected := make(map[listenerKey]PortListener)
 	for _, servicePort := range service.Spec.Ports {
-		expected[int(servicePort.Port)] = PortListener{
+		pl := PortListener{
 			Port:     int(servicePort.Port),
-			Protocol: string(servicePort.Protocol),
+			Protocol: getListenerProtocol(anno, servicePort),
 			NodePort: servicePort.NodePort,
 		}
+		lc := anno.listenerConfig(pl.Port)
+		switch pl.Protocol {
+		case "HTTP", "HTTPS":
+			if pl.Protocol == "HTTPS" {
+				pl.CertID = lc.CertID
+			}
+			// ClientIP affinity is kept by the session cookie inserted by BLB
+			if clientIPAffinity {
+				pl.KeepSession = true
+				pl.KeepSessionType = "insert"
+				pl.KeepSessionDuration = getSessionAffinityTimeout(service)
+			}
+		case "TCP", "UDP":
+			pl.Scheduler = lc.Scheduler
+			// ClientIP affinity is kept by hashing the source ip
+			if clientIPAffinity {
+				pl.Scheduler = "Hash"
+			}
+			pl.HealthCheckTimeoutInSecond = lc.HealthCheckTimeoutInSecond
+			pl.HealthCheckInterval = lc.HealthCheckInterval
+			pl.UnhealthyThreshold = lc.UnhealthyThreshold
+			pl.HealthyThreshold = lc.HealthyThreshold
+			if pl.Protocol == "UDP" {
+				pl.HealthCheckString = lc.HealthCheckString
+			}
+		}
+		// UDP listeners keep checking backends by UDP, /healthz can only be checked by TCP, HTTP and HTTPS listeners
+		if localHealthCheck && pl.Protocol != "UDP" {
+			pl.HealthCheckType = localHealthCheckType
+			pl.HealthCheckPort = int(service.Spec.HealthCheckNodePort)
+			pl.HealthCheckURI = localHealthCheckURI
+		}
+		expected[pl.key()] = withListenerDefaults(pl)
 	}
 
 	lb, exist, err := bc.getServiceAssociatedBLB(ctx, clusterName, service)
@@ -64,9 +196,9 @@ func (bc *Baiducloud) reconcileListeners(ctx context.Context, clusterName string
 	}
 	var deleteList []PortListener
 	for _, l := range all {
-		port, ok := expected[l.Port]
+		port, ok := expected[l.key()]
 		if !ok {
-			// delete listener port
+			// delete listener port, including the one whose protocol changed since listener can not be updated in place
 			// add to deleteList
 			deleteList = append(deleteList, l)
 		} else {
@@ -78,7 +210,7 @@ func (bc *Baiducloud) reconcileListeners(ctx context.Context, clusterName string
 					return err
 				}
 			}
-			delete(expected, l.Port)
+			delete(expected, l.key())
 		}
 	}
 	// delete listener
@@ -103,124 +235,345 @@ func (bc *Baiducloud) reconcileListeners(ctx context.Context, clusterName string
 }
 
 func (bc *Baiducloud) createListener(ctx context.Context, lb *blb.LoadBalancer, pl PortListener) error {
+	pl = withListenerDefaults(pl)
+	healthCheckType, healthCheckPort, healthCheckURI := listenerHealthCheck(pl)
 	switch pl.Protocol {
 	case "UDP":
-		args := blb.CreateUDPListenerArgs{
-			LoadBalancerId:    lb.BlbId,
-			ListenerPort:      pl.Port,
-			BackendPort:       int(pl.NodePort),
-			Scheduler:         "RoundRobin",
-			HealthCheckString: "HealthCheck",
-		}
-		err := bc.clientSet.BLBClient.CreateUDPListener(ctx, &args, bc.getSignOption(ctx))
+		args := tempblb.CreateUDPListenerArgs{
+			CreateTCPListenerArgs: tempblb.CreateTCPListenerArgs{
+				LoadBalancerId:             lb.BlbId,
+				ListenerPort:               pl.Port,
+				BackendPort:                int(pl.NodePort),
+				HealthCheckType:            healthCheckType,
+				HealthCheckPort:            healthCheckPort,
+				HealthCheckURI:             healthCheckURI,
+				Scheduler:                  pl.Scheduler,
+				HealthCheckTimeoutInSecond: pl.HealthCheckTimeoutInSecond,
+				HealthCheckInterval:        pl.HealthCheckInterval,
+				UnhealthyThreshold:         pl.UnhealthyThreshold,
+				HealthyThreshold:           pl.HealthyThreshold,
+			},
+			HealthCheckString: pl.HealthCheckString,
+		}
+		err := bc.clientSet.BLBListenerClient.CreateUDPListener(ctx, &args, bc.getSignOption(ctx))
 		if err != nil {
 			return err
 		}
 		return nil
 	case "TCP":
-		args := blb.CreateTCPListenerArgs{
-			LoadBalancerId: lb.BlbId,
-			ListenerPort:   pl.Port,
-			BackendPort:    int(pl.NodePort),
-			Scheduler:      "RoundRobin",
+		args := tempblb.CreateTCPListenerArgs{
+			LoadBalancerId:             lb.BlbId,
+			ListenerPort:               pl.Port,
+			BackendPort:                int(pl.NodePort),
+			HealthCheckType:            healthCheckType,
+			HealthCheckPort:            healthCheckPort,
+			HealthCheckURI:             healthCheckURI,
+			Scheduler:                  pl.Scheduler,
+			HealthCheckTimeoutInSecond: pl.HealthCheckTimeoutInSecond,
+			HealthCheckInterval:        pl.HealthCheckInterval,
+			UnhealthyThreshold:         pl.UnhealthyThreshold,
+			HealthyThreshold:           pl.HealthyThreshold,
 		}
-		err := bc.clientSet.BLBClient.CreateTCPListener(ctx, &args, bc.getSignOption(ctx))
+		err := bc.clientSet.BLBListenerClient.CreateTCPListener(ctx, &args, bc.getSignOption(ctx))
 		if err != nil {
 			return err
 		}
 		return nil
 	case "HTTP":
-		// TODO
+		args := tempblb.CreateHTTPListenerArgs{
+			LoadBalancerId:      lb.BlbId,
+			ListenerPort:        pl.Port,
+			BackendPort:         int(pl.NodePort),
+			HealthCheckType:     healthCheckType,
+			HealthCheckPort:     healthCheckPort,
+			HealthCheckURI:      healthCheckURI,
+			Scheduler:           "RoundRobin",
+			KeepSession:         pl.KeepSession,
+			KeepSessionType:     pl.KeepSessionType,
+			KeepSessionDuration: pl.KeepSessionDuration,
+			XForwardFor:         true,
+		}
+		err := bc.clientSet.BLBListenerClient.CreateHTTPListener(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+		return nil
 	case "HTTPS":
-		// TODO
+		if pl.CertID == "" {
+			return fmt.Errorf("CreateListener HTTPS listener %d need cert id", pl.Port)
+		}
+		args := tempblb.CreateHTTPSListenerArgs{
+			CreateHTTPListenerArgs: tempblb.CreateHTTPListenerArgs{
+				LoadBalancerId:      lb.BlbId,
+				ListenerPort:        pl.Port,
+				BackendPort:         int(pl.NodePort),
+				HealthCheckType:     healthCheckType,
+				HealthCheckPort:     healthCheckPort,
+				HealthCheckURI:      healthCheckURI,
+				Scheduler:           "RoundRobin",
+				KeepSession:         pl.KeepSession,
+				KeepSessionType:     pl.KeepSessionType,
+				KeepSessionDuration: pl.KeepSessionDuration,
+				XForwardFor:         true,
+			},
+			CertIds: []string{pl.CertID},
+		}
+		err := bc.clientSet.BLBListenerClient.CreateHTTPSListener(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+		return nil
 	}
 	return fmt.Errorf("CreateListener protocol not match: %s", pl.Protocol)
 }
 
 func (bc *Baiducloud) updateListener(ctx context.Context, lb *blb.LoadBalancer, pl PortListener) error {
+	pl = withListenerDefaults(pl)
+	healthCheckType, healthCheckPort, healthCheckURI := listenerHealthCheck(pl)
 	switch pl.Protocol {
 	case "UDP":
-		args := blb.UpdateUDPListenerArgs{
-			LoadBalancerId:    lb.BlbId,
-			ListenerPort:      pl.Port,
-			BackendPort:       int(pl.NodePort),
-			Scheduler:         "RoundRobin",
-			HealthCheckString: "HealthCheck",
-		}
-		err := bc.clientSet.BLBClient.UpdateUDPListener(ctx, &args, bc.getSignOption(ctx))
+		args := tempblb.UpdateUDPListenerArgs{
+			UpdateTCPListenerArgs: tempblb.UpdateTCPListenerArgs{
+				LoadBalancerId:             lb.BlbId,
+				ListenerPort:               pl.Port,
+				BackendPort:                int(pl.NodePort),
+				HealthCheckType:            healthCheckType,
+				HealthCheckPort:            healthCheckPort,
+				HealthCheckURI:             healthCheckURI,
+				Scheduler:                  pl.Scheduler,
+				HealthCheckTimeoutInSecond: pl.HealthCheckTimeoutInSecond,
+				HealthCheckInterval:        pl.HealthCheckInterval,
+				UnhealthyThreshold:         pl.UnhealthyThreshold,
+				HealthyThreshold:           pl.HealthyThreshold,
+			},
+			HealthCheckString: pl.HealthCheckString,
+		}
+		err := bc.clientSet.BLBListenerClient.UpdateUDPListener(ctx, &args, bc.getSignOption(ctx))
 		if err != nil {
 			return err
 		}
 		return nil
 	case "TCP":
-		args := blb.UpdateTCPListenerArgs{
-			LoadBalancerId: lb.BlbId,
-			ListenerPort:   pl.Port,
-			BackendPort:    int(pl.NodePort),
-			Scheduler:      "RoundRobin",
+		args := tempblb.UpdateTCPListenerArgs{
+			LoadBalancerId:             lb.BlbId,
+			ListenerPort:               pl.Port,
+			BackendPort:                int(pl.NodePort),
+			HealthCheckType:            healthCheckType,
+			HealthCheckPort:            healthCheckPort,
+			HealthCheckURI:             healthCheckURI,
+			Scheduler:                  pl.Scheduler,
+			HealthCheckTimeoutInSecond: pl.HealthCheckTimeoutInSecond,
+			HealthCheckInterval:        pl.HealthCheckInterval,
+			UnhealthyThreshold:         pl.UnhealthyThreshold,
+			HealthyThreshold:           pl.HealthyThreshold,
 		}
-		err := bc.clientSet.BLBClient.UpdateTCPListener(ctx, &args, bc.getSignOption(ctx))
+		err := bc.clientSet.BLBListenerClient.UpdateTCPListener(ctx, &args, bc.getSignOption(ctx))
 		if err != nil {
 			return err
 		}
 		return nil
 	case "HTTP":
-		// TODO
+		args := tempblb.UpdateHTTPListenerArgs{
+			LoadBalancerId:      lb.BlbId,
+			ListenerPort:        pl.Port,
+			BackendPort:         int(pl.NodePort),
+			HealthCheckType:     healthCheckType,
+			HealthCheckPort:     healthCheckPort,
+			HealthCheckURI:      healthCheckURI,
+			Scheduler:           "RoundRobin",
+			KeepSession:         pl.KeepSession,
+			KeepSessionType:     pl.KeepSessionType,
+			KeepSessionDuration: pl.KeepSessionDuration,
+			XForwardFor:         true,
+		}
+		err := bc.clientSet.BLBListenerClient.UpdateHTTPListener(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+		return nil
 	case "HTTPS":
-		// TODO
+		if pl.CertID == "" {
+			return fmt.Errorf("updateListener HTTPS listener %d need cert id", pl.Port)
+		}
+		args := tempblb.UpdateHTTPSListenerArgs{
+			UpdateHTTPListenerArgs: tempblb.UpdateHTTPListenerArgs{
+				LoadBalancerId:      lb.BlbId,
+				ListenerPort:        pl.Port,
+				BackendPort:         int(pl.NodePort),
+				HealthCheckType:     healthCheckType,
+				HealthCheckPort:     healthCheckPort,
+				HealthCheckURI:      healthCheckURI,
+				Scheduler:           "RoundRobin",
+				KeepSession:         pl.KeepSession,
+				KeepSessionType:     pl.KeepSessionType,
+				KeepSessionDuration: pl.KeepSessionDuration,
+				XForwardFor:         true,
+			},
+			CertIds: []string{pl.CertID},
+		}
+		err := bc.clientSet.BLBListenerClient.UpdateHTTPSListener(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+		return nil
 	}
 	return fmt.Errorf("updateListener protocol not match: %s", pl.Protocol)
 }
 
+// listenerHealthCheck returns the health check type, port and uri of listener,
+// which defaults to check the port of backends by the protocol of listener, port 0 means the port of backends
+func listenerHealthCheck(pl PortListener) (string, int, string) {
+	if pl.HealthCheckPort != 0 {
+		return pl.HealthCheckType, pl.HealthCheckPort, pl.HealthCheckURI
+	}
+	switch pl.Protocol {
+	case "HTTP", "HTTPS":
+		return "HTTP", 0, "/"
+	}
+	return pl.Protocol, 0, ""
+}
+
+// getSessionAffinityTimeout returns the ClientIP affinity timeout of service in second
+func getSessionAffinityTimeout(service *v1.Service) int {
+	config := service.Spec.SessionAffinityConfig
+	if config != nil && config.ClientIP != nil && config.ClientIP.TimeoutSeconds != nil {
+		return int(*config.ClientIP.TimeoutSeconds)
+	}
+	return int(v1.DefaultClientIPServiceAffinitySeconds)
+}
+
 func (bc *Baiducloud) getAllListeners(ctx context.Context, lb *blb.LoadBalancer) ([]PortListener, error) {
 	var allListeners []PortListener
 
 	// add TCPlisteners
-	describeTCPListenerArgs := blb.DescribeTCPListenerArgs{
+	describeTCPListenerArgs := tempblb.DescribeTCPListenerArgs{
 		LoadBalancerId: lb.BlbId,
 	}
-	tcpListeners, err := bc.clientSet.BLBClient.DescribeTCPListener(ctx, &describeTCPListenerArgs, bc.getSignOption(ctx))
+	tcpListeners, err := bc.clientSet.BLBListenerClient.DescribeTCPListener(ctx, &describeTCPListenerArgs, bc.getSignOption(ctx))
 	if err != nil {
 		return nil, err
 	}
 	for _, listener := range tcpListeners {
-		allListeners = append(allListeners, PortListener{
-			Port:     listener.ListenerPort,
-			Protocol: "TCP",
-			NodePort: int32(listener.BackendPort),
-		})
+		pl := PortListener{
+			Port:                       listener.ListenerPort,
+			Protocol:                   "TCP",
+			NodePort:                   int32(listener.BackendPort),
+			Scheduler:                  listener.Scheduler,
+			HealthCheckTimeoutInSecond: listener.HealthCheckTimeoutInSecond,
+			HealthCheckInterval:        listener.HealthCheckInterval,
+			UnhealthyThreshold:         listener.UnhealthyThreshold,
+			HealthyThreshold:           listener.HealthyThreshold,
+		}
+		if listener.HealthCheckPort != 0 && listener.HealthCheckPort != listener.BackendPort {
+			pl.HealthCheckType = listener.HealthCheckType
+			pl.HealthCheckPort = listener.HealthCheckPort
+			pl.HealthCheckURI = listener.HealthCheckURI
+		}
+		allListeners = append(allListeners, pl)
 	}
 
 	// add UDPlisteners
-	describeUDPListenerArgs := blb.DescribeUDPListenerArgs{
+	describeUDPListenerArgs := tempblb.DescribeUDPListenerArgs{
 		LoadBalancerId: lb.BlbId,
 	}
-	udpListeners, err := bc.clientSet.BLBClient.DescribeUDPListener(ctx, &describeUDPListenerArgs, bc.getSignOption(ctx))
+	udpListeners, err := bc.clientSet.BLBListenerClient.DescribeUDPListener(ctx, &describeUDPListenerArgs, bc.getSignOption(ctx))
 	if err != nil {
 		return nil, err
 	}
 	for _, listener := range udpListeners {
-		allListeners = append(allListeners, PortListener{
+		pl := PortListener{
+			Port:                       listener.ListenerPort,
+			Protocol:                   "UDP",
+			NodePort:                   int32(listener.BackendPort),
+			Scheduler:                  listener.Scheduler,
+			HealthCheckTimeoutInSecond: listener.HealthCheckTimeoutInSecond,
+			HealthCheckInterval:        listener.HealthCheckInterval,
+			UnhealthyThreshold:         listener.UnhealthyThreshold,
+			HealthyThreshold:           listener.HealthyThreshold,
+			HealthCheckString:          listener.HealthCheckString,
+		}
+		if listener.HealthCheckPort != 0 && listener.HealthCheckPort != listener.BackendPort {
+			pl.HealthCheckType = listener.HealthCheckType
+			pl.HealthCheckPort = listener.HealthCheckPort
+			pl.HealthCheckURI = listener.HealthCheckURI
+		}
+		allListeners = append(allListeners, pl)
+	}
+
+	// add HTTPlisteners
+	describeHTTPListenerArgs := tempblb.DescribeHTTPListenerArgs{
+		LoadBalancerId: lb.BlbId,
+	}
+	httpListeners, err := bc.clientSet.BLBListenerClient.DescribeHTTPListener(ctx, &describeHTTPListenerArgs, bc.getSignOption(ctx))
+	if err != nil {
+		return nil, err
+	}
+	for _, listener := range httpListeners {
+		pl := PortListener{
 			Port:     listener.ListenerPort,
-			Protocol: "UDP",
+			Protocol: "HTTP",
 			NodePort: int32(listener.BackendPort),
-		})
+		}
+		if listener.KeepSession {
+			pl.KeepSession = true
+			pl.KeepSessionType = listener.KeepSessionType
+			pl.KeepSessionDuration = listener.KeepSessionDuration
+		}
+		if listener.HealthCheckPort != 0 && listener.HealthCheckPort != listener.BackendPort {
+			pl.HealthCheckType = listener.HealthCheckType
+			pl.HealthCheckPort = listener.HealthCheckPort
+			pl.HealthCheckURI = listener.HealthCheckURI
+		}
+		allListeners = append(allListeners, pl)
+	}
+
+	// add HTTPSlisteners
+	describeHTTPSListenerArgs := tempblb.DescribeHTTPSListenerArgs{
+		LoadBalancerId: lb.BlbId,
+	}
+	httpsListeners, err := bc.clientSet.BLBListenerClient.DescribeHTTPSListener(ctx, &describeHTTPSListenerArgs, bc.getSignOption(ctx))
+	if err != nil {
+		return nil, err
+	}
+	for _, listener := range httpsListeners {
+		pl := PortListener{
+			Port:     listener.ListenerPort,
+			Protocol: "HTTPS",
+			NodePort: int32(listener.BackendPort),
+		}
+		if len(listener.CertIds) > 0 {
+			pl.CertID = listener.CertIds[0]
+		}
+		if listener.KeepSession {
+			pl.KeepSession = true
+			pl.KeepSessionType = listener.KeepSessionType
+			pl.KeepSessionDuration = listener.KeepSessionDuration
+		}
+		if listener.HealthCheckPort != 0 && listener.HealthCheckPort != listener.BackendPort {
+			pl.HealthCheckType = listener.HealthCheckType
+			pl.HealthCheckPort = listener.HealthCheckPort
+			pl.HealthCheckURI = listener.HealthCheckURI
+		}
+		allListeners = append(allListeners, pl)
 	}
 
-	// TODO: add HTTP,HTTPS
 	return allListeners, nil
 }
 
 func (bc *Baiducloud) deleteListener(ctx context.Context, lb *blb.LoadBalancer, pl []PortListener) error {
-	var portList []int
+	// delete by port and protocol, otherwise listeners of other protocols on the same port are deleted too
+	var portTypeList []tempblb.PortTypeModel
 	for _, l := range pl {
-		portList = append(portList, l.Port)
+		portTypeList = append(portTypeList, tempblb.PortTypeModel{
+			Port: l.Port,
+			Type: l.Protocol,
+		})
 	}
-	args := blb.DeleteListenersArgs{
+	args := tempblb.DeleteListenersArgs{
 		LoadBalancerId: lb.BlbId,
-		PortList:       portList,
+		PortTypeList:   portTypeList,
 	}
-	err := bc.clientSet.BLBClient.DeleteListeners(ctx, &args, bc.getSignOption(ctx))
+	err := bc.clientSet.BLBListenerClient.DeleteListeners(ctx, &args, bc.getSignOption(ctx))
 	if err != nil {
 		return err
 	}
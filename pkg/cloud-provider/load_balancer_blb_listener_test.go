@@ -92,6 +92,17 @@ func TestCreateListener(t *testing.T) {
 			Protocol: "UDP",
 			NodePort: 11,
 		},
+		{
+			Port:     80,
+			Protocol: "HTTP",
+			NodePort: 11,
+		},
+		{
+			Port:     443,
+			Protocol: "HTTPS",
+			NodePort: 11,
+			CertID:   "cert-test",
+		},
 	}
 	for _, pl := range pls {
 		err = cloud.createListener(ctx, lb, pl)
@@ -105,11 +116,6 @@ func TestCreateListener(t *testing.T) {
 			Protocol: "HTTPS",
 			NodePort: 11,
 		},
-		{
-			Port:     12,
-			Protocol: "HTTP",
-			NodePort: 11,
-		},
 		{
 			Port:     12,
 			Protocol: "test",
@@ -174,12 +180,36 @@ func TestUpdateListener(t *testing.T) {
 	}
 	pls = []PortListener{
 		{
-			Port:     12,
+			Port:     80,
 			Protocol: "HTTP",
 			NodePort: 11,
 		},
+		{
+			Port:     443,
+			Protocol: "HTTPS",
+			NodePort: 11,
+			CertID:   "cert-test",
+		},
+	}
+	for _, pl := range pls {
+		err = cloud.createListener(ctx, lb, pl)
+		if err != nil {
+			t.Errorf("createListener err, err: %v", err)
+		}
+		pl.NodePort = 15
+		err = cloud.updateListener(ctx, lb, pl)
+		if err != nil {
+			t.Errorf("updateListener err, err: %v", err)
+		}
+	}
+	pls = []PortListener{
 		{
 			Port:     12,
+			Protocol: "HTTP",
+			NodePort: 11,
+		},
+		{
+			Port:     443,
 			Protocol: "HTTPS",
 			NodePort: 11,
 		},
@@ -288,3 +318,395 @@ func TestReconcileListeners(t *testing.T) {
 	}
 	// to complate...
 }
+
+func TestReconcileHTTPListeners(t *testing.T) {
+	cloud, resp, err := beforeTestListener()
+	if err != nil {
+		t.Errorf("beforeTestListener err, err: %v", err)
+	}
+	ctx := context.Background()
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+			Annotations: map[string]string{
+				ServiceAnnotationLoadBalancerListenerProtocol: "80:HTTP,443:HTTPS",
+				ServiceAnnotationLoadBalancerCertID:           "cert-test",
+			},
+		},
+		Spec: api.ServiceSpec{
+			Ports: []api.ServicePort{
+				{
+					Name:     "http",
+					Port:     80,
+					Protocol: "TCP",
+					NodePort: 30080,
+				},
+				{
+					Name:     "https",
+					Port:     443,
+					Protocol: "TCP",
+					NodePort: 30443,
+				},
+			},
+		},
+	}
+	err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Errorf("reconcileListeners err, err %v", err)
+	}
+	lb := &blb.LoadBalancer{
+		BlbId: resp.LoadBalancerId,
+	}
+	pls, err := cloud.getAllListeners(ctx, lb)
+	if err != nil {
+		t.Errorf("getAllListeners err, err: %v", err)
+	}
+	if len(pls) != 2 {
+		t.Errorf("reconcileListeners err, get pls: %v", pls)
+	}
+	for _, pl := range pls {
+		if pl.Port == 80 && pl.Protocol != "HTTP" {
+			t.Errorf("reconcileListeners err, port 80 should be HTTP, get %v", pl)
+		}
+		if pl.Port == 443 && (pl.Protocol != "HTTPS" || pl.CertID != "cert-test") {
+			t.Errorf("reconcileListeners err, port 443 should be HTTPS, get %v", pl)
+		}
+	}
+
+	// switch port 80 back to TCP
+	svc.Annotations[ServiceAnnotationLoadBalancerListenerProtocol] = "443:HTTPS"
+	err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Errorf("reconcileListeners err, err %v", err)
+	}
+	pls, err = cloud.getAllListeners(ctx, lb)
+	if err != nil {
+		t.Errorf("getAllListeners err, err: %v", err)
+	}
+	if len(pls) != 2 {
+		t.Errorf("reconcileListeners err, get pls: %v", pls)
+	}
+	for _, pl := range pls {
+		if pl.Port == 80 && pl.Protocol != "TCP" {
+			t.Errorf("reconcileListeners err, port 80 should be TCP, get %v", pl)
+		}
+	}
+}
+
+func TestReconcileListenersHealthCheck(t *testing.T) {
+	cloud, resp, err := beforeTestListener()
+	if err != nil {
+		t.Errorf("beforeTestListener err, err: %v", err)
+	}
+	ctx := context.Background()
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: api.ServiceSpec{
+			Ports: []api.ServicePort{
+				{
+					Name:     "tcp",
+					Port:     11,
+					Protocol: "TCP",
+					NodePort: 12,
+				},
+				{
+					Name:     "udp",
+					Port:     13,
+					Protocol: "UDP",
+					NodePort: 14,
+				},
+			},
+		},
+	}
+	lb := &blb.LoadBalancer{
+		BlbId: resp.LoadBalancerId,
+	}
+	// without annotations, listeners converge to BLB defaults
+	err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Errorf("reconcileListeners err, err %v", err)
+	}
+	pls, err := cloud.getAllListeners(ctx, lb)
+	if err != nil {
+		t.Errorf("getAllListeners err, err: %v", err)
+	}
+	for _, pl := range pls {
+		if pl.Scheduler != defaultBLBScheduler || pl.HealthCheckInterval != defaultBLBHealthCheckInterval {
+			t.Errorf("reconcileListeners err, listener should use defaults, get %v", pl)
+		}
+	}
+
+	// changed annotations update the existing listeners
+	svc.Annotations = map[string]string{
+		ServiceAnnotationLoadBalancerScheduler:                  "Hash",
+		ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond: "10",
+		ServiceAnnotationLoadBalancerHealthCheckInterval:        "5",
+		ServiceAnnotationLoadBalancerUnhealthyThreshold:         "4",
+		ServiceAnnotationLoadBalancerHealthyThreshold:           "2",
+		ServiceAnnotationLoadBalancerHealthCheckString:          "ping",
+	}
+	err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Errorf("reconcileListeners err, err %v", err)
+	}
+	pls, err = cloud.getAllListeners(ctx, lb)
+	if err != nil {
+		t.Errorf("getAllListeners err, err: %v", err)
+	}
+	if len(pls) != 2 {
+		t.Errorf("reconcileListeners err, get pls: %v", pls)
+	}
+	for _, pl := range pls {
+		if pl.Scheduler != "Hash" || pl.HealthCheckTimeoutInSecond != 10 || pl.HealthCheckInterval != 5 ||
+			pl.UnhealthyThreshold != 4 || pl.HealthyThreshold != 2 {
+			t.Errorf("reconcileListeners err, listener not updated, get %v", pl)
+		}
+		if pl.Protocol == "UDP" && pl.HealthCheckString != "ping" {
+			t.Errorf("reconcileListeners err, udp health check string not updated, get %v", pl)
+		}
+	}
+}
+
+func TestReconcileListenersSessionAffinity(t *testing.T) {
+	cloud, resp, err := beforeTestListener()
+	if err != nil {
+		t.Errorf("beforeTestListener err, err: %v", err)
+	}
+	ctx := context.Background()
+	timeout := int32(600)
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+			Annotations: map[string]string{
+				ServiceAnnotationLoadBalancerListenerProtocol: "80:HTTP",
+			},
+		},
+		Spec: api.ServiceSpec{
+			Ports: []api.ServicePort{
+				{
+					Name:     "tcp",
+					Port:     11,
+					Protocol: "TCP",
+					NodePort: 12,
+				},
+				{
+					Name:     "udp",
+					Port:     13,
+					Protocol: "UDP",
+					NodePort: 14,
+				},
+				{
+					Name:     "http",
+					Port:     80,
+					Protocol: "TCP",
+					NodePort: 30080,
+				},
+			},
+			SessionAffinity: api.ServiceAffinityClientIP,
+			SessionAffinityConfig: &api.SessionAffinityConfig{
+				ClientIP: &api.ClientIPConfig{
+					TimeoutSeconds: &timeout,
+				},
+			},
+		},
+	}
+	lb := &blb.LoadBalancer{
+		BlbId: resp.LoadBalancerId,
+	}
+	err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Errorf("reconcileListeners err, err %v", err)
+	}
+	pls, err := cloud.getAllListeners(ctx, lb)
+	if err != nil {
+		t.Errorf("getAllListeners err, err: %v", err)
+	}
+	if len(pls) != 3 {
+		t.Errorf("reconcileListeners err, get pls: %v", pls)
+	}
+	for _, pl := range pls {
+		switch pl.Protocol {
+		case "TCP", "UDP":
+			if pl.Scheduler != "Hash" {
+				t.Errorf("reconcileListeners err, ClientIP affinity should use Hash scheduler, get %v", pl)
+			}
+		case "HTTP":
+			if !pl.KeepSession || pl.KeepSessionDuration != 600 {
+				t.Errorf("reconcileListeners err, ClientIP affinity should keep session, get %v", pl)
+			}
+		}
+	}
+
+	// revert to None
+	svc.Spec.SessionAffinity = api.ServiceAffinityNone
+	svc.Spec.SessionAffinityConfig = nil
+	err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Errorf("reconcileListeners err, err %v", err)
+	}
+	pls, err = cloud.getAllListeners(ctx, lb)
+	if err != nil {
+		t.Errorf("getAllListeners err, err: %v", err)
+	}
+	for _, pl := range pls {
+		switch pl.Protocol {
+		case "TCP", "UDP":
+			if pl.Scheduler != defaultBLBScheduler {
+				t.Errorf("reconcileListeners err, scheduler should be reverted, get %v", pl)
+			}
+		case "HTTP":
+			if pl.KeepSession {
+				t.Errorf("reconcileListeners err, keep session should be reverted, get %v", pl)
+			}
+		}
+	}
+}
+
+func TestReconcileListenersSamePortMixedProtocol(t *testing.T) {
+	cloud, resp, err := beforeTestListener()
+	if err != nil {
+		t.Errorf("beforeTestListener err, err: %v", err)
+	}
+	ctx := context.Background()
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: api.ServiceSpec{
+			Ports: []api.ServicePort{
+				{
+					Name:     "dns-tcp",
+					Port:     53,
+					Protocol: "TCP",
+					NodePort: 30053,
+				},
+				{
+					Name:     "dns-udp",
+					Port:     53,
+					Protocol: "UDP",
+					NodePort: 30053,
+				},
+			},
+		},
+	}
+	lb := &blb.LoadBalancer{
+		BlbId: resp.LoadBalancerId,
+	}
+	for i := 0; i < 2; i++ {
+		err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+		if err != nil {
+			t.Errorf("reconcileListeners err, err %v", err)
+		}
+		pls, err := cloud.getAllListeners(ctx, lb)
+		if err != nil {
+			t.Errorf("getAllListeners err, err: %v", err)
+		}
+		if len(pls) != 2 || pls[0].Port != 53 || pls[1].Port != 53 || pls[0].Protocol == pls[1].Protocol {
+			t.Errorf("reconcileListeners err, should have 53/TCP and 53/UDP listeners, get pls: %v", pls)
+		}
+	}
+
+	// delete 53/UDP only
+	err = cloud.deleteListener(ctx, lb, []PortListener{{Port: 53, Protocol: "UDP"}})
+	if err != nil {
+		t.Errorf("deleteListener err, err: %v", err)
+	}
+	pls, err := cloud.getAllListeners(ctx, lb)
+	if err != nil {
+		t.Errorf("getAllListeners err, err: %v", err)
+	}
+	if len(pls) != 1 || pls[0].Protocol != "TCP" {
+		t.Errorf("deleteListener err, only 53/TCP should be left, get pls: %v", pls)
+	}
+}
+
+func TestReconcileListenersLocalHealthCheck(t *testing.T) {
+	cloud, resp, err := beforeTestListener()
+	if err != nil {
+		t.Errorf("beforeTestListener err, err: %v", err)
+	}
+	ctx := context.Background()
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+			Annotations: map[string]string{
+				ServiceAnnotationLoadBalancerListenerProtocol: "80:HTTP",
+			},
+		},
+		Spec: api.ServiceSpec{
+			Ports: []api.ServicePort{
+				{
+					Name:     "tcp",
+					Port:     11,
+					Protocol: "TCP",
+					NodePort: 12,
+				},
+				{
+					Name:     "http",
+					Port:     80,
+					Protocol: "TCP",
+					NodePort: 30080,
+				},
+				{
+					Name:     "dns",
+					Port:     53,
+					Protocol: "UDP",
+					NodePort: 30053,
+				},
+			},
+			ExternalTrafficPolicy: api.ServiceExternalTrafficPolicyTypeLocal,
+			HealthCheckNodePort:   32000,
+		},
+	}
+	lb := &blb.LoadBalancer{
+		BlbId: resp.LoadBalancerId,
+	}
+	// listeners check kube-proxy on HealthCheckNodePort
+	err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Errorf("reconcileListeners err, err %v", err)
+	}
+	pls, err := cloud.getAllListeners(ctx, lb)
+	if err != nil {
+		t.Errorf("getAllListeners err, err: %v", err)
+	}
+	if len(pls) != 3 {
+		t.Errorf("reconcileListeners err, get pls: %v", pls)
+	}
+	for _, pl := range pls {
+		if pl.Protocol == "UDP" {
+			// UDP listener can not check /healthz
+			if pl.HealthCheckPort != 0 || pl.HealthCheckURI != "" {
+				t.Errorf("reconcileListeners err, UDP listener should not check HealthCheckNodePort, get %v", pl)
+			}
+			continue
+		}
+		if pl.HealthCheckType != "HTTP" || pl.HealthCheckPort != 32000 || pl.HealthCheckURI != "/healthz" {
+			t.Errorf("reconcileListeners err, listener should check HealthCheckNodePort, get %v", pl)
+		}
+	}
+
+	// back to Cluster, listeners check the port of backends again
+	svc.Spec.ExternalTrafficPolicy = api.ServiceExternalTrafficPolicyTypeCluster
+	svc.Spec.HealthCheckNodePort = 0
+	err = cloud.reconcileListeners(ctx, cloud.ClusterName, svc)
+	if err != nil {
+		t.Errorf("reconcileListeners err, err %v", err)
+	}
+	pls, err = cloud.getAllListeners(ctx, lb)
+	if err != nil {
+		t.Errorf("getAllListeners err, err: %v", err)
+	}
+	for _, pl := range pls {
+		if pl.HealthCheckPort != 0 {
+			t.Errorf("reconcileListeners err, listener should not check HealthCheckNodePort, get %v", pl)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+
+	tempblb "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
+	tempvpc "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-vpc"
+)
+
+// getSourceRanges returns the normalized Spec.LoadBalancerSourceRanges of service
+func getSourceRanges(service *v1.Service) ([]string, error) {
+	var sourceRanges []string
+	seen := make(map[string]bool)
+	for _, sourceRange := range service.Spec.LoadBalancerSourceRanges {
+		network, err := ParseIPv4CIDR(sourceRange)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LoadBalancerSourceRanges %q: %v", sourceRange, err)
+		}
+		cidr := network.String()
+		if !seen[cidr] {
+			seen[cidr] = true
+			sourceRanges = append(sourceRanges, cidr)
+		}
+	}
+	return sourceRanges, nil
+}
+
+func getSecurityGroupName(clusterID string, service *v1.Service) string {
+	return fmt.Sprintf("CCE/SVC/%s/%s/%s/source-ranges", clusterID, service.Namespace, service.Name)
+}
+
+// reconcileSourceRanges restricts the sources which can access the BLB to Spec.LoadBalancerSourceRanges
+// by binding a security group to the BLB, the security group is removed when the field is cleared
+func (bc *Baiducloud) reconcileSourceRanges(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer) error {
+	startTime := time.Now()
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	defer func() {
+		klog.V(4).Infof(Message(ctx, fmt.Sprintf("Finished reconcileSourceRanges for service %q (%v)", serviceKey, time.Since(startTime))))
+	}()
+	sourceRanges, err := getSourceRanges(service)
+	if err != nil {
+		return err
+	}
+	sg, exist, err := bc.getServiceSecurityGroup(ctx, service, lb)
+	if err != nil {
+		return err
+	}
+
+	if len(sourceRanges) == 0 {
+		if !exist {
+			return nil
+		}
+		klog.Infof(Message(ctx, fmt.Sprintf("reconcileSourceRanges for service %s: LoadBalancerSourceRanges cleared, delete security group %s", serviceKey, sg.ID)))
+		err = bc.deleteServiceSecurityGroup(ctx, lb, sg)
+		if err != nil {
+			return err
+		}
+		// the deleted group may still be returned for its client token if source ranges are set again
+		return bc.bumpClientTokenKind(service)
+	}
+
+	if exist {
+		return bc.reconcileSecurityGroupRules(ctx, sg, sourceRanges)
+	}
+
+	sg, err = bc.createServiceSecurityGroup(ctx, service, sourceRanges)
+	if err != nil {
+		return err
+	}
+	klog.Infof(Message(ctx, fmt.Sprintf("reconcileSourceRanges for service %s: bind security group %s to BLB %s", serviceKey, sg.ID, lb.BlbId)))
+	args := tempblb.SecurityGroupsArgs{
+		LoadBalancerId:   lb.BlbId,
+		SecurityGroupIds: []string{sg.ID},
+	}
+	return bc.clientSet.BLBSecurityGroupClient.BindSecurityGroups(ctx, &args, bc.getSignOption(ctx))
+}
+
+// ensureSourceRangesDeleted unbinds and deletes the security group created for service if it exists
+func (bc *Baiducloud) ensureSourceRangesDeleted(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer) error {
+	sg, exist, err := bc.getServiceSecurityGroup(ctx, service, lb)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return nil
+	}
+	return bc.deleteServiceSecurityGroup(ctx, lb, sg)
+}
+
+// getServiceSecurityGroup finds the security group of service among the ones bound to lb by name,
+// which contains cluster id and is unique in region
+func (bc *Baiducloud) getServiceSecurityGroup(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer) (*tempvpc.SecurityGroup, bool, error) {
+	if lb == nil || lb.BlbId == "" {
+		return nil, false, nil
+	}
+	args := tempblb.DescribeSecurityGroupsArgs{
+		LoadBalancerId: lb.BlbId,
+	}
+	bound, err := bc.clientSet.BLBSecurityGroupClient.DescribeSecurityGroups(ctx, &args, bc.getSignOption(ctx))
+	if err != nil {
+		return nil, false, err
+	}
+	sgName := getSecurityGroupName(bc.ClusterID, service)
+	for _, b := range bound {
+		sg, err := bc.clientSet.VPCSecurityGroupClient.GetSecurityGroup(ctx, b.SecurityGroupId, bc.getSignOption(ctx))
+		if err != nil {
+			return nil, false, err
+		}
+		if sg.Name == sgName {
+			return sg, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func (bc *Baiducloud) createServiceSecurityGroup(ctx context.Context, service *v1.Service, sourceRanges []string) (*tempvpc.SecurityGroup, error) {
+	vpcID, err := bc.getVpcID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules := []tempvpc.SecurityGroupRule{
+		{
+			Direction: "egress",
+			Ethertype: "IPv4",
+			Protocol:  "all",
+			PortRange: "1-65535",
+			DestIP:    "0.0.0.0/0",
+		},
+	}
+	for _, sourceRange := range sourceRanges {
+		rules = append(rules, buildSourceRangeRule(sourceRange))
+	}
+	args := tempvpc.CreateSecurityGroupArgs{
+		Name:  getSecurityGroupName(bc.ClusterID, service),
+		VpcID: vpcID,
+		Desc:  "auto generated by cce:" + bc.ClusterID,
+		Rules: rules,
+		// the group created by a retry after a lost response is returned instead of creating another one
+		ClientToken: getClientToken(bc.ClusterID, service, bc.clientTokenKind(service, clientTokenKindSecurityGroup)),
+	}
+	sgID, err := bc.clientSet.VPCSecurityGroupClient.CreateSecurityGroup(ctx, &args, bc.getSignOption(ctx))
+	if err != nil {
+		return nil, err
+	}
+	klog.Infof(Message(ctx, fmt.Sprintf("create security group %s for service %s/%s with source ranges %v", sgID, service.Namespace, service.Name, sourceRanges)))
+	return &tempvpc.SecurityGroup{
+		ID:    sgID,
+		Name:  args.Name,
+		VpcID: vpcID,
+		Rules: rules,
+	}, nil
+}
+
+// reconcileSecurityGroupRules makes the ingress rules of sg match sourceRanges
+func (bc *Baiducloud) reconcileSecurityGroupRules(ctx context.Context, sg *tempvpc.SecurityGroup, sourceRanges []string) error {
+	expected := make(map[string]bool)
+	for _, sourceRange := range sourceRanges {
+		expected[sourceRange] = true
+	}
+	for _, rule := range sg.Rules {
+		if rule.Direction != "ingress" {
+			continue
+		}
+		if expected[rule.SourceIP] {
+			delete(expected, rule.SourceIP)
+			continue
+		}
+		klog.Infof(Message(ctx, fmt.Sprintf("revoke source range %s from security group %s", rule.SourceIP, sg.ID)))
+		args := tempvpc.SecurityGroupRuleArgs{
+			SecurityGroupID: sg.ID,
+			Rule:            rule,
+		}
+		err := bc.clientSet.VPCSecurityGroupClient.RevokeSecurityGroupRule(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+	}
+	for _, sourceRange := range sourceRanges {
+		if !expected[sourceRange] {
+			continue
+		}
+		klog.Infof(Message(ctx, fmt.Sprintf("authorize source range %s to security group %s", sourceRange, sg.ID)))
+		args := tempvpc.SecurityGroupRuleArgs{
+			SecurityGroupID: sg.ID,
+			Rule:            buildSourceRangeRule(sourceRange),
+		}
+		err := bc.clientSet.VPCSecurityGroupClient.AuthorizeSecurityGroupRule(ctx, &args, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (bc *Baiducloud) isSecurityGroupBound(ctx context.Context, lb *blb.LoadBalancer, sgID string) (bool, error) {
+	args := tempblb.DescribeSecurityGroupsArgs{
+		LoadBalancerId: lb.BlbId,
+	}
+	sgs, err := bc.clientSet.BLBSecurityGroupClient.DescribeSecurityGroups(ctx, &args, bc.getSignOption(ctx))
+	if err != nil {
+		return false, err
+	}
+	for _, sg := range sgs {
+		if sg.SecurityGroupId == sgID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (bc *Baiducloud) deleteServiceSecurityGroup(ctx context.Context, lb *blb.LoadBalancer, sg *tempvpc.SecurityGroup) error {
+	if lb != nil && lb.BlbId != "" {
+		bound, err := bc.isSecurityGroupBound(ctx, lb, sg.ID)
+		if err != nil {
+			return err
+		}
+		if bound {
+			args := tempblb.SecurityGroupsArgs{
+				LoadBalancerId:   lb.BlbId,
+				SecurityGroupIds: []string{sg.ID},
+			}
+			err = bc.clientSet.BLBSecurityGroupClient.UnbindSecurityGroups(ctx, &args, bc.getSignOption(ctx))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return bc.clientSet.VPCSecurityGroupClient.DeleteSecurityGroup(ctx, sg.ID, bc.getSignOption(ctx))
+}
+
+func buildSourceRangeRule(sourceRange string) tempvpc.SecurityGroupRule {
+	return tempvpc.SecurityGroupRule{
+		Direction: "ingress",
+		Ethertype: "IPv4",
+		Protocol:  "all",
+		PortRange: "1-65535",
+		SourceIP:  sourceRange,
+	}
+}
@@ -0,0 +1,108 @@
+package cloud_provider
+
+import (
+	"context"
+	"testing"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+
+	tempvpc "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-vpc"
+)
+
+func TestReconcileSourceRanges(t *testing.T) {
+	cloud, _, resp, err := beforeTestBlb()
+	if err != nil {
+		t.Errorf("beforeTestBlb err, err: %v", err)
+	}
+	ctx := context.Background()
+	lb := &blb.LoadBalancer{
+		BlbId: resp.LoadBalancerId,
+	}
+	svc := buildService()
+
+	// no source ranges, nothing to do
+	err = cloud.reconcileSourceRanges(ctx, svc, lb)
+	if err != nil {
+		t.Errorf("reconcileSourceRanges err, err: %v", err)
+	}
+	_, exist, err := cloud.getServiceSecurityGroup(ctx, svc, lb)
+	if err != nil || exist {
+		t.Errorf("getServiceSecurityGroup should not exist, exist: %v, err: %v", exist, err)
+	}
+
+	// create and bind security group
+	svc.Spec.LoadBalancerSourceRanges = []string{"10.0.0.0/8", "192.168.1.1/24"}
+	err = cloud.reconcileSourceRanges(ctx, svc, lb)
+	if err != nil {
+		t.Errorf("reconcileSourceRanges err, err: %v", err)
+	}
+	sg, exist, err := cloud.getServiceSecurityGroup(ctx, svc, lb)
+	if err != nil || !exist {
+		t.Fatalf("getServiceSecurityGroup should exist, exist: %v, err: %v", exist, err)
+	}
+	bound, err := cloud.isSecurityGroupBound(ctx, lb, sg.ID)
+	if err != nil || !bound {
+		t.Errorf("security group %s should be bound, bound: %v, err: %v", sg.ID, bound, err)
+	}
+	if sources := getIngressSources(sg.Rules); len(sources) != 2 || !sources["10.0.0.0/8"] || !sources["192.168.1.0/24"] {
+		t.Errorf("reconcileSourceRanges err, get ingress sources: %v", sources)
+	}
+
+	// update source ranges
+	svc.Spec.LoadBalancerSourceRanges = []string{"10.0.0.0/8", "172.16.0.0/12"}
+	err = cloud.reconcileSourceRanges(ctx, svc, lb)
+	if err != nil {
+		t.Errorf("reconcileSourceRanges err, err: %v", err)
+	}
+	sg, _, _ = cloud.getServiceSecurityGroup(ctx, svc, lb)
+	if sources := getIngressSources(sg.Rules); len(sources) != 2 || !sources["10.0.0.0/8"] || !sources["172.16.0.0/12"] {
+		t.Errorf("reconcileSourceRanges err, get ingress sources: %v", sources)
+	}
+
+	// clear source ranges
+	svc.Spec.LoadBalancerSourceRanges = nil
+	err = cloud.reconcileSourceRanges(ctx, svc, lb)
+	if err != nil {
+		t.Errorf("reconcileSourceRanges err, err: %v", err)
+	}
+	_, exist, err = cloud.getServiceSecurityGroup(ctx, svc, lb)
+	if err != nil || exist {
+		t.Errorf("security group should be deleted, exist: %v, err: %v", exist, err)
+	}
+	bound, err = cloud.isSecurityGroupBound(ctx, lb, sg.ID)
+	if err != nil || bound {
+		t.Errorf("security group %s should be unbound, bound: %v, err: %v", sg.ID, bound, err)
+	}
+	if _, err := cloud.clientSet.VPCSecurityGroupClient.GetSecurityGroup(ctx, sg.ID, nil); err == nil {
+		t.Errorf("security group %s should be deleted", sg.ID)
+	}
+
+	// the group deleted before is not returned for the client token when source ranges are set again
+	if cloud.clientTokenKind(svc, clientTokenKindSecurityGroup) == clientTokenKindSecurityGroup {
+		t.Errorf("client token should change after security group deleted")
+	}
+	// a retry after a lost response gets the same group
+	svc.Spec.LoadBalancerSourceRanges = []string{"10.0.0.0/8"}
+	created, err := cloud.createServiceSecurityGroup(ctx, svc, svc.Spec.LoadBalancerSourceRanges)
+	if err != nil {
+		t.Fatalf("createServiceSecurityGroup err, err: %v", err)
+	}
+	err = cloud.reconcileSourceRanges(ctx, svc, lb)
+	if err != nil {
+		t.Errorf("reconcileSourceRanges err, err: %v", err)
+	}
+	sg, exist, err = cloud.getServiceSecurityGroup(ctx, svc, lb)
+	if err != nil || !exist || sg.ID != created.ID {
+		t.Errorf("security group %s should be bound, get %v, exist: %v, err: %v", created.ID, sg, exist, err)
+	}
+}
+
+func getIngressSources(rules []tempvpc.SecurityGroupRule) map[string]bool {
+	sources := make(map[string]bool)
+	for _, rule := range rules {
+		if rule.Direction == "ingress" {
+			sources[rule.SourceIP] = true
+		}
+	}
+	return sources
+}
@@ -0,0 +1,316 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"fmt"
+	"reflect"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+
+	cce_v1alpha1 "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/apis/cce/v1alpha1"
+)
+
+// extractServiceAnnotation extracts annotations from service, settings of the LoadBalancerConfig referred by service take precedence
+func (bc *Baiducloud) extractServiceAnnotation(service *v1.Service) (*ServiceAnnotation, error) {
+	anno, err := ExtractServiceAnnotation(service)
+	if err != nil {
+		return nil, err
+	}
+	name := service.Annotations[ServiceAnnotationLoadBalancerConfig]
+	if name == "" {
+		return anno, nil
+	}
+	config, err := bc.getLoadBalancerConfig(service.Namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	klog.V(4).Infof("apply LoadBalancerConfig %s/%s to service %s: %+v", service.Namespace, name, service.Name, config.Spec)
+	if err := applyLoadBalancerConfig(anno, &config.Spec); err != nil {
+		return nil, fmt.Errorf("LoadBalancerConfig %s/%s is invalid: %v", service.Namespace, name, err)
+	}
+	return anno, nil
+}
+
+func (bc *Baiducloud) getLoadBalancerConfig(namespace, name string) (*cce_v1alpha1.LoadBalancerConfig, error) {
+	if bc.loadBalancerConfigLister == nil {
+		return nil, fmt.Errorf("get LoadBalancerConfig %s/%s failed: informer is not initialized", namespace, name)
+	}
+	obj, err := bc.loadBalancerConfigLister.ByNamespace(namespace).Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("get LoadBalancerConfig %s/%s failed: %v", namespace, name, err)
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("get LoadBalancerConfig %s/%s failed: unexpected object %T", namespace, name, obj)
+	}
+	config := &cce_v1alpha1.LoadBalancerConfig{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), config); err != nil {
+		return nil, fmt.Errorf("convert LoadBalancerConfig %s/%s failed: %v", namespace, name, err)
+	}
+	return config, nil
+}
+
+// setLoadBalancerConfigInformer caches LoadBalancerConfigs by informer, services referring to a config
+// are synced again by service controller after the spec of config changes
+func (bc *Baiducloud) setLoadBalancerConfigInformer(informer informers.GenericInformer) {
+	bc.loadBalancerConfigLister = informer.Lister()
+	informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if config, ok := obj.(*unstructured.Unstructured); ok {
+				bc.syncLoadBalancerConfigServices(config, config.GetResourceVersion())
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldConfig, ok1 := oldObj.(*unstructured.Unstructured)
+			newConfig, ok2 := newObj.(*unstructured.Unstructured)
+			if !ok1 || !ok2 || reflect.DeepEqual(oldConfig.Object["spec"], newConfig.Object["spec"]) {
+				return
+			}
+			bc.syncLoadBalancerConfigServices(newConfig, newConfig.GetResourceVersion())
+		},
+		DeleteFunc: func(obj interface{}) {
+			config, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					return
+				}
+				if config, ok = tombstone.Obj.(*unstructured.Unstructured); !ok {
+					return
+				}
+			}
+			bc.syncLoadBalancerConfigServices(config, "")
+		},
+	})
+}
+
+// syncLoadBalancerConfigServices records version of config in annotation of the LoadBalancer services referring to it,
+// the annotation changed makes service controller sync their BLBs with the config again
+func (bc *Baiducloud) syncLoadBalancerConfigServices(config *unstructured.Unstructured, version string) {
+	if bc.serviceLister == nil {
+		return
+	}
+	services, err := bc.serviceLister.Services(config.GetNamespace()).List(labels.Everything())
+	if err != nil {
+		klog.Errorf("list services referring to LoadBalancerConfig %s/%s failed: %v", config.GetNamespace(), config.GetName(), err)
+		return
+	}
+	for _, service := range services {
+		if service.Spec.Type != v1.ServiceTypeLoadBalancer || service.DeletionTimestamp != nil ||
+			service.Annotations[ServiceAnnotationLoadBalancerConfig] != config.GetName() ||
+			service.Annotations[ServiceAnnotationCceLoadBalancerConfigVersion] == version {
+			continue
+		}
+		klog.Infof("LoadBalancerConfig %s/%s changed, sync service %s again", config.GetNamespace(), config.GetName(), service.Name)
+		if err := bc.updateServiceAnnotation(service.DeepCopy(), ServiceAnnotationCceLoadBalancerConfigVersion, version); err != nil {
+			klog.Errorf("update annotation %s of service %s/%s failed: %v", ServiceAnnotationCceLoadBalancerConfigVersion, service.Namespace, service.Name, err)
+		}
+	}
+}
+
+// applyLoadBalancerConfig overrides anno with the settings set in spec, spec is validated again
+// since the OpenAPI validation of the CRD may be skipped by old apiservers
+func applyLoadBalancerConfig(anno *ServiceAnnotation, spec *cce_v1alpha1.LoadBalancerConfigSpec) error {
+	if spec.Scheduler != "" {
+		if err := validateScheduler("spec.scheduler", spec.Scheduler); err != nil {
+			return err
+		}
+		anno.LoadBalancerScheduler = spec.Scheduler
+	}
+
+	if hc := spec.HealthCheck; hc != nil {
+		if err := validateHealthCheck("spec.healthCheck", hc); err != nil {
+			return err
+		}
+		anno.LoadBalancerHealthCheckTimeoutInSecond = int(hc.TimeoutInSecond)
+		anno.LoadBalancerHealthCheckInterval = int(hc.IntervalInSecond)
+		anno.LoadBalancerUnhealthyThreshold = int(hc.UnhealthyThreshold)
+		anno.LoadBalancerHealthyThreshold = int(hc.HealthyThreshold)
+		anno.LoadBalancerHealthCheckString = hc.HealthCheckString
+	}
+
+	for i, l := range spec.Listeners {
+		path := fmt.Sprintf("spec.listeners[%d]", i)
+		if l.Port < 1 || l.Port > 65535 {
+			return fmt.Errorf("%s.port must be in [1, 65535]", path)
+		}
+		port := int(l.Port)
+		if _, ok := anno.LoadBalancerListeners[port]; ok {
+			return fmt.Errorf("%s.port %d is duplicated", path, port)
+		}
+		if l.Protocol != "" {
+			switch l.Protocol {
+			case "TCP", "UDP", "HTTP", "HTTPS":
+			default:
+				return fmt.Errorf("%s.protocol must be TCP, UDP, HTTP or HTTPS", path)
+			}
+			if anno.LoadBalancerListenerProtocol == nil {
+				anno.LoadBalancerListenerProtocol = make(map[int]string)
+			}
+			anno.LoadBalancerListenerProtocol[port] = l.Protocol
+		}
+		if l.Scheduler != "" {
+			if err := validateScheduler(path+".scheduler", l.Scheduler); err != nil {
+				return err
+			}
+		}
+		lc := ListenerConfig{
+			CertID:    l.CertID,
+			Scheduler: l.Scheduler,
+		}
+		if hc := l.HealthCheck; hc != nil {
+			if err := validateHealthCheck(path+".healthCheck", hc); err != nil {
+				return err
+			}
+			lc.HealthCheckTimeoutInSecond = int(hc.TimeoutInSecond)
+			lc.HealthCheckInterval = int(hc.IntervalInSecond)
+			lc.UnhealthyThreshold = int(hc.UnhealthyThreshold)
+			lc.HealthyThreshold = int(hc.HealthyThreshold)
+			lc.HealthCheckString = hc.HealthCheckString
+		}
+		if anno.LoadBalancerListeners == nil {
+			anno.LoadBalancerListeners = make(map[int]ListenerConfig)
+		}
+		anno.LoadBalancerListeners[port] = lc
+	}
+
+	// EIP settings other than billingChangePolicy and driftPolicy are validated by getEipArgsFromAnnotation
+	if e := spec.ElasticIP; e != nil {
+		if e.Name != "" {
+			anno.ElasticIPName = e.Name
+		}
+		if e.PaymentTiming != "" {
+			anno.ElasticIPPaymentTiming = e.PaymentTiming
+		}
+		if e.BillingMethod != "" {
+			anno.ElasticIPBillingMethod = e.BillingMethod
+		}
+		if e.BandwidthInMbps != 0 {
+			anno.ElasticIPBandwidthInMbps = int(e.BandwidthInMbps)
+		}
+		if e.ReservationLength != 0 {
+			anno.ElasticIPReservationLength = int(e.ReservationLength)
+		}
+		if e.Group != "" {
+			anno.ElasticIPGroup = e.Group
+		}
+		if e.RouteType != "" {
+			anno.ElasticIPRouteType = e.RouteType
+		}
+		if e.AutoRenewLength != 0 {
+			anno.ElasticIPAutoRenewLength = int(e.AutoRenewLength)
+		}
+		if len(e.Tags) != 0 {
+			anno.ElasticIPTags = e.Tags
+		}
+		if e.BillingChangePolicy != "" {
+			switch e.BillingChangePolicy {
+			case EIPBillingChangeReject, EIPBillingChangeRecreate, EIPBillingChangeInPlace:
+			default:
+				return fmt.Errorf("spec.elasticIP.billingChangePolicy must be %s, %s or %s", EIPBillingChangeReject, EIPBillingChangeRecreate, EIPBillingChangeInPlace)
+			}
+			anno.ElasticIPBillingChangePolicy = e.BillingChangePolicy
+		}
+		if e.DriftPolicy != "" {
+			switch e.DriftPolicy {
+			case EIPDriftReport, EIPDriftRebind, EIPDriftRepair:
+			default:
+				return fmt.Errorf("spec.elasticIP.driftPolicy must be %s, %s or %s", EIPDriftReport, EIPDriftRebind, EIPDriftRepair)
+			}
+			anno.ElasticIPDriftPolicy = e.DriftPolicy
+		}
+	}
+
+	if b := spec.Backend; b != nil {
+		if b.Type != "" {
+			switch b.Type {
+			case BackendTypeNode, BackendTypePod:
+			default:
+				return fmt.Errorf("spec.backend.type must be %s or %s", BackendTypeNode, BackendTypePod)
+			}
+			anno.LoadBalancerBackendType = b.Type
+		}
+		if b.RsMaxNum != 0 {
+			if err := checkRange("spec.backend.rsMaxNum", b.RsMaxNum, 0, int32(blbMaxRSNum)); err != nil {
+				return err
+			}
+			anno.LoadBalancerRsMaxNum = int(b.RsMaxNum)
+		}
+		if b.DrainPeriodInSecond != 0 {
+			if err := checkRange("spec.backend.drainPeriodInSecond", b.DrainPeriodInSecond, 0, 3600); err != nil {
+				return err
+			}
+			anno.LoadBalancerRsDrainPeriodInSecond = int(b.DrainPeriodInSecond)
+		}
+		if b.NodeSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(b.NodeSelector)
+			if err != nil {
+				return fmt.Errorf("spec.backend.nodeSelector is invalid: %v", err)
+			}
+			anno.LoadBalancerNodeSelector = selector
+		}
+	}
+	return nil
+}
+
+func validateScheduler(path, scheduler string) error {
+	switch scheduler {
+	case "RoundRobin", "LeastConnection", "Hash":
+		return nil
+	}
+	return fmt.Errorf("%s must be RoundRobin, LeastConnection or Hash", path)
+}
+
+// validateHealthCheck validates the fields set, unset fields are filled with BLB defaults
+func validateHealthCheck(path string, hc *cce_v1alpha1.HealthCheck) error {
+	if hc.TimeoutInSecond != 0 {
+		if err := checkRange(path+".timeoutInSecond", hc.TimeoutInSecond, 1, 60); err != nil {
+			return err
+		}
+	}
+	if hc.IntervalInSecond != 0 {
+		if err := checkRange(path+".intervalInSecond", hc.IntervalInSecond, 1, 10); err != nil {
+			return err
+		}
+	}
+	if hc.UnhealthyThreshold != 0 {
+		if err := checkRange(path+".unhealthyThreshold", hc.UnhealthyThreshold, 2, 5); err != nil {
+			return err
+		}
+	}
+	if hc.HealthyThreshold != 0 {
+		if err := checkRange(path+".healthyThreshold", hc.HealthyThreshold, 2, 5); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkRange(path string, value, min, max int32) error {
+	if value < min || value > max {
+		return fmt.Errorf("%s must be in [%d, %d]", path, min, max)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package cloud_provider
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	cce_v1alpha1 "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/apis/cce/v1alpha1"
+)
+
+func newFakeLoadBalancerConfig(t *testing.T, name string, spec cce_v1alpha1.LoadBalancerConfigSpec) runtime.Object {
+	config := &cce_v1alpha1.LoadBalancerConfig{
+		TypeMeta: meta_v1.TypeMeta{
+			APIVersion: cce_v1alpha1.SchemeGroupVersion.String(),
+			Kind:       "LoadBalancerConfig",
+		},
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      name,
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: spec,
+	}
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(config)
+	if err != nil {
+		t.Fatalf("ToUnstructured failed: %v", err)
+	}
+	return &unstructured.Unstructured{Object: obj}
+}
+
+// newFakeLoadBalancerConfigLister returns a lister of configs as LoadBalancerConfig informer caches them
+func newFakeLoadBalancerConfigLister(t *testing.T, configs ...runtime.Object) cache.GenericLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, config := range configs {
+		if err := indexer.Add(config); err != nil {
+			t.Fatalf("add LoadBalancerConfig failed: %v", err)
+		}
+	}
+	return cache.NewGenericLister(indexer, cce_v1alpha1.LoadBalancerConfigResource.GroupResource())
+}
+
+func TestExtractServiceAnnotationWithLoadBalancerConfig(t *testing.T) {
+	cloud := NewFakeCloud("c-config")
+	cloud.loadBalancerConfigLister = newFakeLoadBalancerConfigLister(t,
+		newFakeLoadBalancerConfig(t, "lbc", cce_v1alpha1.LoadBalancerConfigSpec{
+			Scheduler: "LeastConnection",
+			HealthCheck: &cce_v1alpha1.HealthCheck{
+				TimeoutInSecond:    5,
+				IntervalInSecond:   3,
+				UnhealthyThreshold: 3,
+				HealthyThreshold:   3,
+			},
+			Listeners: []cce_v1alpha1.Listener{
+				{Port: 443, Protocol: "HTTPS", CertID: "cert-443"},
+				{Port: 53, Scheduler: "Hash", HealthCheck: &cce_v1alpha1.HealthCheck{TimeoutInSecond: 10}},
+			},
+			ElasticIP: &cce_v1alpha1.ElasticIP{BillingMethod: "ByBandwidth", BandwidthInMbps: 100},
+			Backend: &cce_v1alpha1.Backend{
+				Type:         BackendTypePod,
+				NodeSelector: &meta_v1.LabelSelector{MatchLabels: map[string]string{"pool": "lb"}},
+			},
+		}),
+		newFakeLoadBalancerConfig(t, "invalid", cce_v1alpha1.LoadBalancerConfigSpec{
+			HealthCheck: &cce_v1alpha1.HealthCheck{UnhealthyThreshold: 10},
+		}),
+	)
+
+	// settings not in LoadBalancerConfig fall back to annotations
+	svc := buildService()
+	svc.Annotations = map[string]string{
+		ServiceAnnotationLoadBalancerConfig:                     "lbc",
+		ServiceAnnotationLoadBalancerScheduler:                  "RoundRobin",
+		ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond: "20",
+		ServiceAnnotationLoadBalancerListenerProtocol:           "80:HTTP",
+		ServiceAnnotationLoadBalancerCertID:                     "cert-default",
+		ServiceAnnotationElasticIPBandwidthInMbps:               "1",
+		ServiceAnnotationLoadBalancerRsDrainPeriodInSecond:      "30",
+		ServiceAnnotationElasticIPName:                          "eip-anno",
+	}
+	anno, err := cloud.extractServiceAnnotation(svc)
+	if err != nil {
+		t.Fatalf("extractServiceAnnotation failed: %v", err)
+	}
+	if anno.LoadBalancerScheduler != "LeastConnection" {
+		t.Errorf("expected scheduler LeastConnection, got %s", anno.LoadBalancerScheduler)
+	}
+	if anno.LoadBalancerHealthCheckTimeoutInSecond != 5 {
+		t.Errorf("expected health check timeout 5, got %d", anno.LoadBalancerHealthCheckTimeoutInSecond)
+	}
+	if anno.LoadBalancerListenerProtocol[80] != "HTTP" || anno.LoadBalancerListenerProtocol[443] != "HTTPS" {
+		t.Errorf("expected listener protocol 80:HTTP,443:HTTPS, got %v", anno.LoadBalancerListenerProtocol)
+	}
+	if lc := anno.listenerConfig(443); lc.CertID != "cert-443" || lc.Scheduler != "LeastConnection" {
+		t.Errorf("unexpected listener config of port 443: %+v", lc)
+	}
+	if lc := anno.listenerConfig(53); lc.Scheduler != "Hash" || lc.HealthCheckTimeoutInSecond != 10 || lc.HealthCheckInterval != 3 || lc.CertID != "cert-default" {
+		t.Errorf("unexpected listener config of port 53: %+v", lc)
+	}
+	// fields not set in elasticIP and backend fall back to annotations
+	if anno.ElasticIPBillingMethod != "ByBandwidth" || anno.ElasticIPBandwidthInMbps != 100 || anno.ElasticIPName != "eip-anno" {
+		t.Errorf("unexpected EIP config: %s %d %s", anno.ElasticIPBillingMethod, anno.ElasticIPBandwidthInMbps, anno.ElasticIPName)
+	}
+	if anno.LoadBalancerBackendType != BackendTypePod || anno.LoadBalancerRsDrainPeriodInSecond != 30 {
+		t.Errorf("unexpected backend config: %s %d", anno.LoadBalancerBackendType, anno.LoadBalancerRsDrainPeriodInSecond)
+	}
+	if anno.LoadBalancerNodeSelector == nil || !anno.LoadBalancerNodeSelector.Matches(labels.Set{"pool": "lb"}) ||
+		anno.LoadBalancerNodeSelector.Matches(labels.Set{"pool": "other"}) {
+		t.Errorf("unexpected node selector: %v", anno.LoadBalancerNodeSelector)
+	}
+	if !cloud.isPodBackend(svc) {
+		t.Errorf("backend type pod of LoadBalancerConfig should be used")
+	}
+
+	// annotations are used without LoadBalancerConfig
+	delete(svc.Annotations, ServiceAnnotationLoadBalancerConfig)
+	anno, err = cloud.extractServiceAnnotation(svc)
+	if err != nil {
+		t.Fatalf("extractServiceAnnotation failed: %v", err)
+	}
+	if anno.LoadBalancerScheduler != "RoundRobin" || anno.listenerConfig(443).CertID != "cert-default" {
+		t.Errorf("annotations should be used without LoadBalancerConfig, got %+v", anno)
+	}
+
+	for _, name := range []string{"invalid", "not-found"} {
+		svc.Annotations[ServiceAnnotationLoadBalancerConfig] = name
+		if _, err := cloud.extractServiceAnnotation(svc); err == nil {
+			t.Errorf("LoadBalancerConfig %s should fail", name)
+		}
+	}
+}
+
+func TestSyncLoadBalancerConfigServices(t *testing.T) {
+	cloud := NewFakeCloud("c-config")
+	referring := buildService()
+	referring.Name = "referring"
+	referring.Spec.Type = api.ServiceTypeLoadBalancer
+	referring.Annotations = map[string]string{ServiceAnnotationLoadBalancerConfig: "lbc"}
+	other := buildService()
+	other.Name = "other"
+	other.Spec.Type = api.ServiceTypeLoadBalancer
+	other.Annotations = map[string]string{ServiceAnnotationLoadBalancerConfig: "other"}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, svc := range []*api.Service{referring, other} {
+		if err := indexer.Add(svc); err != nil {
+			t.Fatalf("add service failed: %v", err)
+		}
+	}
+	cloud.serviceLister = corelisters.NewServiceLister(indexer)
+	cloud.kubeClient = fake.NewSimpleClientset(referring, other)
+
+	config := newFakeLoadBalancerConfig(t, "lbc", cce_v1alpha1.LoadBalancerConfigSpec{Scheduler: "Hash"}).(*unstructured.Unstructured)
+	config.SetResourceVersion("2")
+	cloud.syncLoadBalancerConfigServices(config, config.GetResourceVersion())
+	for name, expected := range map[string]string{"referring": "2", "other": ""} {
+		svc, err := cloud.kubeClient.CoreV1().Services(api.NamespaceDefault).Get(name, meta_v1.GetOptions{})
+		if err != nil {
+			t.Fatalf("get service %s failed: %v", name, err)
+		}
+		if version := svc.Annotations[ServiceAnnotationCceLoadBalancerConfigVersion]; version != expected {
+			t.Errorf("service %s expected config version %q, got %q", name, expected, version)
+		}
+	}
+}
@@ -28,6 +28,14 @@ func (bc *Baiducloud) ensureEIP(ctx context.Context, clusterName string, service
 	}
 
 	if len(service.Spec.LoadBalancerIP) == 0 {
+		// EIPs claimed from pools no longer used are returned, lb is bound to no EIP then
+		poolName := service.Annotations[ServiceAnnotationElasticIPPool]
+		if _, err := bc.returnPoolEIPs(ctx, service, lb, poolName); err != nil {
+			return "", err
+		}
+		if len(poolName) != 0 {
+			return bc.ensureEIPFromPool(ctx, service, lb, poolName)
+		}
 		// not set LoadBalancerIP
 		return bc.ensureEIPWithNoSpecificIP(ctx, service, lb)
 	} else {
@@ -37,7 +45,7 @@ func (bc *Baiducloud) ensureEIP(ctx context.Context, clusterName string, service
 }
 
 func (bc *Baiducloud) ensureEIPWithNoSpecificIP(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer) (string, error) {
-	serviceAnnotation, err := ExtractServiceAnnotation(service)
+	serviceAnnotation, err := bc.extractServiceAnnotation(service)
 	if err != nil {
 		return "", err
 	}
@@ -53,8 +61,8 @@ func (bc *Baiducloud) ensureEIPWithNoSpecificIP(ctx context.Context, service *v1
 			args.Name = lb.Name // default EIP name = lb name
 		}
 		//sometimes there are several times to get EIP
-		if !strings.Contains(lb.Desc, "cce_auto_create_eip") {
-			lb.Desc = "cce_auto_create_eip" + lb.Desc
+		if !strings.Contains(lb.Desc, autoCreateEIPBLBDescPrefix) {
+			lb.Desc = autoCreateEIPBLBDescPrefix + lb.Desc
 			newLbArg := blb.UpdateLoadBalancerArgs{
 				LoadBalancerId: lb.BlbId,
 				Desc:           lb.Desc,
@@ -67,12 +75,28 @@ func (bc *Baiducloud) ensureEIPWithNoSpecificIP(ctx context.Context, service *v1
 		}
 		klog.V(3).Infof("lb.Desc: %s", lb.Desc)
 
-		pubIP, err = bc.getServiceAssociatedEip(ctx, service)
-		if err != nil {
-			return "", err
+		// EIP kept by reserve-eip is bound again when service returns
+		reserved := ""
+		if serviceAnnotation.LoadBalancerReserveEIP == "true" {
+			reserved, err = bc.getReservedEIP(ctx, service)
+			if err != nil {
+				return "", err
+			}
 		}
+		pubIP = reserved
 		if len(pubIP) == 0 {
-			pubIP, err = bc.createEIP(ctx, args)
+			pubIP, err = bc.getServiceAssociatedEip(ctx, service)
+			if err != nil {
+				return "", err
+			}
+		}
+		if len(pubIP) == 0 {
+			opts, err := getEipOptionsFromAnnotation(serviceAnnotation, args.Billing.PaymentTiming)
+			if err != nil {
+				return "", err
+			}
+			args.ClientToken = getClientToken(bc.ClusterID, service, bc.clientTokenKind(service, clientTokenKindEIP))
+			pubIP, err = bc.createEIPWithOptions(ctx, args, opts)
 			if err != nil {
 				return "", err
 			}
@@ -82,8 +106,33 @@ func (bc *Baiducloud) ensureEIPWithNoSpecificIP(ctx context.Context, service *v1
 		if err != nil {
 			return "", err
 		}
+		if len(reserved) != 0 {
+			err = bc.unreserveEIP(ctx, reserved)
+			if err != nil {
+				return "", err
+			}
+			bc.eventRecorder.Eventf(service, v1.EventTypeNormal, "EIPReadopted", "Bound reserved EIP %s again", reserved)
+		}
+		if len(serviceAnnotation.ElasticIPGroup) != 0 {
+			err = bc.ensureEIPInGroup(ctx, service, serviceAnnotation, pubIP)
+			if err != nil {
+				return "", err
+			}
+		}
 	} else { // blb already bind eip
 		klog.V(3).Infof("[%v %v] EnsureLoadBalancer: blb's eip already exists, start to ensure...", service.Namespace, service.Name)
+		// billing and bandwidth of EIP in group are those of the group
+		if len(serviceAnnotation.ElasticIPGroup) != 0 {
+			err = bc.ensureEIPInGroup(ctx, service, serviceAnnotation, pubIP)
+			if err != nil {
+				return "", err
+			}
+			return pubIP, nil
+		}
+		err = bc.ensureEIPNotInGroup(ctx, service, serviceAnnotation, pubIP)
+		if err != nil {
+			return "", err
+		}
 		eips, err := bc.getEipByIP(ctx, pubIP)
 		if err != nil {
 			return "", err
@@ -93,15 +142,38 @@ func (bc *Baiducloud) ensureEIPWithNoSpecificIP(ctx context.Context, service *v1
 			return "", err
 		}
 		targetEip := eips[0]
+		args, err := bc.getEipArgsFromAnnotation(serviceAnnotation)
+		if err != nil {
+			return "", err
+		}
+		opts, err := getEipOptionsFromAnnotation(serviceAnnotation, args.Billing.PaymentTiming)
+		if err != nil {
+			return "", err
+		}
+		// route type can not be changed, EIP is recreated as billing changes if allowed
+		routeType, err := bc.getEIPRouteType(ctx, pubIP)
+		if err != nil {
+			return "", err
+		}
+		if len(routeType) != 0 && routeType != opts.RouteType {
+			klog.V(3).Infof("[%v %v] EnsureLoadBalancer: EIP route type change, policy is %q", service.Namespace, service.Name, serviceAnnotation.ElasticIPBillingChangePolicy)
+			if serviceAnnotation.ElasticIPBillingChangePolicy != EIPBillingChangeRecreate {
+				return "", newValidationError(fmt.Errorf("route type of EIP %s can not be changed from %s to %s, set annotation %s to %s",
+					pubIP, routeType, opts.RouteType, ServiceAnnotationElasticIPBillingChangePolicy, EIPBillingChangeRecreate))
+			}
+			return bc.recreateEIP(ctx, service, lb, targetEip, args, opts)
+		}
 		if (len(serviceAnnotation.ElasticIPPaymentTiming) != 0 && serviceAnnotation.ElasticIPPaymentTiming != targetEip.PaymentTiming) ||
 			(len(serviceAnnotation.ElasticIPBillingMethod) != 0 && serviceAnnotation.ElasticIPBillingMethod != targetEip.BillingMethod) {
-			klog.V(3).Infof("[%v %v] EnsureLoadBalancer: EIP config change, need delete old eip and create new one", service.Namespace, service.Name)
-			// TODO
-			//pubIP, err = bc.deleteOldAndCreateNewEip(service, serviceAnnotation, pubIP, lb)
-			//if err != nil {
-			//	return "", err
-			//}
-			return "", fmt.Errorf("not support change ElasticIP PaymentTiming or ElasticIP BillingMethod, you can delete old and create a new one")
+			klog.V(3).Infof("[%v %v] EnsureLoadBalancer: EIP billing change, policy is %q", service.Namespace, service.Name, serviceAnnotation.ElasticIPBillingChangePolicy)
+			pubIP, err = bc.changeEIPBilling(ctx, service, serviceAnnotation, lb, targetEip)
+			if err != nil {
+				return "", err
+			}
+			if pubIP != targetEip.EIP {
+				// bandwidth is set on creating the new EIP
+				return pubIP, nil
+			}
 		}
 		if serviceAnnotation.ElasticIPBandwidthInMbps != 0 && serviceAnnotation.ElasticIPBandwidthInMbps != targetEip.BandwidthInMbps {
 			klog.V(3).Infof("[%v %v] EnsureLoadBalancer: EIP config change, need change ElasticIPBandwidthInMbps", service.Namespace, service.Name)
@@ -116,13 +188,21 @@ func (bc *Baiducloud) ensureEIPWithNoSpecificIP(ctx context.Context, service *v1
 				return "", err
 			}
 		}
+		err = bc.ensureEIPOptions(ctx, pubIP, opts)
+		if err != nil {
+			return "", err
+		}
 	}
 	return pubIP, nil
 }
 
 func (bc *Baiducloud) ensureEIPWithSpecificIP(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer) (string, error) {
+	return bc.ensureEIPBound(ctx, service, lb, service.Spec.LoadBalancerIP)
+}
+
+// ensureEIPBound binds loadBalancerIP, an EIP not created for service, to lb in place of the EIP bound before
+func (bc *Baiducloud) ensureEIPBound(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer, loadBalancerIP string) (string, error) {
 	pubIP := lb.PublicIp
-	loadBalancerIP := service.Spec.LoadBalancerIP
 	klog.V(3).Infof("[%v %v] EnsureLoadBalancer: Try to bind Custom LoadBalancerIP %s to BLB %s.", service.Namespace, service.Name, loadBalancerIP, lb.BlbId)
 	if len(pubIP) == 0 { // blb not bind target eip
 		// check eip status & bind blb
@@ -138,7 +218,15 @@ func (bc *Baiducloud) ensureEIPWithSpecificIP(ctx context.Context, service *v1.S
 			klog.V(3).Infof("[%v %v] EnsureLoadBalancer: BLB %s already bind EIP %s.", service.Namespace, service.Name, lb.BlbId, pubIP)
 		} else { // blb not bind correct LoadBalancerIP, need update
 			klog.V(3).Infof("[%v %v] EnsureLoadBalancer: BLB %s already bind EIP %s, but need updating to %s.", service.Namespace, service.Name, lb.BlbId, pubIP, loadBalancerIP)
-			err := bc.unbindEip(ctx, lb, pubIP)
+			createdForLB, err := bc.isEIPCreatedForLB(ctx, service, lb, pubIP)
+			if err != nil {
+				return "", err
+			}
+			if createdForLB {
+				err = bc.releaseReplacedEIP(ctx, service, lb, pubIP)
+			} else {
+				err = bc.unbindEip(ctx, lb, pubIP)
+			}
 			if err != nil {
 				return "", err
 			}
@@ -155,6 +243,49 @@ func (bc *Baiducloud) ensureEIPWithSpecificIP(ctx context.Context, service *v1.S
 	return pubIP, nil
 }
 
+// isEIPCreatedForLB returns whether ip is the EIP created by ensureEIPWithNoSpecificIP for lb,
+// EIPs of users or pools are never released by CCM
+func (bc *Baiducloud) isEIPCreatedForLB(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer, ip string) (bool, error) {
+	if !strings.Contains(lb.Desc, autoCreateEIPBLBDescPrefix) {
+		return false, nil
+	}
+	eips, err := bc.getEipByIP(ctx, ip)
+	if err != nil {
+		return false, err
+	}
+	if len(eips) == 0 {
+		return false, nil
+	}
+	name := eips[0].Name
+	if name == lb.Name || strings.HasPrefix(name, lb.Name+"-") { // default name, or the name of recreated EIP
+		return true, nil
+	}
+	return len(service.Annotations[ServiceAnnotationElasticIPName]) != 0 && name == service.Annotations[ServiceAnnotationElasticIPName], nil
+}
+
+// releaseReplacedEIP releases ip created for lb after another EIP replaces it, or reserves it as ensureEipDeleted does
+func (bc *Baiducloud) releaseReplacedEIP(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer, ip string) error {
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	if service.Annotations[ServiceAnnotationLoadBalancerReserveEIP] == "true" {
+		if err := bc.reserveEIP(ctx, service, lb, ip); err != nil {
+			return err
+		}
+	} else {
+		if err := bc.unbindEip(ctx, lb, ip); err != nil {
+			return err
+		}
+		if err := bc.deleteEIP(ctx, ip); err != nil {
+			return err
+		}
+		klog.Infof(Message(ctx, fmt.Sprintf("EIP %s created for service %s is released after being replaced", ip, serviceKey)))
+	}
+	if service.Annotations[ServiceAnnotationCceAutoAddEip] == ip {
+		delete(service.Annotations, ServiceAnnotationCceAutoAddEip)
+	}
+	// EIP created later must not be the released one of the same client token
+	return bc.bumpClientTokenKind(service)
+}
+
 func (bc *Baiducloud) unbindEip(ctx context.Context, lb *blb.LoadBalancer, ip string) error {
 	eips, err := bc.getEipByIP(ctx, ip)
 	if err != nil {
@@ -173,20 +304,18 @@ func (bc *Baiducloud) unbindEip(ctx context.Context, lb *blb.LoadBalancer, ip st
 }
 
 func (bc *Baiducloud) bindEip(ctx context.Context, lb *blb.LoadBalancer, ip string, service *v1.Service) (*blb.LoadBalancer, error) {
-	for i := 0; i < 10; i++ {
-		eips, err := bc.getEipByIP(ctx, ip)
-		if err != nil {
-			return nil, err
-		}
-		if eips == nil || len(eips) == 0 {
-			err = fmt.Errorf("[%v %v] EnsureLoadBalancer: EIP %s not Exist", service.Namespace, service.Name, ip)
+	eips, err := bc.getEipByIP(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+	if eips == nil || len(eips) == 0 {
+		err = fmt.Errorf("[%v %v] EnsureLoadBalancer: EIP %s not Exist", service.Namespace, service.Name, ip)
+		return nil, err
+	}
+	if eips[0].Status != eip.EIPAvailable {
+		if err := bc.waitForEIPAvailable(ctx, ip); err != nil {
 			return nil, err
 		}
-		if eips[0].Status == eip.EIPAvailable {
-			break
-		}
-		klog.Infof(Message(ctx, fmt.Sprintf("eip %s status is %s, not available, wait...", ip, eips[0].Status)))
-		time.Sleep(3 * time.Second)
 	}
 
 	// bind blb
@@ -197,7 +326,7 @@ func (bc *Baiducloud) bindEip(ctx context.Context, lb *blb.LoadBalancer, ip stri
 	}
 	klog.V(3).Infof("[%v %v] Bind EIP: %v", service.Namespace, service.Name, argsBind)
 	klog.V(3).Infof("[%v %v] Bind BLB: %v", service.Namespace, service.Name, lb)
-	err := bc.clientSet.EIPClient.BindEIP(ctx, ip, argsBind, bc.getSignOption(ctx))
+	err = bc.clientSet.EIPClient.BindEIP(ctx, ip, argsBind, bc.getSignOption(ctx))
 	if err != nil {
 		klog.V(3).Infof("BindEip error: %v", err)
 		return nil, err
@@ -247,8 +376,21 @@ func (bc *Baiducloud) deleteEIP(ctx context.Context, ip string) error {
 		return nil
 	}
 
-	if eips[0].Status != "Available" {
-		time.Sleep(5 * time.Second)
+	if eips[0].Status != eip.EIPAvailable {
+		if err := bc.waitForEIPAvailable(ctx, ip); err != nil {
+			return err
+		}
+	}
+
+	// EIP in group can not be released, it is moved out with the default billing before
+	group, err := bc.getEIPGroupOfIP(ctx, ip)
+	if err != nil {
+		return err
+	}
+	if group != nil {
+		if err := bc.leaveEIPGroup(ctx, &ServiceAnnotation{}, group, ip); err != nil {
+			return err
+		}
 	}
 
 	err = bc.clientSet.EIPClient.DeleteEIP(ctx, ip, bc.getSignOption(ctx))
@@ -274,24 +416,17 @@ func (bc *Baiducloud) getEipArgsFromAnnotation(serviceAnnotation *ServiceAnnotat
 	switch paymentTiming {
 	case eip.PAYMENTTIMING_PREPAID:
 		if len(serviceAnnotation.ElasticIPBillingMethod) != 0 {
-			return nil, fmt.Errorf("when using Prepaid EIP, do not need to set ElasticIPBillingMethod")
+			return nil, newValidationError(fmt.Errorf("when using Prepaid EIP, do not need to set ElasticIPBillingMethod"))
 		}
 		if bandwidthInMbps == 0 { // not set bandwidthInMbps
 			bandwidthInMbps = 200
 		} else {
 			if bandwidthInMbps < 1 || bandwidthInMbps > 200 {
-				return nil, fmt.Errorf("prepaid EIP bandwidthInMbps should in [1, 200]")
+				return nil, newValidationError(fmt.Errorf("prepaid EIP bandwidthInMbps should in [1, 200]"))
 			}
 		}
-		reservationLengthAllowed := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 12, 24, 36}
-		rightReservationLength := false
-		for _, length := range reservationLengthAllowed {
-			if reservationLength == length {
-				rightReservationLength = true
-			}
-		}
-		if !rightReservationLength {
-			return nil, fmt.Errorf("prepaid EIP reservationLength should in [1,2,3,4,5,6,7,8,9,12,24,36]")
+		if !isEIPReservationLengthAllowed(reservationLength) {
+			return nil, newValidationError(fmt.Errorf("prepaid EIP reservationLength should in [1,2,3,4,5,6,7,8,9,12,24,36]"))
 		}
 		args = &eip.CreateEIPArgs{
 			Name:            serviceAnnotation.ElasticIPName,
@@ -311,7 +446,7 @@ func (bc *Baiducloud) getEipArgsFromAnnotation(serviceAnnotation *ServiceAnnotat
 				bandwidthInMbps = 1000
 			} else {
 				if bandwidthInMbps < 1 || bandwidthInMbps > 1000 {
-					return nil, fmt.Errorf("postpaid ByTraffic EIP bandwidthInMbps should in [1, 1000]")
+					return nil, newValidationError(fmt.Errorf("postpaid ByTraffic EIP bandwidthInMbps should in [1, 1000]"))
 				}
 			}
 		case eip.BILLINGMETHOD_BYBANDWIDTH:
@@ -319,11 +454,11 @@ func (bc *Baiducloud) getEipArgsFromAnnotation(serviceAnnotation *ServiceAnnotat
 				bandwidthInMbps = 200
 			} else {
 				if bandwidthInMbps < 1 || bandwidthInMbps > 200 {
-					return nil, fmt.Errorf("postpaid ByBandwidth EIP bandwidthInMbps should in [1, 200]")
+					return nil, newValidationError(fmt.Errorf("postpaid ByBandwidth EIP bandwidthInMbps should in [1, 200]"))
 				}
 			}
 		default:
-			return nil, fmt.Errorf("not support target ElasticIPBillingMethod: %v", billingMethod)
+			return nil, newValidationError(fmt.Errorf("not support target ElasticIPBillingMethod: %v", billingMethod))
 		}
 		args = &eip.CreateEIPArgs{
 			Name:            serviceAnnotation.ElasticIPName,
@@ -334,7 +469,11 @@ func (bc *Baiducloud) getEipArgsFromAnnotation(serviceAnnotation *ServiceAnnotat
 			},
 		}
 	default:
-		return nil, fmt.Errorf("not support target ElasticIPPaymentTiming: %v", paymentTiming)
+		return nil, newValidationError(fmt.Errorf("not support target ElasticIPPaymentTiming: %v", paymentTiming))
+	}
+	// route type, auto-renew and tags are not in args, they are validated with the billing
+	if _, err := getEipOptionsFromAnnotation(serviceAnnotation, paymentTiming); err != nil {
+		return nil, err
 	}
 
 	return args, nil
@@ -366,12 +505,30 @@ func (bc *Baiducloud) ensureEipDeleted(ctx context.Context, service *v1.Service,
 		}
 		return nil
 	}
+	// EIPs of pool are returned instead of deleted
+	returned, err := bc.returnPoolEIPs(ctx, service, lb, "")
+	if err != nil {
+		return err
+	}
+	if poolName := service.Annotations[ServiceAnnotationElasticIPPool]; returned || len(poolName) != 0 {
+		if !returned && lb != nil && len(lb.PublicIp) != 0 {
+			klog.Infof(Message(ctx, fmt.Sprintf("service %s uses EIP pool %s, unbind EIP %s without deleting it", serviceKey, poolName, lb.PublicIp)))
+			if err := bc.unbindEip(ctx, lb, lb.PublicIp); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 	// get eip
 	var targetEip string
 	if len(service.Status.LoadBalancer.Ingress) != 0 { // P0: use service EXTERNAL_IP
 		targetEip = service.Status.LoadBalancer.Ingress[0].IP
 		klog.Infof(Message(ctx, fmt.Sprintf("selected ip %s for service %s from status to delete", targetEip, serviceKey)))
 	}
+	if lb != nil && len(lb.Address) != 0 && targetEip == lb.Address {
+		// status of service changed from internal-vpc is the VIP until EIP is ensured
+		targetEip = ""
+	}
 	if len(targetEip) == 0 && lb != nil { // P1: use BLB public ip
 		targetEip = lb.PublicIp
 		klog.Infof(Message(ctx, fmt.Sprintf("selected ip %s for service %s from lb.PublicIp to delete", targetEip, serviceKey)))
@@ -388,6 +545,10 @@ func (bc *Baiducloud) ensureEipDeleted(ctx context.Context, service *v1.Service,
 		return nil
 	}
 
+	// EIP is kept for service to return
+	if service.Annotations[ServiceAnnotationLoadBalancerReserveEIP] == "true" {
+		return bc.reserveEIP(ctx, service, lb, targetEip)
+	}
 	if lb != nil {
 		err := bc.unbindEip(ctx, lb, targetEip)
 		if err != nil {
@@ -395,7 +556,7 @@ func (bc *Baiducloud) ensureEipDeleted(ctx context.Context, service *v1.Service,
 		}
 	}
 	// delete eip
-	err := bc.deleteEIP(ctx, targetEip)
+	err = bc.deleteEIP(ctx, targetEip)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,168 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+
+	tempeip "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-eip"
+)
+
+const (
+	// EIPBillingChangeReject rejects the change of payment timing or billing method of EIP
+	EIPBillingChangeReject = "reject"
+	// EIPBillingChangeRecreate creates a new EIP with the billing, binds it to BLB and releases the old one
+	EIPBillingChangeRecreate = "recreate"
+	// EIPBillingChangeInPlace changes the billing of EIP in place, the address is kept
+	EIPBillingChangeInPlace = "in-place"
+)
+
+// changeEIPBilling applies the payment timing and billing method in annotations to EIP bound to lb,
+// and returns the EIP used by service afterwards
+func (bc *Baiducloud) changeEIPBilling(ctx context.Context, service *v1.Service, anno *ServiceAnnotation, lb *blb.LoadBalancer, current *eip.EIP) (string, error) {
+	args, err := bc.getEipArgsFromAnnotation(anno)
+	if err != nil {
+		return "", err
+	}
+	switch anno.ElasticIPBillingChangePolicy {
+	case EIPBillingChangeInPlace:
+		return bc.changeEIPBillingInPlace(ctx, service, current, args)
+	case EIPBillingChangeRecreate:
+		opts, err := getEipOptionsFromAnnotation(anno, args.Billing.PaymentTiming)
+		if err != nil {
+			return "", err
+		}
+		return bc.recreateEIP(ctx, service, lb, current, args, opts)
+	}
+	return "", newValidationError(fmt.Errorf("not support change ElasticIP PaymentTiming or ElasticIP BillingMethod, set annotation %s to %s or %s",
+		ServiceAnnotationElasticIPBillingChangePolicy, EIPBillingChangeRecreate, EIPBillingChangeInPlace))
+}
+
+// changeEIPBillingInPlace changes billing of Postpaid EIP, Prepaid EIP keeps its billing until it expires
+func (bc *Baiducloud) changeEIPBillingInPlace(ctx context.Context, service *v1.Service, current *eip.EIP, args *eip.CreateEIPArgs) (string, error) {
+	if current.PaymentTiming == eip.PAYMENTTIMING_PREPAID {
+		return "", newValidationError(fmt.Errorf("billing of Prepaid EIP %s can not be changed in place, use %s %s", current.EIP, ServiceAnnotationElasticIPBillingChangePolicy, EIPBillingChangeRecreate))
+	}
+	klog.Infof(Message(ctx, fmt.Sprintf("change billing of EIP %s from %s/%s to %s/%s", current.EIP,
+		current.PaymentTiming, current.BillingMethod, args.Billing.PaymentTiming, args.Billing.BillingMethod)))
+	changeArgs := tempeip.ChangeEIPBillingArgs{
+		Billing: &tempeip.Billing{
+			PaymentTiming: args.Billing.PaymentTiming,
+			BillingMethod: args.Billing.BillingMethod,
+		},
+	}
+	err := bc.clientSet.EIPOptionClient.ChangeEIPBilling(ctx, current.EIP, &changeArgs, bc.getSignOption(ctx))
+	if err != nil {
+		return "", fmt.Errorf("change billing of EIP %s failed: %v", current.EIP, err)
+	}
+	// bandwidth follows the new billing as a recreated EIP does
+	if current.BandwidthInMbps != args.BandwidthInMbps {
+		err = bc.clientSet.EIPClient.ResizeEIP(ctx, current.EIP, &eip.ResizeEIPArgs{
+			BandwidthInMbps: args.BandwidthInMbps,
+			IP:              current.EIP,
+		}, bc.getSignOption(ctx))
+		if err != nil {
+			return "", fmt.Errorf("resize EIP %s after changing billing failed: %v", current.EIP, err)
+		}
+	}
+	bc.eventRecorder.Eventf(service, v1.EventTypeNormal, "EIPBillingChanged", "Changed billing of EIP %s from %s/%s to %s/%s",
+		current.EIP, current.PaymentTiming, current.BillingMethod, args.Billing.PaymentTiming, args.Billing.BillingMethod)
+	current.PaymentTiming = args.Billing.PaymentTiming
+	current.BillingMethod = args.Billing.BillingMethod
+	current.BandwidthInMbps = args.BandwidthInMbps
+	return current.EIP, nil
+}
+
+// recreateEIP replaces the EIP bound to lb by a new one with the billing in args and options in opts.
+// The new EIP is bound and published in status of service before the old one is released.
+func (bc *Baiducloud) recreateEIP(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer, current *eip.EIP, args *eip.CreateEIPArgs, opts *eipOptions) (string, error) {
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	// retries of the same change get the same new EIP
+	args.ClientToken = getClientToken(bc.ClusterID, service, fmt.Sprintf("%s/%s/%s/%s/%s", bc.clientTokenKind(service, clientTokenKindEIP),
+		current.EIP, args.Billing.PaymentTiming, args.Billing.BillingMethod, opts.RouteType))
+	if len(args.Name) == 0 {
+		args.Name = lb.Name // default EIP name = lb name
+	}
+	// both EIPs exist until the old one is released, so the new one must not be found by the name of the old one
+	args.Name = getRecreatedEIPName(args.Name, args.ClientToken)
+	newIP, err := bc.createEIPWithOptions(ctx, args, opts)
+	if err != nil {
+		return "", err
+	}
+	klog.Infof(Message(ctx, fmt.Sprintf("replace EIP %s of service %s by %s", current.EIP, serviceKey, newIP)))
+
+	// the new EIP is associated with service by annotation before the old one is unbound
+	if err := bc.updateServiceAnnotation(service, ServiceAnnotationCceAutoAddEip, newIP); err != nil {
+		return "", err
+	}
+
+	// BLB is bound to one EIP at a time
+	if err := bc.unbindEip(ctx, lb, current.EIP); err != nil {
+		return "", err
+	}
+	if _, err := bc.bindEip(ctx, lb, newIP, service); err != nil {
+		return "", err
+	}
+	if err := bc.updateServiceIngress(service, newIP); err != nil {
+		// the old EIP is unbound already, it is released anyway and status is updated by service controller
+		klog.Warningf(Message(ctx, fmt.Sprintf("update ingress of service %s to %s failed: %v", serviceKey, newIP, err)))
+	}
+	bc.eventRecorder.Eventf(service, v1.EventTypeNormal, "EIPRecreated", "Replaced EIP %s by %s with billing %s/%s and route type %s",
+		current.EIP, newIP, args.Billing.PaymentTiming, args.Billing.BillingMethod, opts.RouteType)
+
+	if err := bc.deleteEIP(ctx, current.EIP); err != nil {
+		// e.g. Prepaid EIP can not be released before it expires
+		klog.Errorf(Message(ctx, fmt.Sprintf("release old EIP %s of service %s failed: %v", current.EIP, serviceKey, err)))
+		bc.eventRecorder.Eventf(service, v1.EventTypeWarning, "ReleaseEIPFailed", "Error releasing old EIP %s, release it manually: %v", current.EIP, err)
+	}
+	return newIP, nil
+}
+
+// getRecreatedEIPName returns name of the EIP replacing another one, name is the name of EIP from annotation or BLB
+func getRecreatedEIPName(name, clientToken string) string {
+	suffix := "-" + clientToken[:8]
+	if len(name)+len(suffix) > maxLoadBalancerNameLength {
+		name = name[:maxLoadBalancerNameLength-len(suffix)]
+	}
+	return name + suffix
+}
+
+// updateServiceIngress sets the first ingress of service status to ip, the VPC address published after it is kept
+func (bc *Baiducloud) updateServiceIngress(service *v1.Service, ip string) error {
+	if bc.kubeClient == nil {
+		return nil
+	}
+	svc, err := bc.kubeClient.CoreV1().Services(service.Namespace).Get(service.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	svc = svc.DeepCopy()
+	ingress := []v1.LoadBalancerIngress{{IP: ip}}
+	if len(svc.Status.LoadBalancer.Ingress) > 1 {
+		ingress = append(ingress, svc.Status.LoadBalancer.Ingress[1:]...)
+	}
+	svc.Status.LoadBalancer.Ingress = ingress
+	_, err = bc.kubeClient.CoreV1().Services(service.Namespace).UpdateStatus(svc)
+	return err
+}
@@ -0,0 +1,127 @@
+package cloud_provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+	api "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+// newEIPOfBLB creates a Postpaid ByTraffic EIP bound to BLB
+func newEIPOfBLB(t *testing.T, cloud *Baiducloud, blbID string) *blb.LoadBalancer {
+	ctx := context.Background()
+	args, err := cloud.getEipArgsFromAnnotation(&ServiceAnnotation{})
+	if err != nil {
+		t.Fatalf("getEipArgsFromAnnotation err, err: %s", err)
+	}
+	ip, err := cloud.createEIP(ctx, args)
+	if err != nil {
+		t.Fatalf("createEIP err, err: %s", err)
+	}
+	lb := &blb.LoadBalancer{BlbId: blbID, PublicIp: ip}
+	if _, err := cloud.bindEip(ctx, lb, ip, buildService()); err != nil {
+		t.Fatalf("bindEip err, err: %s", err)
+	}
+	return lb
+}
+
+func TestEnsureEIPBillingChange(t *testing.T) {
+	cloud, _, blbResp, err := beforeTestBlb()
+	if err != nil {
+		t.Fatalf("beforeTestBlb err , %v", err)
+	}
+	cloud.eventRecorder = record.NewFakeRecorder(10)
+	ctx := context.Background()
+
+	// reject by default
+	lb := newEIPOfBLB(t, cloud, blbResp.LoadBalancerId)
+	svc := buildService()
+	svc.UID = "uid-1"
+	svc.Annotations = map[string]string{
+		ServiceAnnotationElasticIPBillingMethod: eip.BILLINGMETHOD_BYBANDWIDTH,
+	}
+	var vErr *validationError
+	if _, err := cloud.ensureEIPWithNoSpecificIP(ctx, svc, lb); !errors.As(err, &vErr) {
+		t.Errorf("ensureEIPWithNoSpecificIP err, billing change should be rejected as invalid setting, err: %v", err)
+	}
+
+	// in place, the address is kept
+	svc.Annotations[ServiceAnnotationElasticIPBillingChangePolicy] = EIPBillingChangeInPlace
+	ip, err := cloud.ensureEIPWithNoSpecificIP(ctx, svc, lb)
+	if err != nil {
+		t.Errorf("ensureEIPWithNoSpecificIP err, err: %s", err)
+	}
+	if ip != lb.PublicIp {
+		t.Errorf("ensureEIPWithNoSpecificIP err, expected %s, got %s", lb.PublicIp, ip)
+	}
+	eips, err := cloud.getEipByIP(ctx, ip)
+	if err != nil || len(eips) != 1 {
+		t.Fatalf("getEipByIP err, eips: %v, err: %v", eips, err)
+	}
+	if eips[0].BillingMethod != eip.BILLINGMETHOD_BYBANDWIDTH || eips[0].BandwidthInMbps != 200 {
+		t.Errorf("billing of EIP not changed in place: %s %d", eips[0].BillingMethod, eips[0].BandwidthInMbps)
+	}
+
+	// recreate, the new EIP is bound and published, the old one is released
+	oldIP := lb.PublicIp
+	lb.Name = "CCE/SVC/cluster/uid-1"
+	eips, err = cloud.getEipByIP(ctx, oldIP)
+	if err != nil || len(eips) != 1 {
+		t.Fatalf("getEipByIP err, eips: %v, err: %v", eips, err)
+	}
+	oldName := eips[0].Name
+	svc.Status.LoadBalancer.Ingress = []api.LoadBalancerIngress{{IP: oldIP}}
+	cloud.kubeClient = fake.NewSimpleClientset(svc)
+	svc.Annotations = map[string]string{
+		ServiceAnnotationElasticIPPaymentTiming:       eip.PAYMENTTIMING_PREPAID,
+		ServiceAnnotationElasticIPReservationLength:   "1",
+		ServiceAnnotationElasticIPBillingChangePolicy: EIPBillingChangeRecreate,
+	}
+	ip, err = cloud.ensureEIPWithNoSpecificIP(ctx, svc, lb)
+	if err != nil {
+		t.Fatalf("ensureEIPWithNoSpecificIP err, err: %s", err)
+	}
+	if ip == oldIP {
+		t.Errorf("ensureEIPWithNoSpecificIP err, EIP %s not recreated", ip)
+	}
+	eips, err = cloud.getEipByIP(ctx, ip)
+	if err != nil || len(eips) != 1 {
+		t.Fatalf("getEipByIP err, eips: %v, err: %v", eips, err)
+	}
+	if eips[0].InstanceID != lb.BlbId || eips[0].PaymentTiming != eip.PAYMENTTIMING_PREPAID {
+		t.Errorf("recreated EIP wrong: %+v", eips[0])
+	}
+	if eips[0].Name == oldName || eips[0].Name == lb.Name {
+		t.Errorf("recreated EIP has the name of the old one: %s", eips[0].Name)
+	}
+	eips, err = cloud.getEipByIP(ctx, oldIP)
+	if err != nil || len(eips) != 0 {
+		t.Errorf("old EIP %s not released, eips: %v, err: %v", oldIP, eips, err)
+	}
+	got, err := cloud.kubeClient.CoreV1().Services(svc.Namespace).Get(svc.Name, meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get service err: %v", err)
+	}
+	if len(got.Status.LoadBalancer.Ingress) != 1 || got.Status.LoadBalancer.Ingress[0].IP != ip {
+		t.Errorf("ingress of service not updated: %v", got.Status.LoadBalancer.Ingress)
+	}
+	if got.Annotations[ServiceAnnotationCceAutoAddEip] != ip {
+		t.Errorf("annotation %s of service not updated: %v", ServiceAnnotationCceAutoAddEip, got.Annotations)
+	}
+
+	// Prepaid EIP can not be changed in place
+	lb.PublicIp = ip
+	svc.Annotations = map[string]string{
+		ServiceAnnotationElasticIPPaymentTiming:       eip.PAYMENTTIMING_POSTPAID,
+		ServiceAnnotationElasticIPBillingChangePolicy: EIPBillingChangeInPlace,
+	}
+	if _, err := cloud.ensureEIPWithNoSpecificIP(ctx, svc, lb); err == nil {
+		t.Errorf("ensureEIPWithNoSpecificIP err, billing of Prepaid EIP should not be changed in place")
+	}
+}
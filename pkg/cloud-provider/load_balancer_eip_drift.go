@@ -0,0 +1,204 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog"
+)
+
+const (
+	// EIPDriftReport only reports EIP drifted from service by Warning events
+	EIPDriftReport = "report"
+	// EIPDriftRebind binds EIP unbound out of band to BLB again and fixes bandwidth drift
+	EIPDriftRebind = "rebind"
+	// EIPDriftRepair also replaces EIP released out of band by a new one as EnsureLoadBalancer does, the address changes
+	EIPDriftRepair = "repair"
+
+	defaultEIPDriftCheckPeriod = 5 * time.Minute
+)
+
+// eipDriftCheckPeriod returns the interval of checking EIP drift
+func (bc *Baiducloud) eipDriftCheckPeriod() time.Duration {
+	if bc.EIPDriftCheckPeriodInSecond <= 0 {
+		return defaultEIPDriftCheckPeriod
+	}
+	return time.Duration(bc.EIPDriftCheckPeriodInSecond) * time.Second
+}
+
+// reconcileEIPDrift checks the EIPs of all LoadBalancer services, which may be unbound, released or resized
+// out of band without any change of services, drifts to repair are queued to svcQueue
+func (bc *Baiducloud) reconcileEIPDrift() {
+	ctx := context.WithValue(context.Background(), RequestID, GetRandom())
+	if bc.serviceLister == nil {
+		return
+	}
+	services, err := bc.serviceLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf(Message(ctx, fmt.Sprintf("list services for checking EIP drift failed: %v", err)))
+		return
+	}
+	for _, service := range services {
+		if service.Spec.Type != v1.ServiceTypeLoadBalancer || service.DeletionTimestamp != nil {
+			continue
+		}
+		serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+		drifted, err := bc.checkEIPDrift(ctx, service, false)
+		if err != nil {
+			klog.Errorf(Message(ctx, fmt.Sprintf("check EIP drift of service %s failed: %v", serviceKey, err)))
+			continue
+		}
+		if drifted {
+			bc.eipDriftServices.Store(serviceKey, true)
+			bc.svcQueue.Add(serviceKey)
+		}
+	}
+}
+
+// repairEIPDrift repairs the EIP drift of service found by reconcileEIPDrift, it is called by svcQueue,
+// so that it does not run together with other reconciles of service in svcQueue
+func (bc *Baiducloud) repairEIPDrift(ctx context.Context, service *v1.Service) error {
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	if _, ok := bc.eipDriftServices.Load(serviceKey); !ok {
+		return nil
+	}
+	if service.Spec.Type == v1.ServiceTypeLoadBalancer && service.DeletionTimestamp == nil {
+		if _, err := bc.checkEIPDrift(ctx, service, true); err != nil {
+			return err
+		}
+	}
+	bc.eipDriftServices.Delete(serviceKey)
+	return nil
+}
+
+// checkEIPDrift compares the EIP in status of service with the EIP bound to its BLB. The drift is reported
+// and whether it should be repaired by policy is returned if repair is false, otherwise the drift is repaired
+func (bc *Baiducloud) checkEIPDrift(ctx context.Context, service *v1.Service, repair bool) (bool, error) {
+	if len(service.Status.LoadBalancer.Ingress) == 0 || service.Status.LoadBalancer.Ingress[0].IP == "" {
+		return false, nil
+	}
+	// service is changed by getServiceAssociatedBLB
+	service = service.DeepCopy()
+	anno, err := bc.extractServiceAnnotation(service)
+	if err != nil {
+		return false, err
+	}
+	if anno.LoadBalancerInternalVpc == "true" {
+		return false, nil
+	}
+	lb, exist, err := bc.getServiceAssociatedBLB(ctx, "", service)
+	if err != nil {
+		return false, err
+	}
+	if !exist || isInternalVpcExposure(service, lb) {
+		// BLB is recreated, or internal-vpc is changed and not ensured yet, by service controller
+		return false, nil
+	}
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	policy := anno.ElasticIPDriftPolicy
+	if policy == "" {
+		policy = EIPDriftRebind
+	}
+	ip := service.Status.LoadBalancer.Ingress[0].IP
+
+	eips, err := bc.getEipByIP(ctx, ip)
+	if err != nil {
+		return false, err
+	}
+	if len(eips) == 0 {
+		// EIP specified by user or claimed from pool can not be created again
+		toRepair := policy == EIPDriftRepair && len(service.Spec.LoadBalancerIP) == 0 && len(anno.ElasticIPPool) == 0
+		if !repair {
+			msg := fmt.Sprintf("EIP %s of service %s is released, BLB %s is bound to EIP %q", ip, serviceKey, lb.BlbId, lb.PublicIp)
+			klog.Warningf(Message(ctx, msg))
+			bc.eventRecorder.Eventf(service, v1.EventTypeWarning, "EIPDrift", "EIP %s is released, BLB %s is bound to EIP %q", ip, lb.BlbId, lb.PublicIp)
+			return toRepair, nil
+		}
+		if !toRepair {
+			return false, nil
+		}
+		newIP, err := bc.ensureEIP(ctx, "", service)
+		if err != nil {
+			return false, err
+		}
+		if err := bc.updateServiceIngress(service, newIP); err != nil {
+			return false, err
+		}
+		klog.Infof(Message(ctx, fmt.Sprintf("replace released EIP %s of service %s by %s", ip, serviceKey, newIP)))
+		bc.eventRecorder.Eventf(service, v1.EventTypeNormal, "EIPRepaired", "Replaced released EIP %s by %s", ip, newIP)
+		return false, nil
+	}
+
+	current := eips[0]
+	if current.InstanceID == "" {
+		if !repair {
+			msg := fmt.Sprintf("EIP %s of service %s is unbound from BLB %s", ip, serviceKey, lb.BlbId)
+			klog.Warningf(Message(ctx, msg))
+			bc.eventRecorder.Eventf(service, v1.EventTypeWarning, "EIPDrift", "EIP %s is unbound from BLB %s", ip, lb.BlbId)
+			if len(lb.PublicIp) != 0 && lb.PublicIp != ip {
+				bc.eventRecorder.Eventf(service, v1.EventTypeWarning, "EIPDrift", "BLB %s is bound to EIP %s, EIP %s is not bound again", lb.BlbId, lb.PublicIp, ip)
+				return false, nil
+			}
+			return policy != EIPDriftReport, nil
+		}
+		if policy == EIPDriftReport || len(lb.PublicIp) != 0 && lb.PublicIp != ip {
+			return false, nil
+		}
+		if _, err := bc.bindEip(ctx, lb, ip, service); err != nil {
+			return false, err
+		}
+		klog.Infof(Message(ctx, fmt.Sprintf("bind EIP %s of service %s to BLB %s again", ip, serviceKey, lb.BlbId)))
+		bc.eventRecorder.Eventf(service, v1.EventTypeNormal, "EIPRebound", "Bound EIP %s to BLB %s again", ip, lb.BlbId)
+		return false, nil
+	}
+	if current.InstanceID != lb.BlbId {
+		// EIP used by another instance is never taken back
+		if !repair {
+			msg := fmt.Sprintf("EIP %s of service %s is bound to %s instead of BLB %s", ip, serviceKey, current.InstanceID, lb.BlbId)
+			klog.Warningf(Message(ctx, msg))
+			bc.eventRecorder.Eventf(service, v1.EventTypeWarning, "EIPDrift", "EIP %s is bound to %s instead of BLB %s", ip, current.InstanceID, lb.BlbId)
+		}
+		return false, nil
+	}
+
+	// bandwidth of EIP specified by user, claimed from pool or in group is not managed by annotation
+	if anno.ElasticIPBandwidthInMbps == 0 || len(service.Spec.LoadBalancerIP) != 0 || len(anno.ElasticIPPool) != 0 || len(anno.ElasticIPGroup) != 0 {
+		return false, nil
+	}
+	if current.BandwidthInMbps == anno.ElasticIPBandwidthInMbps {
+		return false, nil
+	}
+	if !repair {
+		msg := fmt.Sprintf("bandwidth of EIP %s of service %s is %d, expected %d", ip, serviceKey, current.BandwidthInMbps, anno.ElasticIPBandwidthInMbps)
+		klog.Warningf(Message(ctx, msg))
+		bc.eventRecorder.Eventf(service, v1.EventTypeWarning, "EIPDrift", "Bandwidth of EIP %s is %d Mbps, expected %d Mbps", ip, current.BandwidthInMbps, anno.ElasticIPBandwidthInMbps)
+		return policy != EIPDriftReport, nil
+	}
+	if policy == EIPDriftReport {
+		return false, nil
+	}
+	if err := bc.resizeEip(ctx, anno, ip); err != nil {
+		return false, err
+	}
+	bc.eventRecorder.Eventf(service, v1.EventTypeNormal, "EIPBandwidthRestored", "Resized EIP %s to %d Mbps", ip, anno.ElasticIPBandwidthInMbps)
+	return false, nil
+}
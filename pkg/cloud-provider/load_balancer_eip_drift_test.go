@@ -0,0 +1,140 @@
+package cloud_provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+	api "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	fakeclient "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/fake"
+)
+
+func TestCheckEIPDrift(t *testing.T) {
+	cloud, _, blbResp, err := beforeTestBlb()
+	if err != nil {
+		t.Fatalf("beforeTestBlb err , %v", err)
+	}
+	recorder := record.NewFakeRecorder(20)
+	cloud.eventRecorder = recorder
+	ctx := context.Background()
+	lb := newEIPOfBLB(t, cloud, blbResp.LoadBalancerId)
+	ip := lb.PublicIp
+	svc := buildService()
+	svc.Spec.Type = api.ServiceTypeLoadBalancer
+	svc.Status.LoadBalancer.Ingress = []api.LoadBalancerIngress{{IP: ip}}
+	cloud.kubeClient = fake.NewSimpleClientset(svc)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	cloud.serviceLister = corelisters.NewServiceLister(indexer)
+	cloud.svcQueue = workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	// checkEIPDrift checks services in lister, and repairs drifts queued as svcQueue workers do
+	checkEIPDrift := func() {
+		if _, err := cloud.kubeClient.CoreV1().Services(svc.Namespace).Update(svc); err != nil {
+			t.Fatalf("update service err: %v", err)
+		}
+		if err := indexer.Update(svc); err != nil {
+			t.Fatalf("update indexer err: %v", err)
+		}
+		cloud.reconcileEIPDrift()
+		for cloud.svcQueue.Len() > 0 {
+			cloud.processNextService()
+		}
+	}
+	// the fake BLB does not reflect EIP bound
+	blbClient := cloud.clientSet.BLBClient.(*fakeclient.BlbFakeClient)
+	setPublicIP := func(ip string) {
+		fakeLB := blbClient.LoadBalancerMap[blbResp.LoadBalancerId]
+		fakeLB.PublicIp = ip
+		blbClient.LoadBalancerMap[blbResp.LoadBalancerId] = fakeLB
+	}
+	getEIP := func(ip string) *eip.EIP {
+		eips, err := cloud.getEipByIP(ctx, ip)
+		if err != nil {
+			t.Fatalf("getEipByIP err, err: %v", err)
+		}
+		if len(eips) == 0 {
+			return nil
+		}
+		return eips[0]
+	}
+
+	// unbound EIP is only reported by report policy
+	if err := cloud.clientSet.EIPClient.UnbindEIP(ctx, ip, nil); err != nil {
+		t.Fatalf("UnbindEIP err, err: %v", err)
+	}
+	setPublicIP("")
+	svc.Annotations = map[string]string{ServiceAnnotationElasticIPDriftPolicy: EIPDriftReport}
+	checkEIPDrift()
+	if e := getEIP(ip); e.InstanceID != "" {
+		t.Errorf("EIP %s should not be bound by report policy", ip)
+	}
+
+	// services being deleted are skipped
+	svc.Annotations = map[string]string{}
+	now := meta_v1.Now()
+	svc.DeletionTimestamp = &now
+	checkEIPDrift()
+	if e := getEIP(ip); e.InstanceID != "" {
+		t.Errorf("EIP %s should not be bound for service being deleted", ip)
+	}
+	svc.DeletionTimestamp = nil
+
+	// unbound EIP is bound again by default
+	svc.Annotations = map[string]string{}
+	checkEIPDrift()
+	if e := getEIP(ip); e.InstanceID != blbResp.LoadBalancerId {
+		t.Errorf("EIP %s not bound again, instance: %s", ip, e.InstanceID)
+	}
+	setPublicIP(ip)
+
+	// bandwidth drift is fixed
+	svc.Annotations = map[string]string{ServiceAnnotationElasticIPBandwidthInMbps: "100"}
+	checkEIPDrift()
+	if e := getEIP(ip); e.BandwidthInMbps != 100 {
+		t.Errorf("bandwidth of EIP %s not fixed: %d", ip, e.BandwidthInMbps)
+	}
+
+	// released EIP is replaced by repair policy, and ingress is updated
+	if err := cloud.clientSet.EIPClient.UnbindEIP(ctx, ip, nil); err != nil {
+		t.Fatalf("UnbindEIP err, err: %v", err)
+	}
+	if err := cloud.clientSet.EIPClient.DeleteEIP(ctx, ip, nil); err != nil {
+		t.Fatalf("DeleteEIP err, err: %v", err)
+	}
+	setPublicIP("")
+	checkEIPDrift()
+	got, err := cloud.kubeClient.CoreV1().Services(svc.Namespace).Get(svc.Name, meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get service err: %v", err)
+	}
+	if got.Status.LoadBalancer.Ingress[0].IP != ip {
+		t.Errorf("released EIP should not be replaced by rebind policy, got %v", got.Status.LoadBalancer.Ingress)
+	}
+	svc.Annotations[ServiceAnnotationElasticIPDriftPolicy] = EIPDriftRepair
+	checkEIPDrift()
+	got, err = cloud.kubeClient.CoreV1().Services(svc.Namespace).Get(svc.Name, meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get service err: %v", err)
+	}
+	// the fake may create EIP of the same address again, so the event is checked
+	repaired := false
+	for len(recorder.Events) > 0 {
+		if strings.Contains(<-recorder.Events, "EIPRepaired") {
+			repaired = true
+		}
+	}
+	if !repaired {
+		t.Errorf("released EIP %s not replaced", ip)
+	}
+	newIP := got.Status.LoadBalancer.Ingress[0].IP
+	if e := getEIP(newIP); e == nil || e.InstanceID != blbResp.LoadBalancerId {
+		t.Errorf("new EIP %s not bound to BLB: %+v", newIP, e)
+	}
+}
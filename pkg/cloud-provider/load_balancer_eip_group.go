@@ -0,0 +1,335 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+
+	tempeip "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-eip"
+)
+
+const (
+	clientTokenKindEIPGroup = "eipgroup"
+	// eipGroupTagKey is the tag of EIPs moved into EIP group by CCM, whose value is the id of group.
+	// EIPs moved into group by others are never moved out by CCM.
+	eipGroupTagKey = "cce-eip-group"
+	// eipGroupCreatedTagKey is the tag of EIPs moved into EIP group created by CCM, whose value is the id of group.
+	// The group is released after the last of them leaves it.
+	eipGroupCreatedTagKey = "cce-eip-group-created"
+	// eipGroupTokenTagKey is the tag of EIPs joining EIP group, whose value is a part of client token of creating the group.
+	// Retries get the group created before, and joins after the group is released create a new one.
+	eipGroupTokenTagKey = "cce-eip-group-token"
+)
+
+// getEIPGroupArgsFromAnnotation returns the args to create the EIP group named in anno.
+// The group is billed as EIP annotations, Postpaid group is ByBandwidth since ByTraffic is not supported by EIP group.
+func (bc *Baiducloud) getEIPGroupArgsFromAnnotation(anno *ServiceAnnotation) (*tempeip.CreateEIPGroupArgs, error) {
+	groupAnno := *anno
+	if groupAnno.ElasticIPPaymentTiming != eip.PAYMENTTIMING_PREPAID {
+		switch groupAnno.ElasticIPBillingMethod {
+		case "":
+			groupAnno.ElasticIPBillingMethod = eip.BILLINGMETHOD_BYBANDWIDTH
+		case eip.BILLINGMETHOD_BYTRAFFIC:
+			return nil, newValidationError(fmt.Errorf("EIP group %s does not support billing method %s", anno.ElasticIPGroup, eip.BILLINGMETHOD_BYTRAFFIC))
+		}
+	}
+	args, err := bc.getEipArgsFromAnnotation(&groupAnno)
+	if err != nil {
+		return nil, err
+	}
+	billing := &tempeip.Billing{
+		PaymentTiming: args.Billing.PaymentTiming,
+		BillingMethod: args.Billing.BillingMethod,
+	}
+	if args.Billing.Reservation != nil {
+		billing.Reservation = &tempeip.Reservation{
+			ReservationLength:   args.Billing.Reservation.ReservationLength,
+			ReservationTimeUnit: args.Billing.Reservation.ReservationTimeUnit,
+		}
+	}
+	return &tempeip.CreateEIPGroupArgs{
+		Name:            anno.ElasticIPGroup,
+		BandwidthInMbps: args.BandwidthInMbps,
+		Billing:         billing,
+	}, nil
+}
+
+// ensureEIPInGroup moves ip into the EIP group named in anno, the group is created if it does not exist.
+// ip is moved out of the group it belongs to before.
+func (bc *Baiducloud) ensureEIPInGroup(ctx context.Context, service *v1.Service, anno *ServiceAnnotation, ip string) error {
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	if bc.clientSet.EIPTagClient == nil {
+		return fmt.Errorf("move EIP %s into EIP group %s failed: EIP tag client is not configured", ip, anno.ElasticIPGroup)
+	}
+	current, err := bc.getEIPGroupOfIP(ctx, ip)
+	if err != nil {
+		return err
+	}
+	if current != nil && current.Name == anno.ElasticIPGroup {
+		return nil
+	}
+	if current != nil {
+		if err := bc.leaveEIPGroup(ctx, anno, current, ip); err != nil {
+			return err
+		}
+	}
+	token, err := bc.getEIPGroupToken(ctx, ip)
+	if err != nil {
+		return err
+	}
+	group, created, err := bc.getOrCreateEIPGroup(ctx, service, anno, token)
+	if err != nil {
+		return err
+	}
+	if !created {
+		// the group is created by CCM if EIPs in it say so
+		tagged, err := bc.clientSet.EIPTagClient.ListEIPsByTag(ctx, &tempeip.ListEIPsByTagArgs{
+			TagKey:   eipGroupCreatedTagKey,
+			TagValue: group.ID,
+		}, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+		created = len(tagged) != 0
+	}
+	// ip is tagged before moved in, so that it is always moved out by CCM
+	tags := []tempeip.Tag{{TagKey: eipGroupTagKey, TagValue: group.ID}}
+	if created {
+		tags = append(tags, tempeip.Tag{TagKey: eipGroupCreatedTagKey, TagValue: group.ID})
+	}
+	err = bc.clientSet.EIPTagClient.BindEIPTags(ctx, ip, &tempeip.EIPTagsArgs{ChangeTags: tags}, bc.getSignOption(ctx))
+	if err != nil {
+		return err
+	}
+	klog.Infof(Message(ctx, fmt.Sprintf("move EIP %s of service %s into EIP group %s(%s)", ip, serviceKey, group.Name, group.ID)))
+	err = bc.clientSet.EIPGroupClient.MoveInEIPs(ctx, group.ID, &tempeip.MoveInEIPsArgs{
+		EIPs: []string{ip},
+	}, bc.getSignOption(ctx))
+	if err != nil {
+		return fmt.Errorf("move EIP %s into EIP group %s failed: %v", ip, group.ID, err)
+	}
+	bc.eventRecorder.Eventf(service, v1.EventTypeNormal, "EIPGroupJoined", "Moved EIP %s into EIP group %s(%s)", ip, group.Name, group.ID)
+	return nil
+}
+
+// ensureEIPNotInGroup moves ip out of the EIP group CCM moved it into, it is billed as EIP annotations afterwards.
+// ip moved into group by others is left in it.
+func (bc *Baiducloud) ensureEIPNotInGroup(ctx context.Context, service *v1.Service, anno *ServiceAnnotation, ip string) error {
+	current, err := bc.getEIPGroupOfIP(ctx, ip)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return nil
+	}
+	movedIn, _, err := bc.getEIPGroupTags(ctx, current.ID, ip)
+	if err != nil {
+		return err
+	}
+	if !movedIn {
+		klog.V(3).Infof(Message(ctx, fmt.Sprintf("EIP %s is not moved into EIP group %s by CCM, keep it in group", ip, current.ID)))
+		return nil
+	}
+	if err := bc.leaveEIPGroup(ctx, anno, current, ip); err != nil {
+		return err
+	}
+	bc.eventRecorder.Eventf(service, v1.EventTypeNormal, "EIPGroupLeft", "Moved EIP %s out of EIP group %s(%s)", ip, current.Name, current.ID)
+	return nil
+}
+
+// leaveEIPGroup moves ip out of group with the billing in anno and removes the tags added by ensureEIPInGroup,
+// group created by CCM is released if it is empty afterwards
+func (bc *Baiducloud) leaveEIPGroup(ctx context.Context, anno *ServiceAnnotation, group *tempeip.EIPGroup, ip string) error {
+	movedIn, created, err := bc.getEIPGroupTags(ctx, group.ID, ip)
+	if err != nil {
+		return err
+	}
+	if err := bc.moveEIPOutOfGroup(ctx, anno, group.ID, ip); err != nil {
+		return err
+	}
+	if movedIn {
+		err = bc.clientSet.EIPTagClient.UnbindEIPTags(ctx, ip, &tempeip.EIPTagsArgs{
+			ChangeTags: []tempeip.Tag{{TagKey: eipGroupTagKey}, {TagKey: eipGroupCreatedTagKey}, {TagKey: eipGroupTokenTagKey}},
+		}, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+	}
+	if !created {
+		return nil
+	}
+	group, err = bc.clientSet.EIPGroupClient.GetEIPGroup(ctx, group.ID, bc.getSignOption(ctx))
+	if err != nil {
+		return err
+	}
+	if len(group.EIPs) != 0 {
+		return nil
+	}
+	klog.Infof(Message(ctx, fmt.Sprintf("release EIP group %s(%s) created by CCM, the last EIP %s left it", group.Name, group.ID, ip)))
+	if err := bc.clientSet.EIPGroupClient.DeleteEIPGroup(ctx, group.ID, bc.getSignOption(ctx)); err != nil {
+		// e.g. Prepaid EIP group can not be released before it expires
+		klog.Warningf(Message(ctx, fmt.Sprintf("release EIP group %s(%s) failed: %v", group.Name, group.ID, err)))
+	}
+	return nil
+}
+
+// getEIPGroupToken returns the token of ip joining EIP group, it is created if ip has none
+func (bc *Baiducloud) getEIPGroupToken(ctx context.Context, ip string) (string, error) {
+	tagged, err := bc.clientSet.EIPTagClient.ListEIPsByTag(ctx, &tempeip.ListEIPsByTagArgs{
+		TagKey: eipGroupTokenTagKey,
+	}, bc.getSignOption(ctx))
+	if err != nil {
+		return "", err
+	}
+	for _, e := range tagged {
+		if e.EIP == ip {
+			return getTagValue(e.Tags, eipGroupTokenTagKey), nil
+		}
+	}
+	token := GetRandom()
+	err = bc.clientSet.EIPTagClient.BindEIPTags(ctx, ip, &tempeip.EIPTagsArgs{
+		ChangeTags: []tempeip.Tag{{TagKey: eipGroupTokenTagKey, TagValue: token}},
+	}, bc.getSignOption(ctx))
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// getEIPGroupTags returns whether ip is moved into group by CCM and whether group is created by CCM, by tags of ip
+func (bc *Baiducloud) getEIPGroupTags(ctx context.Context, groupID, ip string) (bool, bool, error) {
+	if bc.clientSet.EIPTagClient == nil {
+		return false, false, nil
+	}
+	tagged, err := bc.clientSet.EIPTagClient.ListEIPsByTag(ctx, &tempeip.ListEIPsByTagArgs{
+		TagKey:   eipGroupTagKey,
+		TagValue: groupID,
+	}, bc.getSignOption(ctx))
+	if err != nil {
+		return false, false, err
+	}
+	for _, e := range tagged {
+		if e.EIP == ip {
+			return true, getTagValue(e.Tags, eipGroupCreatedTagKey) == groupID, nil
+		}
+	}
+	return false, false, nil
+}
+
+func (bc *Baiducloud) moveEIPOutOfGroup(ctx context.Context, anno *ServiceAnnotation, groupID string, ip string) error {
+	args, err := bc.getEipArgsFromAnnotation(anno)
+	if err != nil {
+		return err
+	}
+	billing := &tempeip.Billing{
+		PaymentTiming: args.Billing.PaymentTiming,
+		BillingMethod: args.Billing.BillingMethod,
+	}
+	if args.Billing.Reservation != nil {
+		billing.Reservation = &tempeip.Reservation{
+			ReservationLength:   args.Billing.Reservation.ReservationLength,
+			ReservationTimeUnit: args.Billing.Reservation.ReservationTimeUnit,
+		}
+	}
+	klog.Infof(Message(ctx, fmt.Sprintf("move EIP %s out of EIP group %s with billing %s/%s and bandwidth %d",
+		ip, groupID, billing.PaymentTiming, billing.BillingMethod, args.BandwidthInMbps)))
+	err = bc.clientSet.EIPGroupClient.MoveOutEIPs(ctx, groupID, &tempeip.MoveOutEIPsArgs{
+		MoveOutEIPs: []tempeip.MoveOutEIP{{
+			EIP:             ip,
+			BandwidthInMbps: args.BandwidthInMbps,
+			Billing:         billing,
+		}},
+	}, bc.getSignOption(ctx))
+	if err != nil {
+		return fmt.Errorf("move EIP %s out of EIP group %s failed: %v", ip, groupID, err)
+	}
+	return nil
+}
+
+// getOrCreateEIPGroup returns the EIP group named in anno and whether it is created now, token is the one
+// of the EIP joining the group. Names of EIP groups used by services must be unique, so they are created one by one.
+func (bc *Baiducloud) getOrCreateEIPGroup(ctx context.Context, service *v1.Service, anno *ServiceAnnotation, token string) (*tempeip.EIPGroup, bool, error) {
+	bc.eipGroupLock.Lock()
+	defer bc.eipGroupLock.Unlock()
+	groups, err := bc.clientSet.EIPGroupClient.ListEIPGroups(ctx, &tempeip.ListEIPGroupsArgs{
+		Name: anno.ElasticIPGroup,
+	}, bc.getSignOption(ctx))
+	if err != nil {
+		return nil, false, err
+	}
+	var named []*tempeip.EIPGroup
+	for _, group := range groups {
+		if group.Name == anno.ElasticIPGroup {
+			named = append(named, group)
+		}
+	}
+	if len(named) > 1 {
+		return nil, false, fmt.Errorf("multi EIP groups named %s exist", anno.ElasticIPGroup)
+	}
+	if len(named) == 1 {
+		return named[0], false, nil
+	}
+
+	args, err := bc.getEIPGroupArgsFromAnnotation(anno)
+	if err != nil {
+		return nil, false, err
+	}
+	args.ClientToken = fmt.Sprintf("%x", sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%s/%s", bc.ClusterID, clientTokenKindEIPGroup, anno.ElasticIPGroup, token))))
+	klog.Infof(Message(ctx, fmt.Sprintf("create EIP group %s for service %s/%s: %v", anno.ElasticIPGroup, service.Namespace, service.Name, args)))
+	id, err := bc.clientSet.EIPGroupClient.CreateEIPGroup(ctx, args, bc.getSignOption(ctx))
+	if err != nil {
+		return nil, false, fmt.Errorf("create EIP group %s failed: %v", anno.ElasticIPGroup, err)
+	}
+	bc.eventRecorder.Eventf(service, v1.EventTypeNormal, "EIPGroupCreated", "Created EIP group %s(%s)", anno.ElasticIPGroup, id)
+	group, err := bc.clientSet.EIPGroupClient.GetEIPGroup(ctx, id, bc.getSignOption(ctx))
+	return group, true, err
+}
+
+// getEIPGroupOfIP returns the EIP group ip belongs to, nil if ip is a standalone EIP
+func (bc *Baiducloud) getEIPGroupOfIP(ctx context.Context, ip string) (*tempeip.EIPGroup, error) {
+	if bc.clientSet.EIPGroupClient == nil {
+		return nil, nil
+	}
+	groups, err := bc.clientSet.EIPGroupClient.ListEIPGroups(ctx, nil, bc.getSignOption(ctx))
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		for _, e := range group.EIPs {
+			if e.EIP == ip {
+				return group, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// getTagValue returns the value of tag key in tags, empty if there is no such tag
+func getTagValue(tags []tempeip.Tag, key string) string {
+	for _, tag := range tags {
+		if tag.TagKey == key {
+			return tag.TagValue
+		}
+	}
+	return ""
+}
@@ -0,0 +1,152 @@
+package cloud_provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+	"k8s.io/client-go/tools/record"
+
+	tempeip "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-eip"
+)
+
+func TestGetEIPGroupArgsFromAnnotation(t *testing.T) {
+	cloud := NewFakeCloud("c-test")
+	args, err := cloud.getEIPGroupArgsFromAnnotation(&ServiceAnnotation{ElasticIPGroup: "shared"})
+	if err != nil {
+		t.Fatalf("getEIPGroupArgsFromAnnotation err, err: %v", err)
+	}
+	if args.Name != "shared" || args.Billing.PaymentTiming != eip.PAYMENTTIMING_POSTPAID ||
+		args.Billing.BillingMethod != eip.BILLINGMETHOD_BYBANDWIDTH || args.BandwidthInMbps != 200 {
+		t.Errorf("getEIPGroupArgsFromAnnotation err, got %+v %+v", args, args.Billing)
+	}
+	_, err = cloud.getEIPGroupArgsFromAnnotation(&ServiceAnnotation{
+		ElasticIPGroup:         "shared",
+		ElasticIPBillingMethod: eip.BILLINGMETHOD_BYTRAFFIC,
+	})
+	var vErr *validationError
+	if !errors.As(err, &vErr) {
+		t.Errorf("getEIPGroupArgsFromAnnotation err, ByTraffic should not be supported, err: %v", err)
+	}
+}
+
+func TestEnsureEIPInGroup(t *testing.T) {
+	cloud, nodesRes, blbResp, err := beforeTestBlb()
+	if err != nil {
+		t.Fatalf("beforeTestBlb err , %v", err)
+	}
+	cloud.eventRecorder = record.NewFakeRecorder(10)
+	ctx := context.Background()
+
+	// the group is created by the first service
+	svc := buildService()
+	svc.UID = "uid-1"
+	svc.Annotations = map[string]string{
+		ServiceAnnotationElasticIPGroup: "shared",
+	}
+	lb := &blb.LoadBalancer{BlbId: blbResp.LoadBalancerId}
+	ip, err := cloud.ensureEIPWithNoSpecificIP(ctx, svc, lb)
+	if err != nil {
+		t.Fatalf("ensureEIPWithNoSpecificIP err, err: %v", err)
+	}
+	group, err := cloud.getEIPGroupOfIP(ctx, ip)
+	if err != nil || group == nil || group.Name != "shared" {
+		t.Fatalf("EIP %s not in group shared, group: %v, err: %v", ip, group, err)
+	}
+	eips, err := cloud.getEipByIP(ctx, ip)
+	if err != nil || len(eips) != 1 {
+		t.Fatalf("getEipByIP err, eips: %v, err: %v", eips, err)
+	}
+	if eips[0].BandwidthInMbps != group.BandwidthInMbps {
+		t.Errorf("EIP in group should use bandwidth of group, got %d", eips[0].BandwidthInMbps)
+	}
+
+	// the second service joins the group
+	svc2 := buildService()
+	svc2.Name = "bar"
+	svc2.UID = "uid-2"
+	svc2.Annotations = map[string]string{
+		ServiceAnnotationElasticIPGroup: "shared",
+	}
+	blbResp2, err := cloud.clientSet.BLBClient.CreateLoadBalancer(ctx, &blb.CreateLoadBalancerArgs{
+		Name:     getBlbName(nodesRes.Nodes[0].ClusterID, svc2),
+		VpcID:    nodesRes.Nodes[0].VPCID,
+		SubnetID: nodesRes.Nodes[0].SubnetID,
+	}, nil)
+	if err != nil {
+		t.Fatalf("CreateLoadBalancer err, err: %v", err)
+	}
+	lb2 := &blb.LoadBalancer{BlbId: blbResp2.LoadBalancerId}
+	ip2, err := cloud.ensureEIPWithNoSpecificIP(ctx, svc2, lb2)
+	if err != nil {
+		t.Fatalf("ensureEIPWithNoSpecificIP err, err: %v", err)
+	}
+	group2, err := cloud.getEIPGroupOfIP(ctx, ip2)
+	if err != nil || group2 == nil || group2.ID != group.ID || len(group2.EIPs) != 2 {
+		t.Fatalf("EIP %s not in group %s, group: %v, err: %v", ip2, group.ID, group2, err)
+	}
+
+	// the EIP is moved out when the annotation is removed
+	lb.PublicIp = ip
+	svc.Annotations = map[string]string{}
+	if _, err := cloud.ensureEIPWithNoSpecificIP(ctx, svc, lb); err != nil {
+		t.Fatalf("ensureEIPWithNoSpecificIP err, err: %v", err)
+	}
+	if group, _ := cloud.getEIPGroupOfIP(ctx, ip); group != nil {
+		t.Errorf("EIP %s should be moved out of group %s", ip, group.ID)
+	}
+	eips, err = cloud.getEipByIP(ctx, ip)
+	if err != nil || len(eips) != 1 {
+		t.Fatalf("getEipByIP err, eips: %v, err: %v", eips, err)
+	}
+	if eips[0].BillingMethod != eip.BILLINGMETHOD_BYTRAFFIC || eips[0].BandwidthInMbps != 1000 {
+		t.Errorf("EIP moved out should be billed as annotations, got %s %d", eips[0].BillingMethod, eips[0].BandwidthInMbps)
+	}
+
+	// the EIP is moved out before released
+	lb2.PublicIp = ip2
+	if err := cloud.unbindEip(ctx, lb2, ip2); err != nil {
+		t.Fatalf("unbindEip err, err: %v", err)
+	}
+	if err := cloud.deleteEIP(ctx, ip2); err != nil {
+		t.Fatalf("deleteEIP err, err: %v", err)
+	}
+	// the group created by CCM is released after the last EIP left
+	if _, err := cloud.clientSet.EIPGroupClient.GetEIPGroup(ctx, group.ID, nil); err == nil {
+		t.Errorf("empty EIP group %s created by CCM should be released", group.ID)
+	}
+
+	// EIP moved into group by others is left in it
+	userGroupID, err := cloud.clientSet.EIPGroupClient.CreateEIPGroup(ctx, &tempeip.CreateEIPGroupArgs{
+		Name:    "user",
+		Billing: &tempeip.Billing{PaymentTiming: eip.PAYMENTTIMING_POSTPAID, BillingMethod: eip.BILLINGMETHOD_BYBANDWIDTH},
+	}, nil)
+	if err != nil {
+		t.Fatalf("CreateEIPGroup err, err: %v", err)
+	}
+	if err := cloud.clientSet.EIPGroupClient.MoveInEIPs(ctx, userGroupID, &tempeip.MoveInEIPsArgs{EIPs: []string{ip}}, nil); err != nil {
+		t.Fatalf("MoveInEIPs err, err: %v", err)
+	}
+	if _, err := cloud.ensureEIPWithNoSpecificIP(ctx, svc, lb); err != nil {
+		t.Fatalf("ensureEIPWithNoSpecificIP err, err: %v", err)
+	}
+	if group, _ := cloud.getEIPGroupOfIP(ctx, ip); group == nil || group.ID != userGroupID {
+		t.Errorf("EIP %s should be kept in group %s of user, got %v", ip, userGroupID, group)
+	}
+
+	// joining a group named as a released one creates a new group
+	svc.Annotations = map[string]string{ServiceAnnotationElasticIPGroup: "shared"}
+	if _, err := cloud.ensureEIPWithNoSpecificIP(ctx, svc, lb); err != nil {
+		t.Fatalf("ensureEIPWithNoSpecificIP err, err: %v", err)
+	}
+	newGroup, err := cloud.getEIPGroupOfIP(ctx, ip)
+	if err != nil || newGroup == nil || newGroup.Name != "shared" || newGroup.ID == group.ID {
+		t.Errorf("EIP %s should be moved into a new group shared, got %v, err: %v", ip, newGroup, err)
+	}
+	// the group of user is not released
+	if _, err := cloud.clientSet.EIPGroupClient.GetEIPGroup(ctx, userGroupID, nil); err != nil {
+		t.Errorf("EIP group %s of user should not be released: %v", userGroupID, err)
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+	"k8s.io/klog"
+
+	tempeip "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-eip"
+)
+
+const (
+	// EIPRouteTypeBGP is the default line type of EIP
+	EIPRouteTypeBGP = "BGP"
+	// EIPRouteTypeBGPS is the static BGP line type of EIP
+	EIPRouteTypeBGPS = "BGP_S"
+
+	// eipTagKeyPrefix is the prefix of tag keys used by the cluster, which are not allowed in annotation
+	eipTagKeyPrefix = "cce-"
+)
+
+// eipOptions are the options of EIP not supported by eip.CreateEIPArgs
+type eipOptions struct {
+	RouteType string
+	// AutoRenewLength is in months, 0 is not renewed automatically
+	AutoRenewLength int
+	// Tags are sorted by key
+	Tags []tempeip.Tag
+}
+
+// getEipOptionsFromAnnotation returns the options of EIP with paymentTiming in anno
+func getEipOptionsFromAnnotation(anno *ServiceAnnotation, paymentTiming string) (*eipOptions, error) {
+	opts := &eipOptions{
+		RouteType:       anno.ElasticIPRouteType,
+		AutoRenewLength: anno.ElasticIPAutoRenewLength,
+	}
+	switch opts.RouteType {
+	case "":
+		opts.RouteType = EIPRouteTypeBGP
+	case EIPRouteTypeBGP, EIPRouteTypeBGPS:
+	default:
+		return nil, newValidationError(fmt.Errorf("not support target ElasticIPRouteType: %v", opts.RouteType))
+	}
+	if opts.AutoRenewLength != 0 {
+		if paymentTiming != eip.PAYMENTTIMING_PREPAID {
+			return nil, newValidationError(fmt.Errorf("only Prepaid EIP can be renewed automatically"))
+		}
+		if !isEIPReservationLengthAllowed(opts.AutoRenewLength) {
+			return nil, newValidationError(fmt.Errorf("prepaid EIP autoRenewLength should in [1,2,3,4,5,6,7,8,9,12,24,36]"))
+		}
+	}
+	for k, v := range anno.ElasticIPTags {
+		if strings.HasPrefix(k, eipTagKeyPrefix) {
+			return nil, newValidationError(fmt.Errorf("EIP tag key %s is reserved, tag keys with prefix %s are used by the cluster", k, eipTagKeyPrefix))
+		}
+		opts.Tags = append(opts.Tags, tempeip.Tag{TagKey: k, TagValue: v})
+	}
+	sort.Slice(opts.Tags, func(i, j int) bool {
+		return opts.Tags[i].TagKey < opts.Tags[j].TagKey
+	})
+	return opts, nil
+}
+
+// createEIPWithOptions creates EIP with args and opts, EIP of a route type other than BGP is created by EIPOptionClient
+func (bc *Baiducloud) createEIPWithOptions(ctx context.Context, args *eip.CreateEIPArgs, opts *eipOptions) (string, error) {
+	var ip string
+	var err error
+	if opts.RouteType == EIPRouteTypeBGP {
+		ip, err = bc.createEIP(ctx, args)
+	} else {
+		optionArgs := &tempeip.CreateEIPArgs{
+			Name:            truncateLoadBalancerName(args.Name),
+			BandwidthInMbps: args.BandwidthInMbps,
+			Billing: &tempeip.Billing{
+				PaymentTiming: args.Billing.PaymentTiming,
+				BillingMethod: args.Billing.BillingMethod,
+			},
+			RouteType:   opts.RouteType,
+			ClientToken: args.ClientToken,
+		}
+		if args.Billing.Reservation != nil {
+			optionArgs.Billing.Reservation = &tempeip.Reservation{
+				ReservationLength:   args.Billing.Reservation.ReservationLength,
+				ReservationTimeUnit: args.Billing.Reservation.ReservationTimeUnit,
+			}
+		}
+		klog.Infof(Message(ctx, fmt.Sprintf("CreateEip with route type %s: %v", opts.RouteType, args)))
+		ip, err = bc.clientSet.EIPOptionClient.CreateEIP(ctx, optionArgs, bc.getSignOption(ctx))
+		if err == nil {
+			klog.Infof(Message(ctx, fmt.Sprintf("CreatedEIP is %s", ip)))
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	// auto-renew and tags are applied as to an existing EIP, so a retry after failure applies them again
+	if err := bc.ensureEIPOptions(ctx, ip, opts); err != nil {
+		return "", err
+	}
+	return ip, nil
+}
+
+// getEIPRouteType returns the route type of ip, empty if it is not returned by EIP API
+func (bc *Baiducloud) getEIPRouteType(ctx context.Context, ip string) (string, error) {
+	current, err := bc.clientSet.EIPOptionClient.GetEIP(ctx, ip, bc.getSignOption(ctx))
+	if err != nil {
+		return "", err
+	}
+	if current == nil {
+		return "", fmt.Errorf("EIP %s not exist", ip)
+	}
+	return current.RouteType, nil
+}
+
+// ensureEIPOptions applies auto-renew and tags in opts to ip. Tags not in opts are left on ip,
+// since they may be added by other tools.
+func (bc *Baiducloud) ensureEIPOptions(ctx context.Context, ip string, opts *eipOptions) error {
+	current, err := bc.clientSet.EIPOptionClient.GetEIP(ctx, ip, bc.getSignOption(ctx))
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return fmt.Errorf("EIP %s not exist", ip)
+	}
+	currentTags := make(map[string]string, len(current.Tags))
+	for _, tag := range current.Tags {
+		currentTags[tag.TagKey] = tag.TagValue
+	}
+
+	if getEIPAutoRenewLength(current) != opts.AutoRenewLength {
+		if opts.AutoRenewLength == 0 {
+			klog.Infof(Message(ctx, fmt.Sprintf("stop auto-renew of EIP %s", ip)))
+			err = bc.clientSet.EIPOptionClient.StopEIPAutoRenew(ctx, ip, bc.getSignOption(ctx))
+			if err != nil {
+				return fmt.Errorf("stop auto-renew of EIP %s failed: %v", ip, err)
+			}
+		} else {
+			args := &tempeip.StartEIPAutoRenewArgs{
+				AutoRenewTimeUnit: tempeip.AutoRenewTimeUnitMonth,
+				AutoRenewTime:     opts.AutoRenewLength,
+			}
+			// auto-renew is limited to 9 months, longer ones are in years
+			if opts.AutoRenewLength%12 == 0 {
+				args.AutoRenewTimeUnit = tempeip.AutoRenewTimeUnitYear
+				args.AutoRenewTime = opts.AutoRenewLength / 12
+			}
+			klog.Infof(Message(ctx, fmt.Sprintf("start auto-renew of EIP %s by %d %s", ip, args.AutoRenewTime, args.AutoRenewTimeUnit)))
+			err = bc.clientSet.EIPOptionClient.StartEIPAutoRenew(ctx, ip, args, bc.getSignOption(ctx))
+			if err != nil {
+				return fmt.Errorf("start auto-renew of EIP %s failed: %v", ip, err)
+			}
+		}
+	}
+	var changeTags []tempeip.Tag
+	for _, tag := range opts.Tags {
+		if value, ok := currentTags[tag.TagKey]; !ok || value != tag.TagValue {
+			changeTags = append(changeTags, tag)
+		}
+	}
+	if len(changeTags) == 0 {
+		return nil
+	}
+	klog.Infof(Message(ctx, fmt.Sprintf("bind tags %v to EIP %s", changeTags, ip)))
+	return bc.clientSet.EIPTagClient.BindEIPTags(ctx, ip, &tempeip.EIPTagsArgs{ChangeTags: changeTags}, bc.getSignOption(ctx))
+}
+
+// getEIPAutoRenewLength returns the months e is renewed by automatically, 0 if it is not renewed automatically
+func getEIPAutoRenewLength(e *tempeip.EIP) int {
+	if !e.AutoRenew {
+		return 0
+	}
+	if e.AutoRenewTimeUnit == tempeip.AutoRenewTimeUnitYear {
+		return e.AutoRenewTime * 12
+	}
+	return e.AutoRenewTime
+}
+
+func isEIPReservationLengthAllowed(length int) bool {
+	for _, allowed := range []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 12, 24, 36} {
+		if length == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package cloud_provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	fakeclient "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/fake"
+	tempeip "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-eip"
+)
+
+func TestGetEipOptionsFromAnnotation(t *testing.T) {
+	cloud := NewFakeCloud("c-option")
+	cases := []struct {
+		name    string
+		anno    *ServiceAnnotation
+		wantErr bool
+	}{
+		{name: "default", anno: &ServiceAnnotation{}},
+		{name: "BGP_S", anno: &ServiceAnnotation{ElasticIPRouteType: EIPRouteTypeBGPS}},
+		{name: "unknown route type", anno: &ServiceAnnotation{ElasticIPRouteType: "ChinaMobile"}, wantErr: true},
+		{name: "prepaid auto-renew", anno: &ServiceAnnotation{
+			ElasticIPPaymentTiming:     eip.PAYMENTTIMING_PREPAID,
+			ElasticIPReservationLength: 1,
+			ElasticIPAutoRenewLength:   12,
+		}},
+		{name: "prepaid auto-renew length", anno: &ServiceAnnotation{
+			ElasticIPPaymentTiming:     eip.PAYMENTTIMING_PREPAID,
+			ElasticIPReservationLength: 1,
+			ElasticIPAutoRenewLength:   10,
+		}, wantErr: true},
+		{name: "postpaid auto-renew", anno: &ServiceAnnotation{ElasticIPAutoRenewLength: 1}, wantErr: true},
+		{name: "tags", anno: &ServiceAnnotation{ElasticIPTags: map[string]string{"team": "web"}}},
+		{name: "reserved tag", anno: &ServiceAnnotation{ElasticIPTags: map[string]string{reservedEIPServiceTagKey: "x"}}, wantErr: true},
+	}
+	var vErr *validationError
+	for _, c := range cases {
+		_, err := cloud.getEipArgsFromAnnotation(c.anno)
+		if (err != nil) != c.wantErr || (err != nil && !errors.As(err, &vErr)) {
+			t.Errorf("case %s: getEipArgsFromAnnotation err %v, wantErr %v", c.name, err, c.wantErr)
+		}
+	}
+}
+
+func TestEnsureEIPOptions(t *testing.T) {
+	cloud, _, blbResp, err := beforeTestBlb()
+	if err != nil {
+		t.Fatalf("beforeTestBlb err , %v", err)
+	}
+	cloud.eventRecorder = record.NewFakeRecorder(10)
+	optionClient := cloud.clientSet.EIPOptionClient.(*fakeclient.EipOptionFakeClient)
+	ctx := context.Background()
+	svc := buildService()
+	svc.UID = "uid-1"
+	svc.Annotations = map[string]string{
+		ServiceAnnotationElasticIPPaymentTiming:     eip.PAYMENTTIMING_PREPAID,
+		ServiceAnnotationElasticIPReservationLength: "1",
+		ServiceAnnotationElasticIPRouteType:         EIPRouteTypeBGPS,
+		ServiceAnnotationElasticIPAutoRenewLength:   "24",
+		ServiceAnnotationElasticIPTags:              "team=web,env=prod",
+	}
+
+	// EIP is created with the options
+	lb := &blb.LoadBalancer{BlbId: blbResp.LoadBalancerId, Name: getBlbName(cloud.ClusterID, svc)}
+	ip, err := cloud.ensureEIPWithNoSpecificIP(ctx, svc, lb)
+	if err != nil {
+		t.Fatalf("ensureEIPWithNoSpecificIP err, err: %s", err)
+	}
+	current, err := optionClient.GetEIP(ctx, ip, nil)
+	if err != nil || current == nil {
+		t.Fatalf("GetEIP err, eip: %v, err: %v", current, err)
+	}
+	if current.RouteType != EIPRouteTypeBGPS {
+		t.Errorf("route type of EIP expected %s, got %s", EIPRouteTypeBGPS, current.RouteType)
+	}
+	if renew := optionClient.AutoRenewMap[ip]; renew == nil || renew.AutoRenewTimeUnit != "year" || renew.AutoRenewTime != 2 {
+		t.Errorf("auto-renew of EIP wrong: %+v", renew)
+	}
+	if length := getEIPAutoRenewLength(current); length != 24 {
+		t.Errorf("auto-renew length of EIP expected 24, got %d", length)
+	}
+	tags := optionClient.TagClient.TagMap[ip]
+	if tags["team"] != "web" || tags["env"] != "prod" {
+		t.Errorf("tags of EIP wrong: %v", tags)
+	}
+
+	// tags and auto-renew are reconciled on the bound EIP
+	lb.PublicIp = ip
+	delete(svc.Annotations, ServiceAnnotationElasticIPAutoRenewLength)
+	svc.Annotations[ServiceAnnotationElasticIPTags] = "team=api"
+	if _, err := cloud.ensureEIPWithNoSpecificIP(ctx, svc, lb); err != nil {
+		t.Fatalf("ensureEIPWithNoSpecificIP err, err: %s", err)
+	}
+	if renew := optionClient.AutoRenewMap[ip]; renew != nil {
+		t.Errorf("auto-renew of EIP not stopped: %+v", renew)
+	}
+	tags = optionClient.TagClient.TagMap[ip]
+	if tags["team"] != "api" || tags["env"] != "prod" {
+		t.Errorf("tags of EIP wrong: %v", tags)
+	}
+
+	// auto-renew changed out of CCM is applied again
+	optionClient.AutoRenewMap[ip] = &tempeip.StartEIPAutoRenewArgs{AutoRenewTimeUnit: tempeip.AutoRenewTimeUnitMonth, AutoRenewTime: 3}
+	if _, err := cloud.ensureEIPWithNoSpecificIP(ctx, svc, lb); err != nil {
+		t.Fatalf("ensureEIPWithNoSpecificIP err, err: %s", err)
+	}
+	if renew := optionClient.AutoRenewMap[ip]; renew != nil {
+		t.Errorf("auto-renew of EIP not stopped: %+v", renew)
+	}
+
+	// route type change is rejected by default, and recreates EIP if allowed
+	delete(svc.Annotations, ServiceAnnotationElasticIPRouteType)
+	var vErr *validationError
+	if _, err := cloud.ensureEIPWithNoSpecificIP(ctx, svc, lb); !errors.As(err, &vErr) {
+		t.Errorf("ensureEIPWithNoSpecificIP err, route type change should be rejected as invalid setting, err: %v", err)
+	}
+	cloud.kubeClient = fake.NewSimpleClientset(svc)
+	svc.Annotations[ServiceAnnotationElasticIPBillingChangePolicy] = EIPBillingChangeRecreate
+	newIP, err := cloud.ensureEIPWithNoSpecificIP(ctx, svc, lb)
+	if err != nil {
+		t.Fatalf("ensureEIPWithNoSpecificIP err, err: %s", err)
+	}
+	if newIP == ip {
+		t.Errorf("ensureEIPWithNoSpecificIP err, EIP %s not recreated", ip)
+	}
+	current, err = optionClient.GetEIP(ctx, newIP, nil)
+	if err != nil || current == nil || current.RouteType != EIPRouteTypeBGP {
+		t.Errorf("recreated EIP wrong: %+v, err: %v", current, err)
+	}
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog"
+
+	cce_v1alpha1 "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/apis/cce/v1alpha1"
+)
+
+// ensureEIPFromPool binds the EIP claimed by service from pool to lb, a free EIP is claimed if service has none
+func (bc *Baiducloud) ensureEIPFromPool(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer, poolName string) (string, error) {
+	ip, err := bc.claimPoolEIP(ctx, service, lb, poolName)
+	if err != nil {
+		return "", err
+	}
+	return bc.ensureEIPBound(ctx, service, lb, ip)
+}
+
+// claimPoolEIP returns the EIP claimed by service from pool. Otherwise a free EIP, which exists and is bound to
+// no other instance, is claimed in the order of spec.eips and the claim is recorded in status of pool.
+func (bc *Baiducloud) claimPoolEIP(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer, poolName string) (string, error) {
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	pool, err := bc.getEIPPool(poolName)
+	if err != nil {
+		return "", err
+	}
+	claimed := make(map[string]bool, len(pool.Status.Claims))
+	claims := make([]cce_v1alpha1.EIPPoolClaim, 0, len(pool.Status.Claims))
+	for _, claim := range pool.Status.Claims {
+		if claim.ServiceUID == string(service.UID) {
+			if err := bc.recordEIPPoolClaim(service, poolName); err != nil {
+				return "", err
+			}
+			return claim.EIP, nil
+		}
+		// claims of deleted services are dropped, otherwise their EIPs are never free again
+		stale, err := bc.isStaleEIPPoolClaim(claim)
+		if err != nil {
+			return "", err
+		}
+		if stale {
+			klog.Infof(Message(ctx, fmt.Sprintf("service %s/%s of EIP %s not exist, drop its claim from pool %s", claim.ServiceNamespace, claim.ServiceName, claim.EIP, poolName)))
+			continue
+		}
+		claims = append(claims, claim)
+		claimed[claim.EIP] = true
+	}
+	pool.Status.Claims = claims
+
+	for _, ip := range pool.Spec.EIPs {
+		if claimed[ip] {
+			continue
+		}
+		eips, err := bc.getEipByIP(ctx, ip)
+		if err != nil {
+			return "", err
+		}
+		if len(eips) == 0 {
+			klog.Warningf(Message(ctx, fmt.Sprintf("EIP %s of pool %s not exist, skip it", ip, poolName)))
+			continue
+		}
+		if eips[0].InstanceID != "" && eips[0].InstanceID != lb.BlbId {
+			klog.Warningf(Message(ctx, fmt.Sprintf("EIP %s of pool %s is bound to %s %s, skip it", ip, poolName, eips[0].InstanceType, eips[0].InstanceID)))
+			continue
+		}
+		pool.Status.Claims = append(pool.Status.Claims, cce_v1alpha1.EIPPoolClaim{
+			EIP:              ip,
+			ServiceNamespace: service.Namespace,
+			ServiceName:      service.Name,
+			ServiceUID:       string(service.UID),
+		})
+		// a conflict fails the claim, the retry claims again with the latest pool
+		if err := bc.updateEIPPoolStatus(pool); err != nil {
+			return "", err
+		}
+		if err := bc.recordEIPPoolClaim(service, poolName); err != nil {
+			return "", err
+		}
+		klog.Infof(Message(ctx, fmt.Sprintf("service %s claimed EIP %s from pool %s", serviceKey, ip, poolName)))
+		bc.eventRecorder.Eventf(service, v1.EventTypeNormal, "EIPClaimed", "Claimed EIP %s from pool %s", ip, poolName)
+		return ip, nil
+	}
+	bc.eventRecorder.Eventf(service, v1.EventTypeWarning, "EIPPoolExhausted", "No free EIP in pool %s", poolName)
+	return "", fmt.Errorf("no free EIP in pool %s for service %s", poolName, serviceKey)
+}
+
+// recordEIPPoolClaim records in annotation of service the pool which its EIP is claimed from
+func (bc *Baiducloud) recordEIPPoolClaim(service *v1.Service, poolName string) error {
+	if service.Annotations[ServiceAnnotationCceEIPPool] == poolName {
+		return nil
+	}
+	return bc.updateServiceAnnotation(service, ServiceAnnotationCceEIPPool, poolName)
+}
+
+// isStaleEIPPoolClaim returns whether the service of claim not exists, a service recreated with the same name has another UID
+func (bc *Baiducloud) isStaleEIPPoolClaim(claim cce_v1alpha1.EIPPoolClaim) (bool, error) {
+	if bc.serviceLister == nil {
+		return false, nil
+	}
+	svc, err := bc.serviceLister.Services(claim.ServiceNamespace).Get(claim.ServiceName)
+	if errors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return string(svc.UID) != claim.ServiceUID, nil
+}
+
+// returnPoolEIPs returns the EIPs claimed by service to their pools except the pool named keepPool,
+// the EIPs are unbound from lb and kept. It returns whether any EIP is returned.
+// Services using no pool and recording no claim have nothing to return, EIPPools are not listed for them.
+func (bc *Baiducloud) returnPoolEIPs(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer, keepPool string) (bool, error) {
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	if bc.dynamicClient == nil {
+		return false, nil
+	}
+	claimedPool := service.Annotations[ServiceAnnotationCceEIPPool]
+	if len(claimedPool) == 0 && len(service.Annotations[ServiceAnnotationElasticIPPool]) == 0 {
+		return false, nil
+	}
+	if len(claimedPool) != 0 && claimedPool == keepPool {
+		return false, nil
+	}
+	pools, err := bc.listEIPPools()
+	if err != nil {
+		return false, err
+	}
+	returned := false
+	for i := range pools {
+		pool := &pools[i]
+		if pool.Name == keepPool {
+			continue
+		}
+		var claims []cce_v1alpha1.EIPPoolClaim
+		var ips []string
+		for _, claim := range pool.Status.Claims {
+			if claim.ServiceUID == string(service.UID) {
+				ips = append(ips, claim.EIP)
+				continue
+			}
+			claims = append(claims, claim)
+		}
+		if len(ips) == 0 {
+			continue
+		}
+		for _, ip := range ips {
+			if lb != nil && lb.PublicIp == ip {
+				if err := bc.unbindEip(ctx, lb, ip); err != nil {
+					return false, err
+				}
+				lb.PublicIp = ""
+			}
+		}
+		pool.Status.Claims = claims
+		if err := bc.updateEIPPoolStatus(pool); err != nil {
+			return false, err
+		}
+		klog.Infof(Message(ctx, fmt.Sprintf("service %s returned EIP %v to pool %s", serviceKey, ips, pool.Name)))
+		bc.eventRecorder.Eventf(service, v1.EventTypeNormal, "EIPReturned", "Returned EIP %v to pool %s", ips, pool.Name)
+		returned = true
+	}
+	// the service may be gone when its EIPs are returned on deletion
+	if err := bc.removeServiceAnnotation(service, ServiceAnnotationCceEIPPool); err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	return returned, nil
+}
+
+func (bc *Baiducloud) getEIPPool(name string) (*cce_v1alpha1.EIPPool, error) {
+	if bc.dynamicClient == nil {
+		return nil, fmt.Errorf("get EIPPool %s failed: dynamic client is not initialized", name)
+	}
+	obj, err := bc.dynamicClient.Resource(cce_v1alpha1.EIPPoolResource).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get EIPPool %s failed: %v", name, err)
+	}
+	pool := &cce_v1alpha1.EIPPool{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), pool); err != nil {
+		return nil, fmt.Errorf("convert EIPPool %s failed: %v", name, err)
+	}
+	return pool, nil
+}
+
+// listEIPPools returns all EIPPools, none if the CRD is not installed or CCM is not allowed to list it
+func (bc *Baiducloud) listEIPPools() ([]cce_v1alpha1.EIPPool, error) {
+	list, err := bc.dynamicClient.Resource(cce_v1alpha1.EIPPoolResource).List(metav1.ListOptions{})
+	if errors.IsNotFound(err) || errors.IsForbidden(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list EIPPools failed: %v", err)
+	}
+	pools := make([]cce_v1alpha1.EIPPool, len(list.Items))
+	for i := range list.Items {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(list.Items[i].UnstructuredContent(), &pools[i]); err != nil {
+			return nil, fmt.Errorf("convert EIPPool %s failed: %v", list.Items[i].GetName(), err)
+		}
+	}
+	return pools, nil
+}
+
+func (bc *Baiducloud) updateEIPPoolStatus(pool *cce_v1alpha1.EIPPool) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pool)
+	if err != nil {
+		return fmt.Errorf("convert EIPPool %s failed: %v", pool.Name, err)
+	}
+	_, err = bc.dynamicClient.Resource(cce_v1alpha1.EIPPoolResource).UpdateStatus(&unstructured.Unstructured{Object: content}, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("update status of EIPPool %s failed: %v", pool.Name, err)
+	}
+	return nil
+}
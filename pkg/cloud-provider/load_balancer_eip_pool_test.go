@@ -0,0 +1,259 @@
+package cloud_provider
+
+import (
+	"context"
+	"testing"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	cce_v1alpha1 "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/apis/cce/v1alpha1"
+)
+
+func newFakeEIPPool(t *testing.T, name string, eips []string) runtime.Object {
+	pool := &cce_v1alpha1.EIPPool{
+		TypeMeta: meta_v1.TypeMeta{
+			APIVersion: cce_v1alpha1.SchemeGroupVersion.String(),
+			Kind:       "EIPPool",
+		},
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name: name,
+		},
+		Spec: cce_v1alpha1.EIPPoolSpec{EIPs: eips},
+	}
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pool)
+	if err != nil {
+		t.Fatalf("ToUnstructured failed: %v", err)
+	}
+	return &unstructured.Unstructured{Object: obj}
+}
+
+func TestEnsureEIPFromPool(t *testing.T) {
+	cloud, nodesRes, blbResp, err := beforeTestBlb()
+	if err != nil {
+		t.Fatalf("beforeTestBlb err , %v", err)
+	}
+	cloud.eventRecorder = record.NewFakeRecorder(20)
+	ctx := context.Background()
+
+	args, err := cloud.getEipArgsFromAnnotation(&ServiceAnnotation{})
+	if err != nil {
+		t.Fatalf("getEipArgsFromAnnotation err, err: %v", err)
+	}
+	var ips []string
+	for i := 0; i < 2; i++ {
+		ip, err := cloud.createEIP(ctx, args)
+		if err != nil {
+			t.Fatalf("createEIP err, err: %v", err)
+		}
+		ips = append(ips, ip)
+	}
+	// EIPs not exist are skipped
+	cloud.dynamicClient = dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		newFakeEIPPool(t, "allow-listed", append([]string{"1.1.1.1"}, ips...)))
+
+	newService := func(name string) *api.Service {
+		svc := buildService()
+		svc.Name = name
+		svc.UID = types.UID("uid-" + name)
+		svc.Annotations = map[string]string{ServiceAnnotationElasticIPPool: "allow-listed"}
+		return svc
+	}
+	newLB := func(svc *api.Service) *blb.LoadBalancer {
+		resp, err := cloud.clientSet.BLBClient.CreateLoadBalancer(ctx, &blb.CreateLoadBalancerArgs{
+			Name:     getBlbName(nodesRes.Nodes[0].ClusterID, svc),
+			VpcID:    nodesRes.Nodes[0].VPCID,
+			SubnetID: nodesRes.Nodes[0].SubnetID,
+		}, nil)
+		if err != nil {
+			t.Fatalf("CreateLoadBalancer err, err: %v", err)
+		}
+		return &blb.LoadBalancer{BlbId: resp.LoadBalancerId}
+	}
+
+	svc1 := newService("foo")
+	lb1 := &blb.LoadBalancer{BlbId: blbResp.LoadBalancerId}
+	ip, err := cloud.ensureEIPFromPool(ctx, svc1, lb1, "allow-listed")
+	if err != nil {
+		t.Fatalf("ensureEIPFromPool err, err: %v", err)
+	}
+	if ip != ips[0] || lb1.PublicIp != ips[0] {
+		t.Errorf("ensureEIPFromPool err, expected %s, got %s", ips[0], ip)
+	}
+	if pool := svc1.Annotations[ServiceAnnotationCceEIPPool]; pool != "allow-listed" {
+		t.Errorf("claim of service foo should be recorded in annotation, got %q", pool)
+	}
+	// the claim is kept
+	ip, err = cloud.ensureEIPFromPool(ctx, svc1, lb1, "allow-listed")
+	if err != nil || ip != ips[0] {
+		t.Errorf("ensureEIPFromPool err, expected %s, got %s, err: %v", ips[0], ip, err)
+	}
+
+	svc2 := newService("bar")
+	ip, err = cloud.ensureEIPFromPool(ctx, svc2, newLB(svc2), "allow-listed")
+	if err != nil || ip != ips[1] {
+		t.Errorf("ensureEIPFromPool err, expected %s, got %s, err: %v", ips[1], ip, err)
+	}
+	svc3 := newService("baz")
+	lb3 := newLB(svc3)
+	if _, err := cloud.ensureEIPFromPool(ctx, svc3, lb3, "allow-listed"); err == nil {
+		t.Errorf("ensureEIPFromPool err, pool should be exhausted")
+	}
+
+	// the EIP is returned instead of deleted
+	if err := cloud.ensureEipDeleted(ctx, svc1, lb1); err != nil {
+		t.Fatalf("ensureEipDeleted err, err: %v", err)
+	}
+	eips, err := cloud.getEipByIP(ctx, ips[0])
+	if err != nil || len(eips) != 1 {
+		t.Fatalf("EIP %s of pool should not be deleted, eips: %v, err: %v", ips[0], eips, err)
+	}
+	if eips[0].InstanceID != "" {
+		t.Errorf("EIP %s of pool should be unbound, got %s", ips[0], eips[0].InstanceID)
+	}
+	pool, err := cloud.getEIPPool("allow-listed")
+	if err != nil {
+		t.Fatalf("getEIPPool err, err: %v", err)
+	}
+	if len(pool.Status.Claims) != 1 || pool.Status.Claims[0].ServiceName != "bar" {
+		t.Errorf("claim of service foo should be removed, got %v", pool.Status.Claims)
+	}
+	if _, ok := svc1.Annotations[ServiceAnnotationCceEIPPool]; ok {
+		t.Errorf("claim of service foo should be removed from annotation")
+	}
+	ip, err = cloud.ensureEIPFromPool(ctx, svc3, lb3, "allow-listed")
+	if err != nil || ip != ips[0] {
+		t.Errorf("ensureEIPFromPool err, expected %s, got %s, err: %v", ips[0], ip, err)
+	}
+
+	// the claim of deleted service bar is dropped, its EIP unbound by deleting BLB is free again
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	if err := indexer.Add(svc3); err != nil {
+		t.Fatalf("add service failed: %v", err)
+	}
+	cloud.serviceLister = corelisters.NewServiceLister(indexer)
+	if err := cloud.clientSet.EIPClient.UnbindEIP(ctx, ips[1], nil); err != nil {
+		t.Fatalf("UnbindEIP err, err: %v", err)
+	}
+	svc4 := newService("qux")
+	ip, err = cloud.ensureEIPFromPool(ctx, svc4, newLB(svc4), "allow-listed")
+	if err != nil || ip != ips[1] {
+		t.Errorf("ensureEIPFromPool err, expected %s, got %s, err: %v", ips[1], ip, err)
+	}
+	pool, err = cloud.getEIPPool("allow-listed")
+	if err != nil {
+		t.Fatalf("getEIPPool err, err: %v", err)
+	}
+	for _, claim := range pool.Status.Claims {
+		if claim.ServiceName == "bar" {
+			t.Errorf("claim of service bar should be dropped, got %v", pool.Status.Claims)
+		}
+	}
+}
+
+func TestEnsureEIPFromPoolReleasesCreatedEIP(t *testing.T) {
+	cloud, _, blbResp, err := beforeTestBlb()
+	if err != nil {
+		t.Fatalf("beforeTestBlb err , %v", err)
+	}
+	cloud.eventRecorder = record.NewFakeRecorder(20)
+	ctx := context.Background()
+
+	svc := buildService()
+	svc.UID = types.UID("uid-foo")
+	svc.Annotations = map[string]string{}
+	lb := &blb.LoadBalancer{BlbId: blbResp.LoadBalancerId, Name: getBlbName(cloud.ClusterID, svc)}
+	created, err := cloud.ensureEIPWithNoSpecificIP(ctx, svc, lb)
+	if err != nil {
+		t.Fatalf("ensureEIPWithNoSpecificIP err, err: %v", err)
+	}
+	lb.PublicIp = created
+	kind := cloud.clientTokenKind(svc, clientTokenKindEIP)
+
+	args, err := cloud.getEipArgsFromAnnotation(&ServiceAnnotation{})
+	if err != nil {
+		t.Fatalf("getEipArgsFromAnnotation err, err: %v", err)
+	}
+	args.Name = "user-eip"
+	pooled, err := cloud.createEIP(ctx, args)
+	if err != nil {
+		t.Fatalf("createEIP err, err: %v", err)
+	}
+	cloud.dynamicClient = dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), newFakeEIPPool(t, "allow-listed", []string{pooled}))
+	svc.Annotations[ServiceAnnotationElasticIPPool] = "allow-listed"
+
+	ip, err := cloud.ensureEIPFromPool(ctx, svc, lb, "allow-listed")
+	if err != nil || ip != pooled {
+		t.Fatalf("ensureEIPFromPool err, expected %s, got %s, err: %v", pooled, ip, err)
+	}
+	eips, err := cloud.getEipByIP(ctx, created)
+	if err != nil || len(eips) != 0 {
+		t.Errorf("EIP %s created for service should be released, eips: %v, err: %v", created, eips, err)
+	}
+	if cloud.clientTokenKind(svc, clientTokenKindEIP) == kind {
+		t.Errorf("client token of EIP should change after releasing EIP %s", created)
+	}
+
+	// EIPs not created for service are only unbound
+	cloud.dynamicClient = dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), newFakeEIPPool(t, "other", []string{created}))
+	args.Name = "other-eip"
+	other, err := cloud.createEIP(ctx, args)
+	if err != nil {
+		t.Fatalf("createEIP err, err: %v", err)
+	}
+	if _, err := cloud.ensureEIPBound(ctx, svc, lb, other); err != nil {
+		t.Fatalf("ensureEIPBound err, err: %v", err)
+	}
+	eips, err = cloud.getEipByIP(ctx, pooled)
+	if err != nil || len(eips) != 1 {
+		t.Errorf("EIP %s of pool should not be released, eips: %v, err: %v", pooled, eips, err)
+	}
+}
+
+func TestReturnPoolEIPs(t *testing.T) {
+	cloud, _, blbResp, err := beforeTestBlb()
+	if err != nil {
+		t.Fatalf("beforeTestBlb err , %v", err)
+	}
+	cloud.eventRecorder = record.NewFakeRecorder(20)
+	ctx := context.Background()
+	lb := &blb.LoadBalancer{BlbId: blbResp.LoadBalancerId}
+	svc := buildService()
+	svc.UID = types.UID("uid-foo")
+	svc.Annotations = map[string]string{}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	listed := 0
+	dynamicClient.PrependReactor("list", "eippools", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		listed++
+		return true, nil, errors.NewForbidden(cce_v1alpha1.EIPPoolResource.GroupResource(), "", nil)
+	})
+	cloud.dynamicClient = dynamicClient
+
+	// EIPPools are not listed for services using no pool
+	if returned, err := cloud.returnPoolEIPs(ctx, svc, lb, ""); err != nil || returned {
+		t.Errorf("returnPoolEIPs err, returned: %v, err: %v", returned, err)
+	}
+	if listed != 0 {
+		t.Errorf("EIPPools should not be listed for service using no pool, listed %d times", listed)
+	}
+
+	// EIPPools not allowed to list are the same as the CRD not installed
+	svc.Annotations[ServiceAnnotationCceEIPPool] = "allow-listed"
+	if returned, err := cloud.returnPoolEIPs(ctx, svc, lb, ""); err != nil || returned {
+		t.Errorf("returnPoolEIPs err, returned: %v, err: %v", returned, err)
+	}
+	if listed != 1 {
+		t.Errorf("EIPPools should be listed for service recording a claim, listed %d times", listed)
+	}
+}
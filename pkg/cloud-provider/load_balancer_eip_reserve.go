@@ -0,0 +1,191 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+
+	tempeip "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-eip"
+)
+
+const (
+	// reservedEIPServiceTagKey is the tag key of EIPs kept by reserve-eip, the value is <clusterID>/<namespace>/<name> of service
+	reservedEIPServiceTagKey = "cce-reserved-service"
+	// reservedEIPAtTagKey is the tag key of when the EIP is kept, in unix seconds
+	reservedEIPAtTagKey = "cce-reserved-at"
+
+	defaultReservedEIPTTL    = 7 * 24 * time.Hour
+	reservedEIPReleasePeriod = 10 * time.Minute
+)
+
+// reservedEIPTagValue returns the value of reservedEIPServiceTagKey for EIPs kept for service
+func (bc *Baiducloud) reservedEIPTagValue(service *v1.Service) string {
+	return fmt.Sprintf("%s/%s/%s", bc.ClusterID, service.Namespace, service.Name)
+}
+
+// reservedEIPTTL returns how long a kept EIP stays unbound before it is released
+func (bc *Baiducloud) reservedEIPTTL() time.Duration {
+	if bc.ReservedEIPTTLInSecond <= 0 {
+		return defaultReservedEIPTTL
+	}
+	return time.Duration(bc.ReservedEIPTTLInSecond) * time.Second
+}
+
+// reserveEIP unbinds ip from lb and tags it with service instead of deleting it
+func (bc *Baiducloud) reserveEIP(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer, ip string) error {
+	if bc.clientSet.EIPTagClient == nil {
+		return fmt.Errorf("reserve EIP %s failed: EIP tag client is not configured", ip)
+	}
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	if lb != nil {
+		eips, err := bc.getEipByIP(ctx, ip)
+		if err != nil {
+			return err
+		}
+		if len(eips) == 0 {
+			return fmt.Errorf("reserve EIP %s failed: EIP not exist", ip)
+		}
+		// a retry after unbinding only tags the EIP, unbinding an unbound EIP fails
+		if eips[0].InstanceID != "" {
+			if err := bc.clientSet.EIPClient.UnbindEIP(ctx, ip, bc.getSignOption(ctx)); err != nil {
+				return err
+			}
+		}
+	}
+	args := tempeip.EIPTagsArgs{
+		ChangeTags: []tempeip.Tag{
+			{TagKey: reservedEIPServiceTagKey, TagValue: bc.reservedEIPTagValue(service)},
+			{TagKey: reservedEIPAtTagKey, TagValue: strconv.FormatInt(time.Now().Unix(), 10)},
+		},
+	}
+	if err := bc.clientSet.EIPTagClient.BindEIPTags(ctx, ip, &args, bc.getSignOption(ctx)); err != nil {
+		return err
+	}
+	klog.Infof(Message(ctx, fmt.Sprintf("EIP %s of service %s is reserved for %v", ip, serviceKey, bc.reservedEIPTTL())))
+	bc.eventRecorder.Eventf(service, v1.EventTypeNormal, "EIPReserved", "Reserved EIP %s, it is released if service does not return in %v", ip, bc.reservedEIPTTL())
+	return nil
+}
+
+// getReservedEIP returns the EIP kept for service by reserve-eip, empty if none
+func (bc *Baiducloud) getReservedEIP(ctx context.Context, service *v1.Service) (string, error) {
+	if bc.clientSet.EIPTagClient == nil {
+		return "", nil
+	}
+	args := tempeip.ListEIPsByTagArgs{
+		TagKey:   reservedEIPServiceTagKey,
+		TagValue: bc.reservedEIPTagValue(service),
+	}
+	eips, err := bc.clientSet.EIPTagClient.ListEIPsByTag(ctx, &args, bc.getSignOption(ctx))
+	if err != nil {
+		return "", err
+	}
+	if len(eips) == 0 {
+		return "", nil
+	}
+	if len(eips) > 1 {
+		return "", fmt.Errorf("has multi eips reserved for service %s/%s: %d", service.Namespace, service.Name, len(eips))
+	}
+	return eips[0].EIP, nil
+}
+
+// unreserveEIP removes the tags added by reserveEIP, after which ip is treated as other EIPs of services
+func (bc *Baiducloud) unreserveEIP(ctx context.Context, ip string) error {
+	args := tempeip.EIPTagsArgs{
+		ChangeTags: []tempeip.Tag{
+			{TagKey: reservedEIPServiceTagKey},
+			{TagKey: reservedEIPAtTagKey},
+		},
+	}
+	return bc.clientSet.EIPTagClient.UnbindEIPTags(ctx, ip, &args, bc.getSignOption(ctx))
+}
+
+// listReservedEIPs returns the EIPs kept by reserve-eip for services of the cluster
+func (bc *Baiducloud) listReservedEIPs(ctx context.Context) ([]*tempeip.TaggedEIP, error) {
+	if bc.clientSet.EIPTagClient == nil {
+		return nil, nil
+	}
+	args := tempeip.ListEIPsByTagArgs{
+		TagKey: reservedEIPServiceTagKey,
+	}
+	eips, err := bc.clientSet.EIPTagClient.ListEIPsByTag(ctx, &args, bc.getSignOption(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var result []*tempeip.TaggedEIP
+	for _, e := range eips {
+		if strings.HasPrefix(getTagValue(e.Tags, reservedEIPServiceTagKey), bc.ClusterID+"/") {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+// reservedEIPSet returns the set of EIPs kept by reserve-eip, which are not collected as orphaned
+func (bc *Baiducloud) reservedEIPSet(ctx context.Context) (sets.String, error) {
+	eips, err := bc.listReservedEIPs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := sets.NewString()
+	for _, e := range eips {
+		result.Insert(e.EIP)
+	}
+	return result, nil
+}
+
+// releaseExpiredReservedEIPs deletes the EIPs kept by reserve-eip for longer than the TTL,
+// EIPs bound again are left with their tags removed
+func (bc *Baiducloud) releaseExpiredReservedEIPs() {
+	ctx := context.WithValue(context.Background(), RequestID, GetRandom())
+	eips, err := bc.listReservedEIPs(ctx)
+	if err != nil {
+		klog.Errorf(Message(ctx, fmt.Sprintf("list reserved EIPs failed: %v", err)))
+		return
+	}
+	ttl := bc.reservedEIPTTL()
+	for _, e := range eips {
+		service := getTagValue(e.Tags, reservedEIPServiceTagKey)
+		if e.InstanceID != "" {
+			klog.Infof(Message(ctx, fmt.Sprintf("reserved EIP %s of service %s is bound to %s, unreserve it", e.EIP, service, e.InstanceID)))
+			if err := bc.unreserveEIP(ctx, e.EIP); err != nil {
+				klog.Errorf(Message(ctx, fmt.Sprintf("unreserve EIP %s failed: %v", e.EIP, err)))
+			}
+			continue
+		}
+		reservedAt, err := strconv.ParseInt(getTagValue(e.Tags, reservedEIPAtTagKey), 10, 64)
+		if err != nil {
+			klog.Warningf(Message(ctx, fmt.Sprintf("reserved EIP %s of service %s has invalid tag %s: %v", e.EIP, service, reservedEIPAtTagKey, err)))
+			continue
+		}
+		if time.Since(time.Unix(reservedAt, 0)) < ttl {
+			continue
+		}
+		klog.Infof(Message(ctx, fmt.Sprintf("reserved EIP %s of service %s expired after %v, release it", e.EIP, service, ttl)))
+		if err := bc.deleteEIP(ctx, e.EIP); err != nil {
+			klog.Errorf(Message(ctx, fmt.Sprintf("release reserved EIP %s failed: %v", e.EIP, err)))
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	"k8s.io/client-go/tools/record"
+
+	"icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/fake"
+)
+
+// strictUnbindEipClient fails to unbind EIPs not bound as EIP API does
+type strictUnbindEipClient struct {
+	*fake.EipFakeClient
+}
+
+func (c *strictUnbindEipClient) UnbindEIP(ctx context.Context, ip string, option *bce.SignOption) error {
+	if e, ok := c.EIPMap[ip]; ok && e.InstanceID == "" {
+		return fmt.Errorf("EIP %s is not bound", ip)
+	}
+	return c.EipFakeClient.UnbindEIP(ctx, ip, option)
+}
+
+func TestReserveEIP(t *testing.T) {
+	cloud, _, blbResp, err := beforeTestBlb()
+	if err != nil {
+		t.Fatalf("beforeTestBlb err , %v", err)
+	}
+	cloud.eventRecorder = record.NewFakeRecorder(10)
+	ctx := context.Background()
+	svc := buildService()
+	svc.UID = "uid-1"
+	svc.Annotations = map[string]string{ServiceAnnotationLoadBalancerReserveEIP: "true"}
+
+	// EIP is unbound and tagged instead of deleted
+	lb := &blb.LoadBalancer{BlbId: blbResp.LoadBalancerId, Name: getBlbName(cloud.ClusterID, svc)}
+	ip, err := cloud.ensureEIPWithNoSpecificIP(ctx, svc, lb)
+	if err != nil {
+		t.Fatalf("ensureEIPWithNoSpecificIP err, err: %s", err)
+	}
+	lb.PublicIp = ip
+	if err := cloud.ensureEipDeleted(ctx, svc, lb); err != nil {
+		t.Fatalf("ensureEipDeleted err, err: %s", err)
+	}
+	eips, err := cloud.getEipByIP(ctx, ip)
+	if err != nil || len(eips) != 1 {
+		t.Fatalf("reserved EIP %s not found, eips: %v, err: %v", ip, eips, err)
+	}
+	if eips[0].InstanceID != "" {
+		t.Errorf("reserved EIP %s not unbound: %s", ip, eips[0].InstanceID)
+	}
+	reserved, err := cloud.getReservedEIP(ctx, svc)
+	if err != nil || reserved != ip {
+		t.Errorf("getReservedEIP err, expected %s, got %s, err: %v", ip, reserved, err)
+	}
+
+	// a retry does not unbind the unbound EIP again
+	eipClient := cloud.clientSet.EIPClient
+	cloud.clientSet.EIPClient = &strictUnbindEipClient{EipFakeClient: eipClient.(*fake.EipFakeClient)}
+	if err := cloud.reserveEIP(ctx, svc, lb, ip); err != nil {
+		t.Errorf("reserveEIP retry err, err: %s", err)
+	}
+	cloud.clientSet.EIPClient = eipClient
+
+	// reserved EIP is not orphaned
+	orphans, err := cloud.ListOrphanedLoadBalancerResources(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListOrphanedLoadBalancerResources failed: %v", err)
+	}
+	for _, orphan := range orphans {
+		if orphan.ID == ip {
+			t.Errorf("reserved EIP %s is collected as orphaned", ip)
+		}
+	}
+
+	// EIP is bound again when service returns
+	returned := buildService()
+	returned.UID = "uid-2"
+	returned.Annotations = map[string]string{ServiceAnnotationLoadBalancerReserveEIP: "true"}
+	lb = &blb.LoadBalancer{BlbId: blbResp.LoadBalancerId, Name: "lb-returned"}
+	got, err := cloud.ensureEIPWithNoSpecificIP(ctx, returned, lb)
+	if err != nil {
+		t.Fatalf("ensureEIPWithNoSpecificIP err, err: %s", err)
+	}
+	if got != ip {
+		t.Errorf("reserved EIP not readopted, expected %s, got %s", ip, got)
+	}
+	reserved, err = cloud.getReservedEIP(ctx, returned)
+	if err != nil || reserved != "" {
+		t.Errorf("readopted EIP %s still reserved, err: %v", reserved, err)
+	}
+
+	// expired EIP is released, bound one is left
+	lb.PublicIp = got
+	if err := cloud.ensureEipDeleted(ctx, returned, lb); err != nil {
+		t.Fatalf("ensureEipDeleted err, err: %s", err)
+	}
+	tagClient := cloud.clientSet.EIPTagClient.(*fake.EipTagFakeClient)
+	cloud.ReservedEIPTTLInSecond = 3600
+	cloud.releaseExpiredReservedEIPs()
+	if eips, _ := cloud.getEipByIP(ctx, ip); len(eips) != 1 {
+		t.Errorf("reserved EIP %s released before TTL", ip)
+	}
+	tagClient.TagMap[ip][reservedEIPAtTagKey] = strconv.FormatInt(time.Now().Add(-2*time.Hour).Unix(), 10)
+	cloud.releaseExpiredReservedEIPs()
+	if eips, _ := cloud.getEipByIP(ctx, ip); len(eips) != 0 {
+		t.Errorf("reserved EIP %s not released after TTL", ip)
+	}
+}
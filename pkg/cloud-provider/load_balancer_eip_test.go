@@ -58,6 +58,19 @@ func TestCreateEIP(t *testing.T) {
 	if len(ip) == 0 {
 		t.Errorf("createEIP err, ip is nil")
 	}
+	// same client token returns the same EIP
+	args.ClientToken = getClientToken(cloud.ClusterID, buildService(), clientTokenKindEIP)
+	ip1, err := cloud.createEIP(ctx, args)
+	if err != nil {
+		t.Errorf("createEIP err, err: %s", err)
+	}
+	ip2, err := cloud.createEIP(ctx, args)
+	if err != nil {
+		t.Errorf("createEIP err, err: %s", err)
+	}
+	if ip1 == ip || ip1 != ip2 {
+		t.Errorf("createEIP with client token err, ip: %s, ip1: %s, ip2: %s", ip, ip1, ip2)
+	}
 }
 func TestDeleteEIP(t *testing.T) {
 	cloud, _, _, err := beforeTestBlb()
@@ -318,7 +331,10 @@ func TestEnsureEIPWithNoSpecificIP(t *testing.T) {
 	if len(eip) == 0 {
 		t.Errorf("createEIP err, ip is nil")
 	}
-	ip2, err := cloud.ensureEIPWithNoSpecificIP(ctx, svc, lb2)
+	// another service, the same service gets the EIP created in case1 by client token
+	svc2 := buildService()
+	svc2.UID = "uid-2"
+	ip2, err := cloud.ensureEIPWithNoSpecificIP(ctx, svc2, lb2)
 	if err != nil {
 		t.Errorf("ensureEIPWithNoSpecificIP err, err: %s", err)
 	}
@@ -0,0 +1,99 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+)
+
+const (
+	// internalVpcBLBDescPrefix is added before desc of BLBs provisioned for internal-vpc services,
+	// so the exposure mode is known even if the annotation is changed
+	internalVpcBLBDescPrefix = "cce_internal_vpc"
+	// autoCreateEIPBLBDescPrefix is added before desc of BLBs after an EIP is created for them
+	autoCreateEIPBLBDescPrefix = "cce_auto_create_eip"
+)
+
+// isInternalVpcService returns whether service asks to be exposed in VPC only
+func isInternalVpcService(service *v1.Service) bool {
+	return service.Annotations[ServiceAnnotationLoadBalancerInternalVpc] == "true"
+}
+
+// isInternalVpcExposure returns the exposure mode provisioned for lb. BLBs provisioned before the mode is
+// recorded and bound to no EIP follow the annotation of service.
+func isInternalVpcExposure(service *v1.Service, lb *blb.LoadBalancer) bool {
+	if lb == nil {
+		return isInternalVpcService(service)
+	}
+	if strings.HasPrefix(lb.Desc, internalVpcBLBDescPrefix) {
+		return true
+	}
+	if strings.Contains(lb.Desc, autoCreateEIPBLBDescPrefix) || len(lb.PublicIp) != 0 {
+		return false
+	}
+	return isInternalVpcService(service)
+}
+
+// ensureExposureMode moves lb to the exposure mode of service when internal-vpc is changed on a live service.
+// The EIP is released (or returned, unbound or reserved as deleting service does) when service becomes internal,
+// and the VIP is not taken as EIP any more when service becomes external, the EIP is ensured by caller then.
+func (bc *Baiducloud) ensureExposureMode(ctx context.Context, service *v1.Service, lb *blb.LoadBalancer) error {
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	internal := isInternalVpcService(service)
+	if internal == isInternalVpcExposure(service, lb) {
+		return nil
+	}
+	desc := lb.Desc
+	if internal {
+		klog.Infof(Message(ctx, fmt.Sprintf("service %s is changed to internal-vpc, release EIP of BLB %s", serviceKey, lb.BlbId)))
+		if err := bc.ensureEipDeleted(ctx, service, lb); err != nil {
+			return err
+		}
+		// the EIP ensured when service becomes external again is a new one
+		if err := bc.bumpClientTokenKind(service); err != nil {
+			return err
+		}
+		desc = internalVpcBLBDescPrefix + strings.Replace(desc, autoCreateEIPBLBDescPrefix, "", 1)
+	} else {
+		klog.Infof(Message(ctx, fmt.Sprintf("service %s is changed from internal-vpc, ensure EIP of BLB %s", serviceKey, lb.BlbId)))
+		desc = strings.TrimPrefix(desc, internalVpcBLBDescPrefix)
+		// EIP recorded before service became internal is released already
+		delete(service.Annotations, ServiceAnnotationCceAutoAddEip)
+	}
+	args := blb.UpdateLoadBalancerArgs{
+		LoadBalancerId: lb.BlbId,
+		Name:           lb.Name,
+		Desc:           desc,
+	}
+	if err := bc.clientSet.BLBClient.UpdateLoadBalancer(ctx, &args, bc.getSignOption(ctx)); err != nil {
+		return err
+	}
+	lb.Desc = desc
+	if internal {
+		lb.PublicIp = ""
+		bc.eventRecorder.Eventf(service, v1.EventTypeNormal, "ExposureChanged", "Service is exposed in VPC by %s, EIP is released", lb.Address)
+	} else {
+		bc.eventRecorder.Eventf(service, v1.EventTypeNormal, "ExposureChanged", "Service is exposed by EIP instead of VPC address %s", lb.Address)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package cloud_provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEnsureExposureMode(t *testing.T) {
+	cloud, _, blbResp, err := beforeTestBlb()
+	if err != nil {
+		t.Fatalf("beforeTestBlb err , %v", err)
+	}
+	cloud.eventRecorder = record.NewFakeRecorder(10)
+	ctx := context.Background()
+	lb := newEIPOfBLB(t, cloud, blbResp.LoadBalancerId)
+	lb.Name = "lb-exposure"
+	lb.Desc = autoCreateEIPBLBDescPrefix + blbDescPrefix + cloud.ClusterID
+	lb.Address = "192.168.0.10"
+	ip := lb.PublicIp
+	svc := buildService()
+	svc.Annotations = map[string]string{ServiceAnnotationCceAutoAddEip: ip}
+	svc.Status.LoadBalancer.Ingress = []api.LoadBalancerIngress{{IP: ip}}
+
+	// unchanged external service is left untouched
+	if err := cloud.ensureExposureMode(ctx, svc, lb); err != nil {
+		t.Fatalf("ensureExposureMode err, err: %v", err)
+	}
+	if eips, _ := cloud.getEipByIP(ctx, ip); len(eips) != 1 {
+		t.Fatalf("EIP %s of external service released", ip)
+	}
+
+	// EIP is released when service becomes internal
+	svc.Annotations[ServiceAnnotationLoadBalancerInternalVpc] = "true"
+	if err := cloud.ensureExposureMode(ctx, svc, lb); err != nil {
+		t.Fatalf("ensureExposureMode err, err: %v", err)
+	}
+	if eips, _ := cloud.getEipByIP(ctx, ip); len(eips) != 0 {
+		t.Errorf("EIP %s not released after service became internal", ip)
+	}
+	if kind := cloud.clientTokenKind(svc, clientTokenKindEIP); kind == clientTokenKindEIP {
+		t.Errorf("client token kind not changed after EIP released: %s", kind)
+	}
+	if !strings.HasPrefix(lb.Desc, internalVpcBLBDescPrefix) || strings.Contains(lb.Desc, autoCreateEIPBLBDescPrefix) {
+		t.Errorf("exposure mode not recorded in desc: %s", lb.Desc)
+	}
+	// deletion follows the mode provisioned instead of the annotation
+	svc.Annotations[ServiceAnnotationLoadBalancerInternalVpc] = "false"
+	if !isInternalVpcExposure(svc, lb) {
+		t.Errorf("exposure mode of BLB %s should be internal", lb.BlbId)
+	}
+
+	// EIP is created when service becomes external again, the stale EIP is not used
+	svc.Status.LoadBalancer.Ingress = []api.LoadBalancerIngress{{IP: lb.Address}}
+	if err := cloud.ensureExposureMode(ctx, svc, lb); err != nil {
+		t.Fatalf("ensureExposureMode err, err: %v", err)
+	}
+	if strings.HasPrefix(lb.Desc, internalVpcBLBDescPrefix) || isInternalVpcExposure(svc, lb) {
+		t.Errorf("exposure mode not recorded in desc: %s", lb.Desc)
+	}
+	if _, ok := svc.Annotations[ServiceAnnotationCceAutoAddEip]; ok {
+		t.Errorf("stale EIP %s still in annotation", ip)
+	}
+	// VIP in status is never deleted as EIP
+	if err := cloud.ensureEipDeleted(ctx, svc, lb); err != nil {
+		t.Errorf("ensureEipDeleted err, err: %v", err)
+	}
+	newIP, err := cloud.ensureEIPWithNoSpecificIP(ctx, svc, lb)
+	if err != nil {
+		t.Fatalf("ensureEIPWithNoSpecificIP err, err: %v", err)
+	}
+	if eips, _ := cloud.getEipByIP(ctx, newIP); len(eips) != 1 || eips[0].InstanceID != lb.BlbId {
+		t.Errorf("EIP %s not bound to BLB %s: %v", newIP, lb.BlbId, eips)
+	}
+}
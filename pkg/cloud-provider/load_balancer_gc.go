@@ -0,0 +1,227 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+
+	"icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/gc"
+	tempblb "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
+)
+
+const (
+	gcResourceKindBLB = "BLB"
+	gcResourceKindEIP = "EIP"
+
+	defaultLoadBalancerGCGracePeriod = time.Hour
+	loadBalancerGCPeriod             = 10 * time.Minute
+
+	// blbDescPrefix is the prefix of desc of BLBs created by the cluster,
+	// cce_auto_create_eip may be added before it after an EIP is created, cce_internal_vpc for internal-vpc services
+	blbDescPrefix = "auto generated by cce:"
+	// reservedBLBDescPrefix is the prefix of desc of BLBs kept by reserve-lb, which are not collected
+	reservedBLBDescPrefix = "reserved by cce:"
+	// name of BLB and EIP is limited to 65
+	maxLoadBalancerNameLength = 65
+)
+
+var _ gc.Cloud = &Baiducloud{}
+
+// LoadBalancerGCConfig returns the config of loadbalancer-gc controller
+func (bc *Baiducloud) LoadBalancerGCConfig() gc.Config {
+	config := gc.Config{
+		Mode:        bc.LoadBalancerGCMode,
+		GracePeriod: time.Duration(bc.LoadBalancerGCGracePeriodInSecond) * time.Second,
+		Period:      loadBalancerGCPeriod,
+	}
+	if config.Mode == "" {
+		config.Mode = gc.ModeReportOnly
+	}
+	if config.GracePeriod <= 0 {
+		config.GracePeriod = defaultLoadBalancerGCGracePeriod
+	}
+	return config
+}
+
+// ListOrphanedLoadBalancerResources returns the BLBs and EIPs created by the cluster which are used by none of services
+func (bc *Baiducloud) ListOrphanedLoadBalancerResources(ctx context.Context, services []*v1.Service) ([]gc.Resource, error) {
+	ctx = context.WithValue(ctx, RequestID, GetRandom())
+	// BLBs and EIPs are found by id in annotations, or by name if annotations are not persisted yet
+	usedIDs := sets.NewString()
+	usedNames := sets.NewString()
+	for _, service := range services {
+		for _, key := range []string{ServiceAnnotationLoadBalancerId, ServiceAnnotationCceAutoAddLoadBalancerID,
+			ServiceAnnotationLoadBalancerExistID, ServiceAnnotationCceAutoAddEip} {
+			if id := service.Annotations[key]; id != "" {
+				usedIDs.Insert(id)
+			}
+		}
+		if service.Spec.LoadBalancerIP != "" {
+			usedIDs.Insert(service.Spec.LoadBalancerIP)
+		}
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			usedIDs.Insert(ingress.IP)
+		}
+		name := getBlbName(bc.ClusterID, service)
+		usedNames.Insert(name, truncateLoadBalancerName(name))
+	}
+
+	var result []gc.Resource
+	lbs, err := bc.clientSet.BLBClient.DescribeLoadBalancers(ctx, &blb.DescribeLoadBalancersArgs{}, bc.getSignOption(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list BLBs failed: %v", err)
+	}
+	orphanedBLBs := sets.NewString()
+	for _, lb := range lbs {
+		if !bc.LoadBalancerGCCollectBLBs || !strings.HasSuffix(lb.Desc, blbDescPrefix+bc.ClusterID) {
+			continue
+		}
+		if usedIDs.Has(lb.BlbId) || usedNames.Has(lb.Name) {
+			continue
+		}
+		orphanedBLBs.Insert(lb.BlbId)
+		result = append(result, bc.newGCResource(gcResourceKindBLB, lb.BlbId, lb.Name))
+	}
+
+	eips, err := bc.clientSet.EIPClient.GetEIPs(ctx, &eip.GetEIPsArgs{}, bc.getSignOption(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list EIPs failed: %v", err)
+	}
+	reservedEIPs, err := bc.reservedEIPSet(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list reserved EIPs failed: %v", err)
+	}
+	for _, e := range eips {
+		// EIPs are named after BLBs by default, EIPs with names from annotation are not known
+		if !strings.HasPrefix(e.Name, getBlbNamePrefix(bc.ClusterID)) {
+			continue
+		}
+		if usedIDs.Has(e.EIP) || usedNames.Has(e.Name) || reservedEIPs.Has(e.EIP) {
+			continue
+		}
+		// an EIP bound to other instances is still in use
+		if e.InstanceID != "" && !orphanedBLBs.Has(e.InstanceID) {
+			continue
+		}
+		result = append(result, bc.newGCResource(gcResourceKindEIP, e.EIP, e.Name))
+	}
+	return result, nil
+}
+
+// DeleteLoadBalancerResource deletes the orphaned BLB or EIP
+func (bc *Baiducloud) DeleteLoadBalancerResource(ctx context.Context, resource gc.Resource) error {
+	ctx = context.WithValue(ctx, RequestID, GetRandom())
+	klog.Infof(Message(ctx, fmt.Sprintf("delete orphaned %v", resource)))
+	switch resource.Kind {
+	case gcResourceKindBLB:
+		lb := &blb.LoadBalancer{BlbId: resource.ID}
+		err := bc.ensureBLBSecurityGroupsDeleted(ctx, lb)
+		if err != nil {
+			return err
+		}
+		return bc.clientSet.BLBClient.DeleteLoadBalancer(ctx, &blb.DeleteLoadBalancerArgs{LoadBalancerId: resource.ID}, bc.getSignOption(ctx))
+	case gcResourceKindEIP:
+		eips, err := bc.getEipByIP(ctx, resource.ID)
+		if err != nil {
+			return err
+		}
+		if len(eips) == 0 {
+			return nil
+		}
+		if eips[0].InstanceID != "" {
+			err = bc.clientSet.EIPClient.UnbindEIP(ctx, resource.ID, bc.getSignOption(ctx))
+			if err != nil {
+				return err
+			}
+		}
+		return bc.deleteEIP(ctx, resource.ID)
+	}
+	return fmt.Errorf("unknown resource kind %s", resource.Kind)
+}
+
+// ensureBLBSecurityGroupsDeleted unbinds and deletes the security groups created by reconcileSourceRanges from lb,
+// they are found by name since the service of lb is gone
+func (bc *Baiducloud) ensureBLBSecurityGroupsDeleted(ctx context.Context, lb *blb.LoadBalancer) error {
+	args := tempblb.DescribeSecurityGroupsArgs{
+		LoadBalancerId: lb.BlbId,
+	}
+	bound, err := bc.clientSet.BLBSecurityGroupClient.DescribeSecurityGroups(ctx, &args, bc.getSignOption(ctx))
+	if err != nil {
+		return err
+	}
+	for _, b := range bound {
+		sg, err := bc.clientSet.VPCSecurityGroupClient.GetSecurityGroup(ctx, b.SecurityGroupId, bc.getSignOption(ctx))
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(sg.Name, getBlbNamePrefix(bc.ClusterID)) || !strings.HasSuffix(sg.Name, "/source-ranges") {
+			continue
+		}
+		klog.Infof(Message(ctx, fmt.Sprintf("delete security group %s of orphaned BLB %s", sg.ID, lb.BlbId)))
+		err = bc.deleteServiceSecurityGroup(ctx, lb, sg)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newGCResource returns a gc.Resource, the service is parsed from name generated by getBlbName
+func (bc *Baiducloud) newGCResource(kind, id, name string) gc.Resource {
+	resource := gc.Resource{
+		Kind: kind,
+		ID:   id,
+		Name: name,
+	}
+	// names truncated or from annotation can not be parsed
+	parts := strings.Split(strings.TrimPrefix(name, getBlbNamePrefix(bc.ClusterID)), "/")
+	if strings.HasPrefix(name, getBlbNamePrefix(bc.ClusterID)) && len(parts) == 2 && len(name) < maxLoadBalancerNameLength {
+		resource.ServiceNamespace = parts[0]
+		resource.ServiceName = parts[1]
+	}
+	return resource
+}
+
+// markBLBReserved changes desc of BLB kept by reserve-lb, so it is not collected as orphaned
+func (bc *Baiducloud) markBLBReserved(ctx context.Context, lb *blb.LoadBalancer) error {
+	args := blb.UpdateLoadBalancerArgs{
+		LoadBalancerId: lb.BlbId,
+		Name:           lb.Name,
+		Desc:           reservedBLBDescPrefix + bc.ClusterID,
+	}
+	return bc.clientSet.BLBClient.UpdateLoadBalancer(ctx, &args, bc.getSignOption(ctx))
+}
+
+func getBlbNamePrefix(clusterID string) string {
+	return fmt.Sprintf("CCE/SVC/%s/", clusterID)
+}
+
+func truncateLoadBalancerName(name string) string {
+	if len(name) > maxLoadBalancerNameLength {
+		return name[:maxLoadBalancerNameLength]
+	}
+	return name
+}
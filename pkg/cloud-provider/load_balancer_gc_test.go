@@ -0,0 +1,132 @@
+package cloud_provider
+
+import (
+	"context"
+	"testing"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+	api "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/gc"
+	tempblb "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
+	tempvpc "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-vpc"
+)
+
+func TestListOrphanedLoadBalancerResources(t *testing.T) {
+	ctx := context.Background()
+	cloud := NewFakeCloud("c-gc")
+	live := buildService()
+	live.Name = "live"
+	gone := buildService()
+	gone.Name = "gone"
+	reserved := buildService()
+	reserved.Name = "reserved"
+	reserved.Annotations = map[string]string{ServiceAnnotationLoadBalancerReserveLB: "true"}
+
+	createBLB := func(name, desc string) string {
+		resp, err := cloud.clientSet.BLBClient.CreateLoadBalancer(ctx, &blb.CreateLoadBalancerArgs{Name: name, Desc: desc}, nil)
+		if err != nil {
+			t.Fatalf("CreateLoadBalancer failed: %v", err)
+		}
+		return resp.LoadBalancerId
+	}
+	createEIP := func(name, blbID string) string {
+		ip, err := cloud.clientSet.EIPClient.CreateEIP(ctx, &eip.CreateEIPArgs{Name: name}, nil)
+		if err != nil {
+			t.Fatalf("CreateEIP failed: %v", err)
+		}
+		if blbID != "" {
+			err = cloud.clientSet.EIPClient.BindEIP(ctx, ip, &eip.BindEIPArgs{IP: ip, InstanceID: blbID, InstanceType: eip.BLB}, nil)
+			if err != nil {
+				t.Fatalf("BindEIP failed: %v", err)
+			}
+		}
+		return ip
+	}
+	liveBLB := createBLB(getBlbName(cloud.ClusterID, live), blbDescPrefix+cloud.ClusterID)
+	liveEIP := createEIP(getBlbName(cloud.ClusterID, live), liveBLB)
+	goneBLB := createBLB(getBlbName(cloud.ClusterID, gone), "cce_auto_create_eip"+blbDescPrefix+cloud.ClusterID)
+	goneEIP := createEIP(getBlbName(cloud.ClusterID, gone), goneBLB)
+	// BLB of another cluster
+	createBLB("CCE/SVC/c-other/default/gone", blbDescPrefix+"c-other")
+	// EIP bound to an instance not created by the cluster
+	createEIP(getBlbName(cloud.ClusterID, gone)+"-bcc", "i-xxx")
+
+	// BLB kept by reserve-lb
+	reservedBLB := createBLB(getBlbName(cloud.ClusterID, reserved), blbDescPrefix+cloud.ClusterID)
+	err := cloud.EnsureLoadBalancerDeleted(ctx, "", &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:        reserved.Name,
+			Namespace:   reserved.Namespace,
+			Annotations: map[string]string{ServiceAnnotationLoadBalancerReserveLB: "true", ServiceAnnotationLoadBalancerInternalVpc: "true"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("EnsureLoadBalancerDeleted failed: %v", err)
+	}
+
+	// security group of source ranges bound to the orphaned BLB
+	sgID, err := cloud.clientSet.VPCSecurityGroupClient.CreateSecurityGroup(ctx, &tempvpc.CreateSecurityGroupArgs{Name: getSecurityGroupName(cloud.ClusterID, gone)}, nil)
+	if err != nil {
+		t.Fatalf("CreateSecurityGroup failed: %v", err)
+	}
+	err = cloud.clientSet.BLBSecurityGroupClient.BindSecurityGroups(ctx, &tempblb.SecurityGroupsArgs{LoadBalancerId: goneBLB, SecurityGroupIds: []string{sgID}}, nil)
+	if err != nil {
+		t.Fatalf("BindSecurityGroups failed: %v", err)
+	}
+
+	// BLBs are not collected by default, nor EIPs bound to them
+	orphans, err := cloud.ListOrphanedLoadBalancerResources(ctx, []*api.Service{live})
+	if err != nil {
+		t.Fatalf("ListOrphanedLoadBalancerResources failed: %v", err)
+	}
+	if len(orphans) != 0 {
+		t.Fatalf("expected no orphans, got %v", orphans)
+	}
+
+	cloud.LoadBalancerGCCollectBLBs = true
+	orphans, err = cloud.ListOrphanedLoadBalancerResources(ctx, []*api.Service{live})
+	if err != nil {
+		t.Fatalf("ListOrphanedLoadBalancerResources failed: %v", err)
+	}
+	expected := map[string]gc.Resource{
+		goneBLB: {Kind: gcResourceKindBLB, ID: goneBLB, Name: getBlbName(cloud.ClusterID, gone), ServiceNamespace: "default", ServiceName: "gone"},
+		goneEIP: {Kind: gcResourceKindEIP, ID: goneEIP, Name: getBlbName(cloud.ClusterID, gone), ServiceNamespace: "default", ServiceName: "gone"},
+	}
+	if len(orphans) != len(expected) {
+		t.Fatalf("expected orphans %v, got %v", expected, orphans)
+	}
+	for _, orphan := range orphans {
+		if expected[orphan.ID] != orphan {
+			t.Errorf("expected orphan %+v, got %+v", expected[orphan.ID], orphan)
+		}
+	}
+
+	for _, orphan := range orphans {
+		err = cloud.DeleteLoadBalancerResource(ctx, orphan)
+		if err != nil {
+			t.Errorf("DeleteLoadBalancerResource %v failed: %v", orphan, err)
+		}
+	}
+	for _, id := range []string{goneBLB} {
+		if _, exist, _ := cloud.getBLBByID(ctx, id); exist {
+			t.Errorf("BLB %s should be deleted", id)
+		}
+	}
+	for _, id := range []string{liveBLB, reservedBLB} {
+		if _, exist, _ := cloud.getBLBByID(ctx, id); !exist {
+			t.Errorf("BLB %s should not be deleted", id)
+		}
+	}
+	if _, err := cloud.clientSet.VPCSecurityGroupClient.GetSecurityGroup(ctx, sgID, nil); err == nil {
+		t.Errorf("security group %s of BLB %s should be deleted", sgID, goneBLB)
+	}
+	if eips, _ := cloud.getEipByIP(ctx, goneEIP); len(eips) != 0 {
+		t.Errorf("EIP %s should be deleted", goneEIP)
+	}
+	if eips, _ := cloud.getEipByIP(ctx, liveEIP); len(eips) != 1 {
+		t.Errorf("EIP %s should not be deleted", liveEIP)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+)
+
+const (
+	// RollbackPolicyRetain keeps resources created by a failed first provisioning, they are reused by the next retry
+	RollbackPolicyRetain = "retain"
+	// RollbackPolicyDelete deletes resources created by a failed first provisioning
+	RollbackPolicyDelete = "delete"
+)
+
+// validationError is an invalid setting of service found by CCM, which fails again on retry
+type validationError struct {
+	err error
+}
+
+func (e *validationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *validationError) Unwrap() error {
+	return e.err
+}
+
+// newValidationError marks err as an invalid setting of service, nil is kept
+func newValidationError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &validationError{err: err}
+}
+
+// shouldRollback returns whether resources of service should be deleted after EnsureLoadBalancer failed with err.
+// Only errors which fail again on retry are rolled back: invalid settings found by CCM and 4xx errors of BCE
+// other than throttling, any other error is retried with the resources kept.
+func shouldRollback(service *v1.Service, err error) bool {
+	if service.Annotations[ServiceAnnotationLoadBalancerRollbackPolicy] != RollbackPolicyDelete {
+		return false
+	}
+	// the load balancer has been provisioned before
+	if len(service.Status.LoadBalancer.Ingress) != 0 {
+		return false
+	}
+	var vErr *validationError
+	if errors.As(err, &vErr) {
+		return true
+	}
+	var bceErr *bce.Error
+	if errors.As(err, &bceErr) {
+		return isClientError(bceErr)
+	}
+	return false
+}
+
+// isClientError returns whether BCE rejects the request itself, throttled requests and 5xx errors succeed on retry
+func isClientError(err *bce.Error) bool {
+	if err.StatusCode < http.StatusBadRequest || err.StatusCode >= http.StatusInternalServerError {
+		return false
+	}
+	if err.StatusCode == http.StatusTooManyRequests || strings.Contains(err.Code, "RateLimit") {
+		return false
+	}
+	return true
+}
+
+// rollbackLoadBalancer deletes the BLB and EIP created for service by the failed first provisioning,
+// BLBs not created by the cluster are left untouched
+func (bc *Baiducloud) rollbackLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, cause error) {
+	serviceKey := fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+	lb, exist, err := bc.getServiceAssociatedBLB(ctx, clusterName, service)
+	if err != nil || !exist {
+		return
+	}
+	if !strings.HasSuffix(lb.Desc, blbDescPrefix+bc.ClusterID) {
+		klog.Infof(Message(ctx, fmt.Sprintf("BLB %s of service %s is not created by cluster, skip rollback", lb.BlbId, serviceKey)))
+		return
+	}
+
+	klog.Infof(Message(ctx, fmt.Sprintf("rollback BLB %s of service %s: %v", lb.BlbId, serviceKey, cause)))
+	bc.eventRecorder.Eventf(service, v1.EventTypeWarning, "RollbackLoadBalancer",
+		"Deleting BLB %s and its EIP since the first provisioning failed: %v", lb.BlbId, cause)
+	err = bc.EnsureLoadBalancerDeleted(ctx, clusterName, service)
+	if err != nil {
+		klog.Errorf(Message(ctx, fmt.Sprintf("rollback BLB %s of service %s failed: %v", lb.BlbId, serviceKey, err)))
+		bc.eventRecorder.Eventf(service, v1.EventTypeWarning, "RollbackLoadBalancerFailed",
+			"Error deleting BLB %s: %v", lb.BlbId, err)
+		return
+	}
+	if err := bc.bumpClientTokenKind(service); err != nil {
+		klog.Errorf(Message(ctx, fmt.Sprintf("change client tokens of service %s failed: %v", serviceKey, err)))
+	}
+}
+
+// bumpClientTokenKind changes client tokens of service after its resources are deleted, since resources deleted
+// may still be returned for the same client token. The generation is kept in annotation of service across restarts.
+func (bc *Baiducloud) bumpClientTokenKind(service *v1.Service) error {
+	n, _ := strconv.Atoi(service.Annotations[ServiceAnnotationCceClientTokenGeneration])
+	return bc.updateServiceAnnotation(service, ServiceAnnotationCceClientTokenGeneration, strconv.Itoa(n+1))
+}
+
+// clientTokenKind returns the kind used by client token of creating a kind of resource for service,
+// which changes after each deletion of resources of service
+func (bc *Baiducloud) clientTokenKind(service *v1.Service, kind string) string {
+	if generation, ok := service.Annotations[ServiceAnnotationCceClientTokenGeneration]; ok {
+		return fmt.Sprintf("%s/%s", kind, generation)
+	}
+	return kind
+}
@@ -2,11 +2,20 @@ package cloud_provider
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"strings"
 	"testing"
 
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
 	api "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	tempblb "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
 )
 
 func TestGetLoadBalancer(t *testing.T) {
@@ -91,3 +100,175 @@ func TestEnsureLoadBalancer(t *testing.T) {
 	clusterName = "test"
 	svc.Spec.Ports[0].Protocol = "TCP"
 }
+
+func TestEnsureLoadBalancerRollback(t *testing.T) {
+	cloud, _, err := newCluster()
+	if err != nil {
+		t.Fatalf("newCluster err, err: %v", err)
+	}
+	recorder := record.NewFakeRecorder(10)
+	cloud.eventRecorder = recorder
+	ctx := context.Background()
+	svc := &api.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+			UID:       "uid-1",
+			Annotations: map[string]string{
+				ServiceAnnotationLoadBalancerInternalVpc: "true",
+			},
+		},
+		Spec: api.ServiceSpec{
+			Type: api.ServiceTypeLoadBalancer,
+			Ports: []api.ServicePort{
+				{Name: "http", Protocol: "TCP", Port: 80, NodePort: 30080},
+			},
+		},
+	}
+	blbName := getBlbName(cloud.ClusterID, svc)
+	// BLB created by the first provisioning
+	createBLB := func() string {
+		resp, err := cloud.clientSet.BLBClient.CreateLoadBalancer(ctx, &blb.CreateLoadBalancerArgs{Name: blbName, Desc: blbDescPrefix + cloud.ClusterID}, nil)
+		if err != nil {
+			t.Fatalf("CreateLoadBalancer err, err: %v", err)
+		}
+		return resp.LoadBalancerId
+	}
+
+	// invalid annotation is rejected before any cloud resource is changed
+	svc.Annotations[ServiceAnnotationLoadBalancerInternalVpc] = "false"
+	svc.Annotations[ServiceAnnotationElasticIPBillingMethod] = "ByWhatever"
+	_, err = cloud.EnsureLoadBalancer(ctx, "", svc, nil)
+	if err == nil || !strings.Contains(err.Error(), "ByWhatever") {
+		t.Fatalf("EnsureLoadBalancer should fail with invalid EIP annotation, err: %v", err)
+	}
+	delete(svc.Annotations, ServiceAnnotationElasticIPBillingMethod)
+	svc.Annotations[ServiceAnnotationLoadBalancerInternalVpc] = "true"
+
+	// retain by default, BLB rejects the listener
+	cloud.clientSet.BLBListenerClient = &rejectingListenerClient{ListenerInterface: cloud.clientSet.BLBListenerClient}
+	lbID := createBLB()
+	_, err = cloud.EnsureLoadBalancer(ctx, "", svc, nil)
+	if err == nil {
+		t.Fatalf("EnsureLoadBalancer should fail with rejected listener")
+	}
+	if _, exist, _ := cloud.getBLBByID(ctx, lbID); !exist {
+		t.Fatalf("BLB %s should be retained", lbID)
+	}
+
+	// delete resources of the failed first provisioning
+	svc.Annotations[ServiceAnnotationLoadBalancerRollbackPolicy] = RollbackPolicyDelete
+	cloud.kubeClient = fake.NewSimpleClientset(svc.DeepCopy())
+	_, err = cloud.EnsureLoadBalancer(ctx, "", svc, nil)
+	if err == nil {
+		t.Fatalf("EnsureLoadBalancer should fail with rejected listener")
+	}
+	if _, exist, _ := cloud.getBLBByID(ctx, lbID); exist {
+		t.Errorf("BLB %s should be deleted by rollback", lbID)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "RollbackLoadBalancer") {
+			t.Errorf("expected RollbackLoadBalancer event, got %s", event)
+		}
+	default:
+		t.Errorf("expected RollbackLoadBalancer event")
+	}
+	// the retry creates BLB with a new client token
+	if cloud.clientTokenKind(svc, clientTokenKindBLB) == clientTokenKindBLB {
+		t.Errorf("client token should change after rollback")
+	}
+	// the generation survives restarts of CCM
+	got, err := cloud.kubeClient.CoreV1().Services(svc.Namespace).Get(svc.Name, meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get service err: %v", err)
+	}
+	if got.Annotations[ServiceAnnotationCceClientTokenGeneration] != "1" {
+		t.Errorf("client token generation not persisted: %v", got.Annotations)
+	}
+
+	// provisioned before, not rolled back
+	svc.Status.LoadBalancer.Ingress = []api.LoadBalancerIngress{{IP: "1.1.1.1"}}
+	lbID = createBLB()
+	_, err = cloud.EnsureLoadBalancer(ctx, "", svc, nil)
+	if err == nil {
+		t.Fatalf("EnsureLoadBalancer should fail with rejected listener")
+	}
+	if _, exist, _ := cloud.getBLBByID(ctx, lbID); !exist {
+		t.Errorf("BLB %s provisioned before should not be deleted", lbID)
+	}
+}
+
+// rejectingListenerClient fails creating TCP listeners with a 4xx error of BCE
+type rejectingListenerClient struct {
+	tempblb.ListenerInterface
+}
+
+func (c *rejectingListenerClient) CreateTCPListener(ctx context.Context, args *tempblb.CreateTCPListenerArgs, option *bce.SignOption) error {
+	return &bce.Error{StatusCode: 400, Code: "BadRequest", Message: "invalid listener"}
+}
+
+func TestShouldRollback(t *testing.T) {
+	svc := buildService()
+	svc.Annotations = map[string]string{ServiceAnnotationLoadBalancerRollbackPolicy: RollbackPolicyDelete}
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"invalid settings", newValidationError(fmt.Errorf("invalid annotation")), true},
+		{"wrapped invalid settings", fmt.Errorf("ensure EIP: %w", newValidationError(fmt.Errorf("invalid annotation"))), true},
+		{"bad request", &bce.Error{StatusCode: 400, Code: "BadRequest"}, true},
+		{"wrapped bad request", fmt.Errorf("create BLB: %w", &bce.Error{StatusCode: 403}), true},
+		{"throttled", &bce.Error{StatusCode: 429}, false},
+		{"rate limit code", &bce.Error{StatusCode: 400, Code: "RequestRateLimitExceeded"}, false},
+		{"server error", &bce.Error{StatusCode: 500}, false},
+		{"unavailable", &bce.Error{StatusCode: 503}, false},
+		{"network", &net.OpError{Op: "dial", Err: fmt.Errorf("connection refused")}, false},
+		{"canceled", context.Canceled, false},
+		{"wait timeout", &WaitTimeoutError{Resource: "BLB", ID: "lb-1", Expected: "available", Actual: "creating"}, false},
+		{"unknown", fmt.Errorf("no nodes available"), false},
+	}
+	for _, c := range cases {
+		if got := shouldRollback(svc, c.err); got != c.want {
+			t.Errorf("%s: shouldRollback expected %v, got %v", c.name, c.want, got)
+		}
+	}
+}
+
+func TestGetLoadBalancerStatus(t *testing.T) {
+	lb := &blb.LoadBalancer{BlbId: "lb-1", Address: "10.0.3.4", PublicIp: "100.1.1.1"}
+	svc := buildService()
+	svc.Annotations = map[string]string{}
+	status := getLoadBalancerStatus(svc, lb, lb.PublicIp)
+	if len(status.Ingress) != 1 || status.Ingress[0].IP != lb.PublicIp {
+		t.Errorf("status expected only EIP, got %v", status.Ingress)
+	}
+
+	// VPC address is published after EIP
+	svc.Annotations[ServiceAnnotationLoadBalancerPublishVpcAddress] = "true"
+	status = getLoadBalancerStatus(svc, lb, lb.PublicIp)
+	if len(status.Ingress) != 2 || status.Ingress[0].IP != lb.PublicIp || status.Ingress[1].IP != lb.Address {
+		t.Errorf("status expected EIP and VPC address, got %v", status.Ingress)
+	}
+	// VPC address of internal-vpc service is not published twice
+	status = getLoadBalancerStatus(svc, lb, lb.Address)
+	if len(status.Ingress) != 1 || status.Ingress[0].IP != lb.Address {
+		t.Errorf("status expected only VPC address, got %v", status.Ingress)
+	}
+
+	// VPC address is kept when EIP in status is replaced
+	cloud := NewFakeCloud("c-status")
+	svc.Status.LoadBalancer = *getLoadBalancerStatus(svc, lb, lb.PublicIp)
+	cloud.kubeClient = fake.NewSimpleClientset(svc)
+	if err := cloud.updateServiceIngress(svc, "100.2.2.2"); err != nil {
+		t.Fatalf("updateServiceIngress err, err: %v", err)
+	}
+	got, err := cloud.kubeClient.CoreV1().Services(svc.Namespace).Get(svc.Name, meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get service err: %v", err)
+	}
+	if ingress := got.Status.LoadBalancer.Ingress; len(ingress) != 2 || ingress[0].IP != "100.2.2.2" || ingress[1].IP != lb.Address {
+		t.Errorf("ingress expected new EIP and VPC address, got %v", ingress)
+	}
+}
@@ -18,10 +18,14 @@ package cloud_provider
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"net"
 
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog"
 )
 
@@ -46,22 +50,81 @@ func (bc *Baiducloud) workAround(ctx context.Context, clusterName string, servic
 
 func (bc *Baiducloud) validateService(service *v1.Service) error {
 	if len(service.Spec.Ports) == 0 {
-		return fmt.Errorf("requested load balancer with no ports")
+		return newValidationError(fmt.Errorf("requested load balancer with no ports"))
+	}
+	anno, err := bc.extractServiceAnnotation(service)
+	if err != nil {
+		return newValidationError(err)
+	}
+	return newValidationError(bc.validateServiceAnnotation(service, anno))
+}
+
+// validateServiceAnnotation validates service with anno extracted from it, nothing is created
+func (bc *Baiducloud) validateServiceAnnotation(service *v1.Service, anno *ServiceAnnotation) error {
+	if _, err := getSourceRanges(service); err != nil {
+		return err
 	}
 	for _, port := range service.Spec.Ports {
+		scheduler := anno.listenerConfig(int(port.Port)).Scheduler
+		if service.Spec.SessionAffinity == v1.ServiceAffinityClientIP && scheduler != "" && scheduler != "Hash" {
+			return fmt.Errorf("ClientIP session affinity requires scheduler Hash, got scheduler %s of port %d", scheduler, port.Port)
+		}
 		switch port.Protocol {
 		case "TCP":
-			continue
 		case "UDP":
-			continue
-		case "HTTP":
-			return fmt.Errorf("HTTP is not supported")
-		case "HTTPS":
-			return fmt.Errorf("HTTPS is not supported")
+			if scheduler == "LeastConnection" {
+				return fmt.Errorf("scheduler LeastConnection is not supported by UDP port %d", port.Port)
+			}
+		case "HTTP", "HTTPS":
+			return fmt.Errorf("%s is not supported as port protocol, use annotation %s instead", port.Protocol, ServiceAnnotationLoadBalancerListenerProtocol)
 		default:
 			return fmt.Errorf("target protocol is not supported: %v", port.Protocol)
 		}
 	}
+	// listener protocol of a port must fit one of the service ports with that number,
+	// e.g. 53:HTTP applies to 53/TCP and leaves 53/UDP unchanged
+	for listenerPort, protocol := range anno.LoadBalancerListenerProtocol {
+		fit := false
+		var portProtocols []v1.Protocol
+		for _, port := range service.Spec.Ports {
+			if int(port.Port) != listenerPort {
+				continue
+			}
+			portProtocols = append(portProtocols, port.Protocol)
+			if listenerProtocolFitPort(protocol, port.Protocol) {
+				fit = true
+			}
+		}
+		if len(portProtocols) == 0 {
+			continue
+		}
+		if !fit {
+			return fmt.Errorf("listener protocol %s of port %d conflicts with port protocol %v", protocol, listenerPort, portProtocols)
+		}
+		if protocol == "HTTPS" && anno.listenerConfig(listenerPort).CertID == "" {
+			return fmt.Errorf("listener protocol HTTPS of port %d requires annotation %s or certID in LoadBalancerConfig", listenerPort, ServiceAnnotationLoadBalancerCertID)
+		}
+	}
+	if anno.ElasticIPPool != "" && service.Spec.LoadBalancerIP != "" {
+		return fmt.Errorf("annotation %s conflicts with loadBalancerIP %s", ServiceAnnotationElasticIPPool, service.Spec.LoadBalancerIP)
+	}
+	// loadBalancerIP of internal-vpc service is the VPC address of BLB
+	if vip := getInternalVIP(service); len(vip) != 0 {
+		if ip := net.ParseIP(vip); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("VPC address %s of internal-vpc service must be an IPv4 address", vip)
+		}
+	}
+	// EIP is created by annotations only if it is not internal, loadBalancerIP and EIP pool are not set
+	if anno.LoadBalancerInternalVpc != "true" && service.Spec.LoadBalancerIP == "" && anno.ElasticIPPool == "" {
+		if _, err := bc.getEipArgsFromAnnotation(anno); err != nil {
+			return err
+		}
+		if len(anno.ElasticIPGroup) != 0 {
+			if _, err := bc.getEIPGroupArgsFromAnnotation(anno); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -122,3 +185,53 @@ func getBlbName(clusterID string, service *v1.Service) string {
 	}
 	return blbName
 }
+
+const (
+	clientTokenKindBLB           = "blb"
+	clientTokenKindEIP           = "eip"
+	clientTokenKindSecurityGroup = "securitygroup"
+)
+
+// getClientToken returns the client token for creating a kind of resource for service.
+// It is derived from cluster id, service uid and kind, so retries after a lost response
+// return the resource created before instead of creating another one.
+func getClientToken(clusterID string, service *v1.Service, kind string) string {
+	// client token is limited to 64 characters
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%s", clusterID, service.UID, kind))))
+}
+
+// updateServiceAnnotation sets annotation key of service to value, both in service and in apiserver
+func (bc *Baiducloud) updateServiceAnnotation(service *v1.Service, key, value string) error {
+	if service.Annotations == nil {
+		service.Annotations = make(map[string]string, 0)
+	}
+	service.Annotations[key] = value
+	if bc.kubeClient == nil {
+		return nil
+	}
+	j, err := json.Marshal(map[string]string{key: value})
+	if err != nil {
+		return err
+	}
+	data := []byte(fmt.Sprintf(`{"metadata":{"annotations":%s}}`, j))
+	_, err = bc.kubeClient.CoreV1().Services(service.Namespace).Patch(service.Name, types.MergePatchType, data)
+	return err
+}
+
+// removeServiceAnnotation removes annotation key of service, both from service and from apiserver
+func (bc *Baiducloud) removeServiceAnnotation(service *v1.Service, key string) error {
+	if _, ok := service.Annotations[key]; !ok {
+		return nil
+	}
+	delete(service.Annotations, key)
+	if bc.kubeClient == nil {
+		return nil
+	}
+	j, err := json.Marshal(map[string]interface{}{key: nil})
+	if err != nil {
+		return err
+	}
+	data := []byte(fmt.Sprintf(`{"metadata":{"annotations":%s}}`, j))
+	_, err = bc.kubeClient.CoreV1().Services(service.Namespace).Patch(service.Name, types.MergePatchType, data)
+	return err
+}
@@ -49,6 +49,41 @@ func beforeTestBlb() (*Baiducloud, *cce.ListClusterNodesResponse, *blb.CreateLoa
 	return cloud, nodesRes, respBlb, nil
 }
 
+func TestCreateBLBWithClientToken(t *testing.T) {
+	cloud, _, _, err := beforeTestBlb()
+	if err != nil {
+		t.Fatalf("beforeTestBlb err , %v", err)
+	}
+	ctx := context.Background()
+	svc := buildService()
+	svc.UID = "uid-1"
+	id1, err := cloud.createBLB(ctx, svc)
+	if err != nil {
+		t.Fatalf("createBLB err, err: %v", err)
+	}
+	// the retry after a lost response returns the same BLB
+	id2, err := cloud.createBLB(ctx, svc)
+	if err != nil {
+		t.Fatalf("createBLB err, err: %v", err)
+	}
+	if id1 != id2 {
+		t.Errorf("createBLB with the same client token err, got %s and %s", id1, id2)
+	}
+	// another service gets another BLB
+	svc2 := buildService()
+	svc2.UID = "uid-2"
+	id3, err := cloud.createBLB(ctx, svc2)
+	if err != nil {
+		t.Fatalf("createBLB err, err: %v", err)
+	}
+	if id3 == id1 {
+		t.Errorf("createBLB for another service err, got the same BLB %s", id3)
+	}
+	if getClientToken("c-1", svc, clientTokenKindBLB) == getClientToken("c-1", svc, clientTokenKindEIP) {
+		t.Errorf("getClientToken err, BLB and EIP share the same token")
+	}
+}
+
 func TestGetBCELoadBalancer(t *testing.T) {
 	cloud, _, _, err := beforeTestBlb()
 	if err != nil {
@@ -210,4 +245,92 @@ func TestValidateService(t *testing.T) {
 	if err == nil {
 		t.Errorf("validateService err, there should be err, err is nil")
 	}
+	case1.Spec.LoadBalancerSourceRanges = []string{"10.0.0.0/8", "192.168.0.1/32"}
+	err = cloud.validateService(case1)
+	if err != nil {
+		t.Errorf("validateService err, err: %v", err)
+	}
+	case1.Spec.LoadBalancerSourceRanges = []string{"10.0.0.0/33"}
+	err = cloud.validateService(case1)
+	if err == nil {
+		t.Errorf("validateService err, there should be err, err is nil")
+	}
+	case1.Spec.LoadBalancerSourceRanges = nil
+	case1.Spec.SessionAffinity = api.ServiceAffinityClientIP
+	case1.Annotations = map[string]string{ServiceAnnotationLoadBalancerScheduler: "Hash"}
+	err = cloud.validateService(case1)
+	if err != nil {
+		t.Errorf("validateService err, err: %v", err)
+	}
+	case1.Annotations[ServiceAnnotationLoadBalancerScheduler] = "RoundRobin"
+	err = cloud.validateService(case1)
+	if err == nil {
+		t.Errorf("validateService err, there should be err, err is nil")
+	}
+}
+
+func TestValidateServiceListenerProtocol(t *testing.T) {
+	cloud, _, _, err := beforeTestBlb()
+	if err != nil {
+		t.Errorf("beforeTestBlb err , %v", err)
+	}
+	buildSvc := func(protocol api.Protocol, anno map[string]string) *api.Service {
+		return &api.Service{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Name:        "foo",
+				Namespace:   api.NamespaceDefault,
+				Annotations: anno,
+			},
+			Spec: api.ServiceSpec{
+				Ports: []api.ServicePort{
+					{
+						Name:     "test",
+						Port:     443,
+						Protocol: protocol,
+					},
+				},
+			},
+		}
+	}
+	// right case
+	svc := buildSvc("TCP", map[string]string{
+		ServiceAnnotationLoadBalancerListenerProtocol: "443:HTTPS",
+		ServiceAnnotationLoadBalancerCertID:           "cert-test",
+	})
+	if err := cloud.validateService(svc); err != nil {
+		t.Errorf("validateService err, err: %v", err)
+	}
+	// HTTPS without cert id
+	svc = buildSvc("TCP", map[string]string{
+		ServiceAnnotationLoadBalancerListenerProtocol: "443:HTTPS",
+	})
+	if err := cloud.validateService(svc); err == nil {
+		t.Errorf("validateService err, there should be err, err is nil")
+	}
+	// HTTP on UDP port
+	svc = buildSvc("UDP", map[string]string{
+		ServiceAnnotationLoadBalancerListenerProtocol: "443:HTTP",
+	})
+	if err := cloud.validateService(svc); err == nil {
+		t.Errorf("validateService err, there should be err, err is nil")
+	}
+	// HTTP on same port of TCP and UDP, applies to TCP only
+	svc = buildSvc("TCP", map[string]string{
+		ServiceAnnotationLoadBalancerListenerProtocol: "443:HTTP",
+	})
+	svc.Spec.Ports = append(svc.Spec.Ports, api.ServicePort{
+		Name:     "test-udp",
+		Port:     443,
+		Protocol: "UDP",
+	})
+	if err := cloud.validateService(svc); err != nil {
+		t.Errorf("validateService err, err: %v", err)
+	}
+	// bad syntax
+	svc = buildSvc("TCP", map[string]string{
+		ServiceAnnotationLoadBalancerListenerProtocol: "443-HTTP",
+	})
+	if err := cloud.validateService(svc); err == nil {
+		t.Errorf("validateService err, there should be err, err is nil")
+	}
 }
@@ -20,11 +20,14 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"net"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/klog"
 
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/vpc"
+
+	tempblb "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
 )
 
 func (bc *Baiducloud) getVpcInfoForBLB(ctx context.Context, service *v1.Service) (string, string, error) {
@@ -59,6 +62,19 @@ func (bc *Baiducloud) getVpcInfoForBLB(ctx context.Context, service *v1.Service)
 	if len(ins) == 0 {
 		return "", "", fmt.Errorf("getVpcInfoForBLB failed since instance num is zero")
 	}
+	// the VPC address of BLB must be in its subnet, choose the subnet of nodes containing it
+	if vip := getInternalVIP(service); len(vip) != 0 {
+		nodeSubnets := make(map[string]bool, len(ins))
+		for _, node := range ins {
+			nodeSubnets[node.SubnetID] = true
+		}
+		subnetID, err := bc.getSubnetContainingVIP(ctx, vpcID, nodeSubnets, vip)
+		if err != nil {
+			return "", "", err
+		}
+		klog.V(3).Infof("Use subnet %v containing %v for BLB", subnetID, vip)
+		return vpcID, subnetID, nil
+	}
 	// random select a VM to choose subnet
 	randomVM := ins[rand.Intn(len(ins))]
 	subnetID = randomVM.SubnetID
@@ -92,6 +108,31 @@ func (bc *Baiducloud) getVpcInfoForBLB(ctx context.Context, service *v1.Service)
 	return "", "", fmt.Errorf("no suitable subnet found for BLB")
 }
 
+// getSubnetContainingVIP returns the BCC subnet of nodes whose CIDR contains vip
+func (bc *Baiducloud) getSubnetContainingVIP(ctx context.Context, vpcID string, nodeSubnets map[string]bool, vip string) (string, error) {
+	ip := net.ParseIP(vip)
+	if ip == nil || ip.To4() == nil {
+		return "", newValidationError(fmt.Errorf("%s is not an IPv4 address", vip))
+	}
+	subnets, err := bc.clientSet.VPCClient.ListSubnet(ctx, &vpc.ListSubnetArgs{VPCID: vpcID}, bc.getSignOption(ctx))
+	if err != nil {
+		return "", fmt.Errorf("ListSubnet failed: %v", err)
+	}
+	for _, subnet := range subnets {
+		if !nodeSubnets[subnet.SubnetID] || subnet.SubnetType != "BCC" {
+			continue
+		}
+		_, cidr, err := net.ParseCIDR(subnet.CIDR)
+		if err != nil {
+			continue
+		}
+		if cidr.Contains(ip) {
+			return subnet.SubnetID, nil
+		}
+	}
+	return "", newValidationError(fmt.Errorf("%s is not in any BCC subnet of nodes, set annotation %s to choose the subnet of BLB", vip, ServiceAnnotationLoadBalancerSubnetID))
+}
+
 func (bc *Baiducloud) subnetIsTypeBCC(ctx context.Context, subnetID string) (bool, error) {
 	subnet, err := bc.clientSet.VPCClient.DescribeSubnet(ctx, subnetID, bc.getSignOption(ctx))
 	if err != nil {
@@ -99,3 +140,59 @@ func (bc *Baiducloud) subnetIsTypeBCC(ctx context.Context, subnetID string) (boo
 	}
 	return subnet.SubnetType == "BCC", nil
 }
+
+// getInternalVIP returns the VPC address requested for BLB of internal-vpc service,
+// the internal-vip annotation overrides loadBalancerIP
+func getInternalVIP(service *v1.Service) string {
+	if !isInternalVpcService(service) {
+		return ""
+	}
+	if vip, ok := service.Annotations[ServiceAnnotationLoadBalancerInternalVIP]; ok && len(vip) != 0 {
+		return vip
+	}
+	return service.Spec.LoadBalancerIP
+}
+
+// validateInternalVIP checks that vip is a host address in the CIDR of subnet, and is not used by BLBs of the VPC or nodes of cluster.
+// The same address may be used in other VPCs. Addresses used by other instances in the subnet are rejected by CreateLoadBalancer.
+func (bc *Baiducloud) validateInternalVIP(ctx context.Context, vpcID, subnetID string, vip string) error {
+	ip := net.ParseIP(vip)
+	if ip == nil || ip.To4() == nil {
+		return newValidationError(fmt.Errorf("%s is not an IPv4 address", vip))
+	}
+	subnet, err := bc.clientSet.VPCClient.DescribeSubnet(ctx, subnetID, bc.getSignOption(ctx))
+	if err != nil {
+		return fmt.Errorf("DescribeSubnet failed: %v", err)
+	}
+	_, cidr, err := net.ParseCIDR(subnet.CIDR)
+	if err != nil {
+		return fmt.Errorf("CIDR %s of subnet %s is invalid: %v", subnet.CIDR, subnetID, err)
+	}
+	if !cidr.Contains(ip) {
+		return newValidationError(fmt.Errorf("%s is not in CIDR %s of subnet %s", vip, subnet.CIDR, subnetID))
+	}
+	first, last := AddressRange(cidr)
+	if ip.Equal(first) || ip.Equal(last) {
+		return newValidationError(fmt.Errorf("%s is the network or broadcast address of subnet %s", vip, subnetID))
+	}
+
+	lbs, err := bc.clientSet.BLBAddressClient.DescribeLoadBalancers(ctx, &tempblb.DescribeLoadBalancersArgs{Address: vip}, bc.getSignOption(ctx))
+	if err != nil {
+		return err
+	}
+	for _, lb := range lbs {
+		if lb.VpcID == vpcID && lb.Address == vip {
+			return newValidationError(fmt.Errorf("%s is used by BLB %s", vip, lb.BlbId))
+		}
+	}
+	nodes, err := bc.clientSet.CCEClient.ListClusterNodes(ctx, bc.ClusterID, bc.getSignOption(ctx))
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes.Nodes {
+		if node.IP == vip {
+			return newValidationError(fmt.Errorf("%s is used by node %s", vip, node.InstanceID))
+		}
+	}
+	return nil
+}
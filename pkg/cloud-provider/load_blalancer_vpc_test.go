@@ -2,9 +2,18 @@ package cloud_provider
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/vpc"
+
+	"icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/fake"
+	tempblb "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
 )
 
 func TestGetVpcInfoForBLB(t *testing.T) {
@@ -32,3 +41,133 @@ func TestGetVpcInfoForBLB(t *testing.T) {
 		t.Errorf("getVpcInfoForBLB err, get vpcID : %v or get newSubnetID : %v", vpcID, newSubnetID)
 	}
 }
+
+func TestGetVpcInfoForBLBWithInternalVIP(t *testing.T) {
+	cloud, resp, err := newCluster()
+	if err != nil {
+		t.Fatalf("create cluster error, %v", err)
+	}
+	ctx := context.Background()
+	vpcID := resp.Nodes[0].VPCID
+	subnetID, err := cloud.clientSet.VPCClient.CreateSubnet(ctx, &vpc.CreateSubnetArgs{
+		VPCID:      vpcID,
+		SubnetType: vpc.SubnetTypeBCC,
+		CIDR:       "10.1.0.0/16",
+	}, nil)
+	if err != nil {
+		t.Fatalf("CreateSubnet err, err: %v", err)
+	}
+	cloud.clientSet.CCEClient.(*fake.CceFakeClient).NodeMap[resp.Nodes[1].InstanceID].SubnetID = subnetID
+	svc := buildService()
+	svc.Annotations = map[string]string{ServiceAnnotationLoadBalancerInternalVpc: "true"}
+
+	// the subnet of nodes containing the VPC address is chosen
+	for vip, want := range map[string]string{"10.1.2.3": subnetID, "10.0.2.3": resp.Nodes[0].SubnetID} {
+		svc.Spec.LoadBalancerIP = vip
+		for i := 0; i < 5; i++ {
+			_, got, err := cloud.getVpcInfoForBLB(ctx, svc)
+			if err != nil {
+				t.Fatalf("getVpcInfoForBLB err, err: %v", err)
+			}
+			if got != want {
+				t.Errorf("subnet of BLB with address %s expected %s, got %s", vip, want, got)
+			}
+		}
+	}
+
+	// address out of subnets of nodes is rejected
+	svc.Spec.LoadBalancerIP = "10.2.2.3"
+	_, _, err = cloud.getVpcInfoForBLB(ctx, svc)
+	var vErr *validationError
+	if !errors.As(err, &vErr) {
+		t.Errorf("getVpcInfoForBLB err expected validation error, got %v", err)
+	}
+}
+
+func TestValidateInternalVIP(t *testing.T) {
+	cloud, resp, err := newCluster()
+	if err != nil {
+		t.Fatalf("create cluster error, %v", err)
+	}
+	ctx := context.Background()
+	subnetID := resp.Nodes[0].SubnetID
+	cloud.clientSet.CCEClient.(*fake.CceFakeClient).NodeMap[resp.Nodes[0].InstanceID].IP = "10.0.0.5"
+	cases := []struct {
+		vip     string
+		wantErr bool
+	}{
+		{vip: "10.0.3.4"},
+		{vip: "10.0.3", wantErr: true},
+		{vip: "192.168.1.1", wantErr: true},
+		{vip: "10.0.0.0", wantErr: true},
+		{vip: "10.0.255.255", wantErr: true},
+		{vip: "10.0.0.5", wantErr: true},
+		// addresses of BLBs in other VPCs can be used
+		{vip: "10.0.3.7"},
+		{vip: "10.0.3.8", wantErr: true},
+	}
+	for vpcID, vip := range map[string]string{"vpc-other": "10.0.3.7", resp.Nodes[0].VPCID: "10.0.3.8"} {
+		_, err := cloud.clientSet.BLBAddressClient.CreateLoadBalancer(ctx, &tempblb.CreateLoadBalancerArgs{
+			Name:     "blb-" + vip,
+			VpcID:    vpcID,
+			SubnetID: subnetID,
+			Address:  vip,
+		}, nil)
+		if err != nil {
+			t.Fatalf("CreateLoadBalancer err, err: %v", err)
+		}
+	}
+	for _, c := range cases {
+		err := cloud.validateInternalVIP(ctx, resp.Nodes[0].VPCID, subnetID, c.vip)
+		if (err != nil) != c.wantErr {
+			t.Errorf("validateInternalVIP %s err %v, wantErr %v", c.vip, err, c.wantErr)
+		}
+	}
+}
+
+func TestCreateBLBWithInternalVIP(t *testing.T) {
+	cloud, resp, err := newCluster()
+	if err != nil {
+		t.Fatalf("create cluster error, %v", err)
+	}
+	recorder := record.NewFakeRecorder(10)
+	cloud.eventRecorder = recorder
+	ctx := context.Background()
+	newService := func(name string) *v1.Service {
+		svc := buildService()
+		svc.Name = name
+		svc.UID = types.UID("uid-" + name)
+		svc.Annotations = map[string]string{
+			ServiceAnnotationLoadBalancerInternalVpc: "true",
+			ServiceAnnotationLoadBalancerSubnetID:    resp.Nodes[0].SubnetID,
+		}
+		svc.Spec.LoadBalancerIP = "10.0.3.4"
+		return svc
+	}
+
+	// loadBalancerIP is the VPC address of BLB
+	id, err := cloud.createBLB(ctx, newService("foo"))
+	if err != nil {
+		t.Fatalf("createBLB err, err: %v", err)
+	}
+	if lb := cloud.clientSet.BLBClient.(*fake.BlbFakeClient).LoadBalancerMap[id]; lb.Address != "10.0.3.4" {
+		t.Errorf("VPC address of BLB %s expected 10.0.3.4, got %s", id, lb.Address)
+	}
+
+	// address used is rejected with event, the annotation overrides loadBalancerIP
+	svc := newService("bar")
+	if _, err := cloud.createBLB(ctx, svc); err == nil {
+		t.Errorf("createBLB err, address used by BLB %s should be rejected", id)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "InvalidInternalVIP") {
+		t.Errorf("event expected InvalidInternalVIP, got %s", event)
+	}
+	svc.Annotations[ServiceAnnotationLoadBalancerInternalVIP] = "10.0.3.5"
+	id, err = cloud.createBLB(ctx, svc)
+	if err != nil {
+		t.Fatalf("createBLB err, err: %v", err)
+	}
+	if lb := cloud.clientSet.BLBClient.(*fake.BlbFakeClient).LoadBalancerMap[id]; lb.Address != "10.0.3.5" {
+		t.Errorf("VPC address of BLB %s expected 10.0.3.5, got %s", id, lb.Address)
+	}
+}
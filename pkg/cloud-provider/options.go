@@ -18,10 +18,13 @@ package cloud_provider
 
 import (
 	"fmt"
+	"net"
 	"strconv"
+	"strings"
 
 	"k8s.io/klog"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 const (
@@ -31,6 +34,23 @@ const (
 	ServiceAnnotationCceAutoAddLoadBalancerID = ServiceAnnotationLoadBalancerPrefix + "cce-add-id"
 	// ServiceAnnotationCceAutoAddEip is the annotation of CCE adding Eip
 	ServiceAnnotationCceAutoAddEip = ServiceAnnotationLoadBalancerPrefix + "cce-add-eip"
+	// ServiceAnnotationCceAutoAddVpcAddress is the annotation of CCE adding the VPC address of BLB
+	ServiceAnnotationCceAutoAddVpcAddress = ServiceAnnotationLoadBalancerPrefix + "cce-add-vpc-address"
+	// ServiceAnnotationCceClientTokenGeneration is the annotation of CCE counting deletions of resources created for service,
+	// which is a part of client tokens so that resources deleted are not returned for them
+	ServiceAnnotationCceClientTokenGeneration = ServiceAnnotationLoadBalancerPrefix + "cce-client-token-generation"
+	// ServiceAnnotationCceLoadBalancerConfigVersion is the annotation of CCE recording the resource version of the LoadBalancerConfig
+	// referred by service, which is changed with the config so that service controller syncs the service again
+	ServiceAnnotationCceLoadBalancerConfigVersion = ServiceAnnotationLoadBalancerPrefix + "cce-config-version"
+	// ServiceAnnotationCceBackendType is the annotation of CCE recording the backend type registered to the BLB, "node" if absent,
+	// so that backends of the other type are only removed after the backend type changed
+	ServiceAnnotationCceBackendType = ServiceAnnotationLoadBalancerPrefix + "cce-backend-type"
+	// ServiceAnnotationCceDrainingBackends is the annotation of CCE recording when the draining rs of BLB started draining,
+	// a JSON object of instance id to unix seconds, so that the drain period is kept across restarts of CCM
+	ServiceAnnotationCceDrainingBackends = ServiceAnnotationLoadBalancerPrefix + "cce-draining-backends"
+	// ServiceAnnotationCceEIPPool is the annotation of CCE recording the EIPPool which the EIP of service is claimed from,
+	// so that EIPPools are only listed for services using them
+	ServiceAnnotationCceEIPPool = ServiceAnnotationLoadBalancerPrefix + "cce-eip-pool"
 	// ServiceAnnotationLoadBalancerExistID is the annotation of user assign blbid
 	ServiceAnnotationLoadBalancerExistID = ServiceAnnotationLoadBalancerPrefix + "exist-id"
 
@@ -38,34 +58,53 @@ const (
 
 	// ServiceAnnotationLoadBalancerInternalVpc is the annotation of LoadBalancerInternalVpc
 	ServiceAnnotationLoadBalancerInternalVpc = ServiceAnnotationLoadBalancerPrefix + "internal-vpc"
+	// ServiceAnnotationLoadBalancerInternalVIP is the annotation of the private address requested for BLB of internal-vpc service,
+	// which overrides loadBalancerIP
+	ServiceAnnotationLoadBalancerInternalVIP = ServiceAnnotationLoadBalancerPrefix + "internal-vip"
 	// ServiceAnnotationLoadBalancerAllocateVip is the annotation which indicates BLB with a VIP
 	ServiceAnnotationLoadBalancerAllocateVip = ServiceAnnotationLoadBalancerPrefix + "allocate-vip"
 	//ServiceAnnotationLoadBalancerSubnetID is the annotation which indicates the BCC type subnet the BLB will use
 	ServiceAnnotationLoadBalancerSubnetID = ServiceAnnotationLoadBalancerPrefix + "subnet-id"
 	// ServiceAnnotationLoadBalancerRsMaxNum is the annotation which set max num of rs of the BLB
 	ServiceAnnotationLoadBalancerRsMaxNum = ServiceAnnotationLoadBalancerPrefix + "rs-max-num"
+	// ServiceAnnotationLoadBalancerRsDrainPeriodInSecond is the annotation of how long a rs leaving the BLB keeps weight 0 before removed, default 0, [0, 3600]
+	ServiceAnnotationLoadBalancerRsDrainPeriodInSecond = ServiceAnnotationLoadBalancerPrefix + "rs-drain-period-in-second"
+	// ServiceAnnotationLoadBalancerBackendType is the annotation of backend type of the BLB, "node" (default) or "pod"
+	ServiceAnnotationLoadBalancerBackendType = ServiceAnnotationLoadBalancerPrefix + "backend-type"
+	// ServiceAnnotationLoadBalancerNodeSelector is the annotation of label selector which restricts the nodes used as rs of the BLB, e.g. "pool=ingress"
+	ServiceAnnotationLoadBalancerNodeSelector = ServiceAnnotationLoadBalancerPrefix + "node-selector"
+	// ServiceAnnotationLoadBalancerRollbackPolicy is the annotation of what to do with resources created by a failed first provisioning, "retain" (default) or "delete"
+	ServiceAnnotationLoadBalancerRollbackPolicy = ServiceAnnotationLoadBalancerPrefix + "rollback-policy"
+	// ServiceAnnotationLoadBalancerConfig is the annotation of the name of LoadBalancerConfig in the namespace of service, whose settings override annotations
+	ServiceAnnotationLoadBalancerConfig = ServiceAnnotationLoadBalancerPrefix + "config"
+	// ServiceAnnotationLoadBalancerDefaulted is the annotation of keys of annotations written by the admission webhook with default values, separated by comma
+	ServiceAnnotationLoadBalancerDefaulted = ServiceAnnotationLoadBalancerPrefix + "defaulted"
 	// ServiceAnnotationLoadBalancerReserveBLB is the annotation which not delete BLB when delete service
 	ServiceAnnotationLoadBalancerReserveLB = ServiceAnnotationLoadBalancerPrefix + "reserve-lb"
+	// ServiceAnnotationLoadBalancerReserveEIP is the annotation which unbinds and keeps the EIP created for service when delete service,
+	// the EIP is bound again when a service with the same namespace and name returns
+	ServiceAnnotationLoadBalancerReserveEIP = ServiceAnnotationLoadBalancerPrefix + "reserve-eip"
+	// ServiceAnnotationLoadBalancerPublishVpcAddress is the annotation which publishes the VPC address of BLB in status after the EIP
+	ServiceAnnotationLoadBalancerPublishVpcAddress = ServiceAnnotationLoadBalancerPrefix + "publish-vpc-address"
 
 	ServiceAnnotationLoadBalancerBLBName = ServiceAnnotationLoadBalancerPrefix + "lb-name"
 
-	// TODO:
+	// ServiceAnnotationLoadBalancerListenerProtocol is the annotation which overrides the listener protocol of ports, e.g. "80:HTTP,443:HTTPS"
+	ServiceAnnotationLoadBalancerListenerProtocol = ServiceAnnotationLoadBalancerPrefix + "listener-protocol"
+	// ServiceAnnotationLoadBalancerCertID is the annotation of the certificate id used by HTTPS listeners
+	ServiceAnnotationLoadBalancerCertID = ServiceAnnotationLoadBalancerPrefix + "cert-id"
+
 	// ServiceAnnotationLoadBalancerScheduler is the annotation of load balancer which can be "RoundRobin"/"LeastConnection"/"Hash"
 	ServiceAnnotationLoadBalancerScheduler = ServiceAnnotationLoadBalancerPrefix + "scheduler"
-	// TODO:
 	// ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond is the annotation of health check timeout, default 3s, [1, 60]
 	ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond = ServiceAnnotationLoadBalancerPrefix + "health-check-timeout-in-second"
-	// TODO:
 	// ServiceAnnotationLoadBalancerHealthCheckInterval is the annotation of health check interval, default 3s, [1, 10]
 	ServiceAnnotationLoadBalancerHealthCheckInterval = ServiceAnnotationLoadBalancerPrefix + "health-check-interval"
-	// TODO:
 	// ServiceAnnotationLoadBalancerUnhealthyThreshold is the annotation of unhealthy threshold, default 3, [2, 5]
 	ServiceAnnotationLoadBalancerUnhealthyThreshold = ServiceAnnotationLoadBalancerPrefix + "unhealthy-threshold"
-	// TODO:
 	// ServiceAnnotationLoadBalancerHealthyThreshold is the annotation of healthy threshold, default 3, [2, 5]
 	ServiceAnnotationLoadBalancerHealthyThreshold = ServiceAnnotationLoadBalancerPrefix + "healthy-threshold"
-	// TODO:
-	// ServiceAnnotationLoadBalancerHealthCheckString is the annotation of health check string
+	// ServiceAnnotationLoadBalancerHealthCheckString is the annotation of health check string, only used by UDP listener
 	ServiceAnnotationLoadBalancerHealthCheckString = ServiceAnnotationLoadBalancerPrefix + "health-check-string"
 
 	// ServiceAnnotationElasticIPPrefix is the annotation prefix of ElasticIP
@@ -80,6 +119,20 @@ const (
 	ServiceAnnotationElasticIPBandwidthInMbps = ServiceAnnotationElasticIPPrefix + "bandwidth-in-mbps"
 	// ServiceAnnotationElasticIPReservationLength is the annotation of ElasticIPReservationLength
 	ServiceAnnotationElasticIPReservationLength = ServiceAnnotationElasticIPPrefix + "reservation-length"
+	// ServiceAnnotationElasticIPBillingChangePolicy is the annotation of how a change of payment timing or billing method of EIP is applied, "reject" (default), "recreate" or "in-place"
+	ServiceAnnotationElasticIPBillingChangePolicy = ServiceAnnotationElasticIPPrefix + "billing-change-policy"
+	// ServiceAnnotationElasticIPGroup is the annotation of the name of EIP group sharing bandwidth, which EIP is moved into
+	ServiceAnnotationElasticIPGroup = ServiceAnnotationElasticIPPrefix + "group"
+	// ServiceAnnotationElasticIPPool is the annotation of the name of EIPPool which EIP is claimed from
+	ServiceAnnotationElasticIPPool = ServiceAnnotationElasticIPPrefix + "pool"
+	// ServiceAnnotationElasticIPRouteType is the annotation of the line type of EIP, "BGP" (default) or "BGP_S"
+	ServiceAnnotationElasticIPRouteType = ServiceAnnotationElasticIPPrefix + "route-type"
+	// ServiceAnnotationElasticIPAutoRenewLength is the annotation of the months a Prepaid EIP is renewed automatically by
+	ServiceAnnotationElasticIPAutoRenewLength = ServiceAnnotationElasticIPPrefix + "auto-renew-length"
+	// ServiceAnnotationElasticIPTags is the annotation of the tags of EIP, e.g. "team=web,env=prod"
+	ServiceAnnotationElasticIPTags = ServiceAnnotationElasticIPPrefix + "tags"
+	// ServiceAnnotationElasticIPDriftPolicy is the annotation of what to do when EIP drifts from service out of band, "report", "rebind" (default) or "repair"
+	ServiceAnnotationElasticIPDriftPolicy = ServiceAnnotationElasticIPPrefix + "drift-policy"
 )
 
 const (
@@ -97,6 +150,9 @@ const (
 
 	// NodeAnnotationAdvertiseRoute indicates whether to advertise route to vpc route table
 	NodeAnnotationAdvertiseRoute = NodeAnnotationPrefix + "advertise-route"
+
+	// NodeAnnotationBLBRsWeight is the weight of node as BLB rs, default 100, [1, 100]
+	NodeAnnotationBLBRsWeight = NodeAnnotationPrefix + "blb-rs-weight"
 )
 
 // ServiceAnnotation contains annotations from service
@@ -108,11 +164,22 @@ type ServiceAnnotation struct {
 	CceAutoAddEip            string
 	LoadBalancerExistID      string
 	LoadBalancerInternalVpc  string
+	LoadBalancerInternalVIP  string
 	LoadBalancerAllocateVip  string
 	LoadBalancerSubnetID     string
 	LoadBalancerScheduler    string
 	LoadBalancerRsMaxNum     int
 	LoadBalancerReserveLB    string
+	LoadBalancerReserveEIP   string
+
+	LoadBalancerRsDrainPeriodInSecond int
+	LoadBalancerNodeSelector          labels.Selector
+	LoadBalancerBackendType           string
+	LoadBalancerRollbackPolicy        string
+	LoadBalancerPublishVpcAddress     string
+
+	LoadBalancerListenerProtocol map[int]string
+	LoadBalancerCertID           string
 
 	LoadBalancerHealthCheckTimeoutInSecond int
 	LoadBalancerHealthCheckInterval        int
@@ -120,12 +187,74 @@ type ServiceAnnotation struct {
 	LoadBalancerHealthyThreshold           int
 	LoadBalancerHealthCheckString          string
 
+	// LoadBalancerListeners overrides settings of listeners by port, it is only set by LoadBalancerConfig
+	LoadBalancerListeners map[int]ListenerConfig
+
 	/* EIP */
 	ElasticIPName              string
 	ElasticIPPaymentTiming     string
 	ElasticIPBillingMethod     string
 	ElasticIPBandwidthInMbps   int
 	ElasticIPReservationLength int
+
+	ElasticIPBillingChangePolicy string
+	ElasticIPGroup               string
+	ElasticIPPool                string
+	ElasticIPRouteType           string
+	ElasticIPAutoRenewLength     int
+	ElasticIPTags                map[string]string
+	ElasticIPDriftPolicy         string
+}
+
+// ListenerConfig overrides settings of the listeners of a port, zero values are not overridden
+type ListenerConfig struct {
+	CertID    string
+	Scheduler string
+
+	HealthCheckTimeoutInSecond int
+	HealthCheckInterval        int
+	UnhealthyThreshold         int
+	HealthyThreshold           int
+	HealthCheckString          string
+}
+
+// listenerConfig returns the settings of the listeners of port
+func (anno *ServiceAnnotation) listenerConfig(port int) ListenerConfig {
+	result := ListenerConfig{
+		CertID:                     anno.LoadBalancerCertID,
+		Scheduler:                  anno.LoadBalancerScheduler,
+		HealthCheckTimeoutInSecond: anno.LoadBalancerHealthCheckTimeoutInSecond,
+		HealthCheckInterval:        anno.LoadBalancerHealthCheckInterval,
+		UnhealthyThreshold:         anno.LoadBalancerUnhealthyThreshold,
+		HealthyThreshold:           anno.LoadBalancerHealthyThreshold,
+		HealthCheckString:          anno.LoadBalancerHealthCheckString,
+	}
+	lc, ok := anno.LoadBalancerListeners[port]
+	if !ok {
+		return result
+	}
+	if lc.CertID != "" {
+		result.CertID = lc.CertID
+	}
+	if lc.Scheduler != "" {
+		result.Scheduler = lc.Scheduler
+	}
+	if lc.HealthCheckTimeoutInSecond != 0 {
+		result.HealthCheckTimeoutInSecond = lc.HealthCheckTimeoutInSecond
+	}
+	if lc.HealthCheckInterval != 0 {
+		result.HealthCheckInterval = lc.HealthCheckInterval
+	}
+	if lc.UnhealthyThreshold != 0 {
+		result.UnhealthyThreshold = lc.UnhealthyThreshold
+	}
+	if lc.HealthyThreshold != 0 {
+		result.HealthyThreshold = lc.HealthyThreshold
+	}
+	if lc.HealthCheckString != "" {
+		result.HealthCheckString = lc.HealthCheckString
+	}
+	return result
 }
 
 // NodeAnnotation contains annotations from node
@@ -175,6 +304,14 @@ func ExtractServiceAnnotation(service *v1.Service) (*ServiceAnnotation, error) {
 		result.LoadBalancerInternalVpc = loadBalancerInternalVpc
 	}
 
+	loadBalancerInternalVIP, exist := annotation[ServiceAnnotationLoadBalancerInternalVIP]
+	if exist {
+		if ip := net.ParseIP(loadBalancerInternalVIP); ip == nil || ip.To4() == nil {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerInternalVIP must be an IPv4 address")
+		}
+		result.LoadBalancerInternalVIP = loadBalancerInternalVIP
+	}
+
 	loadBalancerAllocateVip, ok := annotation[ServiceAnnotationLoadBalancerAllocateVip]
 	if ok {
 		result.LoadBalancerAllocateVip = loadBalancerAllocateVip
@@ -197,9 +334,55 @@ func ExtractServiceAnnotation(service *v1.Service) (*ServiceAnnotation, error) {
 		}
 	}
 
+	loadBalancerRsDrainPeriod, ok := annotation[ServiceAnnotationLoadBalancerRsDrainPeriodInSecond]
+	if ok {
+		i, err := strconv.Atoi(loadBalancerRsDrainPeriod)
+		if err != nil {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerRsDrainPeriodInSecond must be int, err: %v", err)
+		} else if i < 0 || i > 3600 {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerRsDrainPeriodInSecond must be in [0, 3600]")
+		} else {
+			result.LoadBalancerRsDrainPeriodInSecond = i
+		}
+	}
+
+	loadBalancerNodeSelector, ok := annotation[ServiceAnnotationLoadBalancerNodeSelector]
+	if ok {
+		selector, err := labels.Parse(loadBalancerNodeSelector)
+		if err != nil {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerNodeSelector syntax error: %v", err)
+		}
+		result.LoadBalancerNodeSelector = selector
+	}
+
+	loadBalancerBackendType, ok := annotation[ServiceAnnotationLoadBalancerBackendType]
+	if ok {
+		switch loadBalancerBackendType {
+		case BackendTypeNode, BackendTypePod:
+			result.LoadBalancerBackendType = loadBalancerBackendType
+		default:
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerBackendType must be %s or %s", BackendTypeNode, BackendTypePod)
+		}
+	}
+
+	loadBalancerRollbackPolicy, ok := annotation[ServiceAnnotationLoadBalancerRollbackPolicy]
+	if ok {
+		switch loadBalancerRollbackPolicy {
+		case RollbackPolicyRetain, RollbackPolicyDelete:
+			result.LoadBalancerRollbackPolicy = loadBalancerRollbackPolicy
+		default:
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerRollbackPolicy must be %s or %s", RollbackPolicyRetain, RollbackPolicyDelete)
+		}
+	}
+
 	loadBalancerScheduler, ok := annotation[ServiceAnnotationLoadBalancerScheduler]
 	if ok {
-		result.LoadBalancerScheduler = loadBalancerScheduler
+		switch loadBalancerScheduler {
+		case "RoundRobin", "LeastConnection", "Hash":
+			result.LoadBalancerScheduler = loadBalancerScheduler
+		default:
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerScheduler must be RoundRobin, LeastConnection or Hash")
+		}
 	}
 
 	loadBalancerReserveLB, ok := annotation[ServiceAnnotationLoadBalancerReserveLB]
@@ -207,11 +390,37 @@ func ExtractServiceAnnotation(service *v1.Service) (*ServiceAnnotation, error) {
 		result.LoadBalancerReserveLB = loadBalancerReserveLB
 	}
 
+	loadBalancerReserveEIP, ok := annotation[ServiceAnnotationLoadBalancerReserveEIP]
+	if ok {
+		result.LoadBalancerReserveEIP = loadBalancerReserveEIP
+	}
+
+	loadBalancerPublishVpcAddress, ok := annotation[ServiceAnnotationLoadBalancerPublishVpcAddress]
+	if ok {
+		result.LoadBalancerPublishVpcAddress = loadBalancerPublishVpcAddress
+	}
+
+	loadBalancerListenerProtocol, ok := annotation[ServiceAnnotationLoadBalancerListenerProtocol]
+	if ok {
+		listenerProtocol, err := parseListenerProtocol(loadBalancerListenerProtocol)
+		if err != nil {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerListenerProtocol syntax error: %v", err)
+		}
+		result.LoadBalancerListenerProtocol = listenerProtocol
+	}
+
+	loadBalancerCertID, ok := annotation[ServiceAnnotationLoadBalancerCertID]
+	if ok {
+		result.LoadBalancerCertID = loadBalancerCertID
+	}
+
 	loadBalancerHealthCheckTimeoutInSecond, exist := annotation[ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond]
 	if exist {
 		i, err := strconv.Atoi(loadBalancerHealthCheckTimeoutInSecond)
 		if err != nil {
 			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond must be int")
+		} else if i < 1 || i > 60 {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond must be in [1, 60]")
 		} else {
 			result.LoadBalancerHealthCheckTimeoutInSecond = i
 		}
@@ -222,6 +431,8 @@ func ExtractServiceAnnotation(service *v1.Service) (*ServiceAnnotation, error) {
 		i, err := strconv.Atoi(loadBalancerHealthCheckInterval)
 		if err != nil {
 			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerHealthCheckInterval must be int")
+		} else if i < 1 || i > 10 {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerHealthCheckInterval must be in [1, 10]")
 		} else {
 			result.LoadBalancerHealthCheckInterval = i
 		}
@@ -232,6 +443,8 @@ func ExtractServiceAnnotation(service *v1.Service) (*ServiceAnnotation, error) {
 		i, err := strconv.Atoi(loadBalancerUnhealthyThreshold)
 		if err != nil {
 			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerUnhealthyThreshold must be int")
+		} else if i < 2 || i > 5 {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerUnhealthyThreshold must be in [2, 5]")
 		} else {
 			result.LoadBalancerUnhealthyThreshold = i
 		}
@@ -242,6 +455,8 @@ func ExtractServiceAnnotation(service *v1.Service) (*ServiceAnnotation, error) {
 		i, err := strconv.Atoi(loadBalancerHealthyThreshold)
 		if err != nil {
 			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerHealthyThreshold must be int")
+		} else if i < 2 || i > 5 {
+			return nil, fmt.Errorf("ServiceAnnotationLoadBalancerHealthyThreshold must be in [2, 5]")
 		} else {
 			result.LoadBalancerHealthyThreshold = i
 		}
@@ -287,6 +502,103 @@ func ExtractServiceAnnotation(service *v1.Service) (*ServiceAnnotation, error) {
 		}
 	}
 
+	elasticIPBillingChangePolicy, exist := annotation[ServiceAnnotationElasticIPBillingChangePolicy]
+	if exist {
+		switch elasticIPBillingChangePolicy {
+		case EIPBillingChangeReject, EIPBillingChangeRecreate, EIPBillingChangeInPlace:
+			result.ElasticIPBillingChangePolicy = elasticIPBillingChangePolicy
+		default:
+			return nil, fmt.Errorf("ServiceAnnotationElasticIPBillingChangePolicy must be %s, %s or %s", EIPBillingChangeReject, EIPBillingChangeRecreate, EIPBillingChangeInPlace)
+		}
+	}
+
+	elasticIPGroup, exist := annotation[ServiceAnnotationElasticIPGroup]
+	if exist {
+		result.ElasticIPGroup = elasticIPGroup
+	}
+
+	elasticIPPool, exist := annotation[ServiceAnnotationElasticIPPool]
+	if exist {
+		result.ElasticIPPool = elasticIPPool
+	}
+
+	elasticIPRouteType, exist := annotation[ServiceAnnotationElasticIPRouteType]
+	if exist {
+		result.ElasticIPRouteType = elasticIPRouteType
+	}
+
+	elasticIPAutoRenewLength, exist := annotation[ServiceAnnotationElasticIPAutoRenewLength]
+	if exist {
+		i, err := strconv.Atoi(elasticIPAutoRenewLength)
+		if err != nil {
+			return nil, fmt.Errorf("ServiceAnnotationElasticIPAutoRenewLength must be int")
+		}
+		result.ElasticIPAutoRenewLength = i
+	}
+
+	elasticIPTags, exist := annotation[ServiceAnnotationElasticIPTags]
+	if exist {
+		tags, err := parseElasticIPTags(elasticIPTags)
+		if err != nil {
+			return nil, err
+		}
+		result.ElasticIPTags = tags
+	}
+
+	elasticIPDriftPolicy, exist := annotation[ServiceAnnotationElasticIPDriftPolicy]
+	if exist {
+		switch elasticIPDriftPolicy {
+		case EIPDriftReport, EIPDriftRebind, EIPDriftRepair:
+			result.ElasticIPDriftPolicy = elasticIPDriftPolicy
+		default:
+			return nil, fmt.Errorf("ServiceAnnotationElasticIPDriftPolicy must be %s, %s or %s", EIPDriftReport, EIPDriftRebind, EIPDriftRepair)
+		}
+	}
+
+	return result, nil
+}
+
+// parseListenerProtocol parses "port:protocol" pairs separated by comma, e.g. "80:HTTP,443:HTTPS"
+func parseListenerProtocol(value string) (map[int]string, error) {
+	result := make(map[int]string)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+		kv := strings.Split(item, ":")
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%q should be in format port:protocol", item)
+		}
+		port, err := strconv.Atoi(strings.TrimSpace(kv[0]))
+		if err != nil {
+			return nil, fmt.Errorf("port of %q must be int", item)
+		}
+		protocol := strings.ToUpper(strings.TrimSpace(kv[1]))
+		switch protocol {
+		case "TCP", "UDP", "HTTP", "HTTPS":
+		default:
+			return nil, fmt.Errorf("protocol of %q is not supported", item)
+		}
+		result[port] = protocol
+	}
+	return result, nil
+}
+
+// parseElasticIPTags parses "key=value" pairs separated by comma, e.g. "team=web,env=prod"
+func parseElasticIPTags(value string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || len(strings.TrimSpace(kv[0])) == 0 {
+			return nil, fmt.Errorf("%q should be in format key=value", item)
+		}
+		result[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
 	return result, nil
 }
 
@@ -77,11 +77,13 @@ func TestExtractServiceAnnotationBLB(t *testing.T) {
 	data[ServiceAnnotationLoadBalancerAllocateVip] = "10.12.1.1"
 	data[ServiceAnnotationLoadBalancerSubnetID] = "10.12.1.1"
 	data[ServiceAnnotationLoadBalancerRsMaxNum] = "11"
-	data[ServiceAnnotationLoadBalancerScheduler] = "dd"
+	data[ServiceAnnotationLoadBalancerRsDrainPeriodInSecond] = "30"
+	data[ServiceAnnotationLoadBalancerRollbackPolicy] = "delete"
+	data[ServiceAnnotationLoadBalancerScheduler] = "LeastConnection"
 	data[ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond] = "11"
-	data[ServiceAnnotationLoadBalancerHealthCheckInterval] = "11"
-	data[ServiceAnnotationLoadBalancerUnhealthyThreshold] = "11"
-	data[ServiceAnnotationLoadBalancerHealthyThreshold] = "11"
+	data[ServiceAnnotationLoadBalancerHealthCheckInterval] = "5"
+	data[ServiceAnnotationLoadBalancerUnhealthyThreshold] = "4"
+	data[ServiceAnnotationLoadBalancerHealthyThreshold] = "2"
 	data[ServiceAnnotationLoadBalancerHealthCheckString] = "dsada11"
 
 	svc.SetAnnotations(data)
@@ -101,7 +103,7 @@ func TestExtractServiceAnnotationBLB(t *testing.T) {
 	if result.LoadBalancerExistID != "dsada11" {
 		t.Errorf("extract service LoadBalancerExistID annotation wrong")
 	}
-	if strconv.Itoa(result.LoadBalancerHealthCheckInterval) != "11" {
+	if strconv.Itoa(result.LoadBalancerHealthCheckInterval) != "5" {
 		t.Errorf("extract service LoadBalancerHealthCheckInterval annotation wrong")
 	}
 	if result.LoadBalancerHealthCheckString != "dsada11" {
@@ -110,7 +112,7 @@ func TestExtractServiceAnnotationBLB(t *testing.T) {
 	if strconv.Itoa(result.LoadBalancerHealthCheckTimeoutInSecond) != "11" {
 		t.Errorf("extract service LoadBalancerHealthCheckTimeoutInSecond annotation wrong")
 	}
-	if strconv.Itoa(result.LoadBalancerHealthyThreshold) != "11" {
+	if strconv.Itoa(result.LoadBalancerHealthyThreshold) != "2" {
 		t.Errorf("extract service LoadBalancerHealthyThreshold annotation wrong")
 	}
 	if result.LoadBalancerInternalVpc != "10.12.1.1" {
@@ -119,10 +121,16 @@ func TestExtractServiceAnnotationBLB(t *testing.T) {
 	if strconv.Itoa(result.LoadBalancerRsMaxNum) != "11" {
 		t.Errorf("extract service LoadBalancerRsMaxNum annotation wrong")
 	}
-	if result.LoadBalancerScheduler != "dd" {
+	if result.LoadBalancerRsDrainPeriodInSecond != 30 {
+		t.Errorf("extract service LoadBalancerRsDrainPeriodInSecond annotation wrong")
+	}
+	if result.LoadBalancerRollbackPolicy != RollbackPolicyDelete {
+		t.Errorf("extract service LoadBalancerRollbackPolicy annotation wrong")
+	}
+	if result.LoadBalancerScheduler != "LeastConnection" {
 		t.Errorf("extract service LoadBalancerScheduler annotation wrong")
 	}
-	if strconv.Itoa(result.LoadBalancerUnhealthyThreshold) != "11" {
+	if strconv.Itoa(result.LoadBalancerUnhealthyThreshold) != "4" {
 		t.Errorf("extract service LoadBalancerUnhealthyThreshold annotation wrong")
 	}
 	if result.LoadBalancerSubnetID != "10.12.1.1" {
@@ -170,6 +178,35 @@ func TestExtractServiceAnnotationBLB(t *testing.T) {
 		t.Errorf("extract service LoadBalancerRsMaxNum annotation wrong, should exist wrong")
 	}
 
+	data7 := map[string]string{}
+	data7[ServiceAnnotationLoadBalancerScheduler] = "dd"
+	svc.SetAnnotations(data7)
+	result, err = ExtractServiceAnnotation(svc)
+	if err == nil {
+		t.Errorf("extract service LoadBalancerScheduler annotation wrong, should exist wrong")
+	}
+
+	data8 := map[string]string{}
+	data8[ServiceAnnotationLoadBalancerReserveEIP] = "true"
+	svc.SetAnnotations(data8)
+	result, err = ExtractServiceAnnotation(svc)
+	if err != nil || result.LoadBalancerReserveEIP != "true" {
+		t.Errorf("extract service LoadBalancerReserveEIP annotation wrong")
+	}
+
+	outOfRange := map[string]string{
+		ServiceAnnotationLoadBalancerHealthCheckTimeoutInSecond: "61",
+		ServiceAnnotationLoadBalancerHealthCheckInterval:        "11",
+		ServiceAnnotationLoadBalancerUnhealthyThreshold:         "1",
+		ServiceAnnotationLoadBalancerHealthyThreshold:           "6",
+	}
+	for k, v := range outOfRange {
+		svc.SetAnnotations(map[string]string{k: v})
+		result, err = ExtractServiceAnnotation(svc)
+		if err == nil {
+			t.Errorf("extract service %s=%s annotation wrong, should exist wrong", k, v)
+		}
+	}
 }
 
 func TestExtractServiceAnnotationEIP(t *testing.T) {
@@ -237,6 +274,55 @@ func TestExtractServiceAnnotationEIP(t *testing.T) {
 	if err == nil {
 		t.Errorf("extract service ElasticIPReservationLength annotation wrong, should exist wrong")
 	}
+
+	svc.SetAnnotations(map[string]string{ServiceAnnotationElasticIPBillingChangePolicy: "recreate"})
+	result, err = ExtractServiceAnnotation(svc)
+	if err != nil {
+		t.Errorf("failed to extract service annotation: %v", err)
+	}
+	if result.ElasticIPBillingChangePolicy != EIPBillingChangeRecreate {
+		t.Errorf("extract service ElasticIPBillingChangePolicy annotation wrong")
+	}
+	svc.SetAnnotations(map[string]string{ServiceAnnotationElasticIPTags: "team=web, env = prod"})
+	result, err = ExtractServiceAnnotation(svc)
+	if err != nil || len(result.ElasticIPTags) != 2 || result.ElasticIPTags["env"] != "prod" {
+		t.Errorf("extract service ElasticIPTags annotation wrong")
+	}
+	svc.SetAnnotations(map[string]string{ServiceAnnotationElasticIPTags: "team"})
+	_, err = ExtractServiceAnnotation(svc)
+	if err == nil {
+		t.Errorf("extract service ElasticIPTags annotation wrong, should exist wrong")
+	}
+	svc.SetAnnotations(map[string]string{ServiceAnnotationElasticIPAutoRenewLength: "1y"})
+	_, err = ExtractServiceAnnotation(svc)
+	if err == nil {
+		t.Errorf("extract service ElasticIPAutoRenewLength annotation wrong, should exist wrong")
+	}
+	svc.SetAnnotations(map[string]string{ServiceAnnotationElasticIPBillingChangePolicy: "delete"})
+	result, err = ExtractServiceAnnotation(svc)
+	if err == nil {
+		t.Errorf("extract service ElasticIPBillingChangePolicy annotation wrong, should exist wrong")
+	}
+	svc.SetAnnotations(map[string]string{ServiceAnnotationElasticIPDriftPolicy: EIPDriftRepair})
+	result, err = ExtractServiceAnnotation(svc)
+	if err != nil || result.ElasticIPDriftPolicy != EIPDriftRepair {
+		t.Errorf("extract service ElasticIPDriftPolicy annotation wrong")
+	}
+	svc.SetAnnotations(map[string]string{ServiceAnnotationElasticIPDriftPolicy: "ignore"})
+	_, err = ExtractServiceAnnotation(svc)
+	if err == nil {
+		t.Errorf("extract service ElasticIPDriftPolicy annotation wrong, should exist wrong")
+	}
+	svc.SetAnnotations(map[string]string{ServiceAnnotationLoadBalancerInternalVIP: "10.0.3.4"})
+	result, err = ExtractServiceAnnotation(svc)
+	if err != nil || result.LoadBalancerInternalVIP != "10.0.3.4" {
+		t.Errorf("extract service LoadBalancerInternalVIP annotation wrong")
+	}
+	svc.SetAnnotations(map[string]string{ServiceAnnotationLoadBalancerInternalVIP: "10.0.3"})
+	_, err = ExtractServiceAnnotation(svc)
+	if err == nil {
+		t.Errorf("extract service LoadBalancerInternalVIP annotation wrong, should exist wrong")
+	}
 }
 
 func TestExtractNodeAnnotation(t *testing.T) {
@@ -0,0 +1,135 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud_provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+)
+
+const (
+	blbStatusAvailable = "available"
+	blbStatusCreating  = "creating"
+
+	// waitRetryAfter is how long the service is requeued after after a WaitTimeoutError
+	waitRetryAfter = 10 * time.Second
+)
+
+// waitBackoff polls about 31s before giving up, the caller returns a WaitTimeoutError
+// to be requeued rather than blocking the worker longer
+var waitBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    5,
+}
+
+// WaitTimeoutError is returned when a resource does not reach the expected state in time
+type WaitTimeoutError struct {
+	Resource string
+	ID       string
+	Expected string
+	Actual   string
+}
+
+func (e *WaitTimeoutError) Error() string {
+	return fmt.Sprintf("timeout waiting for %s %s to be %s, current status is %s", e.Resource, e.ID, e.Expected, e.Actual)
+}
+
+// RetryAfter returns when to retry, the service controller requeues the service after it
+func (e *WaitTimeoutError) RetryAfter() time.Duration {
+	return waitRetryAfter
+}
+
+// IsWaitTimeoutError returns whether err is or wraps a WaitTimeoutError
+func IsWaitTimeoutError(err error) bool {
+	var timeoutErr *WaitTimeoutError
+	return errors.As(err, &timeoutErr)
+}
+
+// waitForState polls getState with exponential backoff until it returns expected.
+// It returns a WaitTimeoutError when backoff is exhausted or the deadline of ctx is exceeded,
+// and the error of ctx when ctx is canceled.
+func waitForState(ctx context.Context, backoff wait.Backoff, resource, id, expected string, getState func() (string, error)) error {
+	actual := ""
+	for {
+		state, err := getState()
+		if err != nil {
+			return err
+		}
+		actual = state
+		if state == expected {
+			return nil
+		}
+		if backoff.Steps <= 0 {
+			return &WaitTimeoutError{Resource: resource, ID: id, Expected: expected, Actual: actual}
+		}
+		delay := backoff.Step()
+		klog.Infof(Message(ctx, fmt.Sprintf("%s %s status is %s, wait %v for %s", resource, id, state, delay, expected)))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if ctx.Err() == context.DeadlineExceeded {
+				return &WaitTimeoutError{Resource: resource, ID: id, Expected: expected, Actual: actual}
+			}
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// waitForBLBAvailable waits for the BLB to be available and returns it
+func (bc *Baiducloud) waitForBLBAvailable(ctx context.Context, id string) (*blb.LoadBalancer, error) {
+	var lb *blb.LoadBalancer
+	err := waitForState(ctx, waitBackoff, "BLB", id, blbStatusAvailable, func() (string, error) {
+		result, exist, err := bc.getBLBByID(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		if !exist {
+			return "", fmt.Errorf("BLB %s not exist", id)
+		}
+		lb = result
+		return lb.Status, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lb, nil
+}
+
+// waitForEIPAvailable waits for the EIP to be available, which means it is neither in progress nor bound
+func (bc *Baiducloud) waitForEIPAvailable(ctx context.Context, ip string) error {
+	return waitForState(ctx, waitBackoff, "EIP", ip, eip.EIPAvailable, func() (string, error) {
+		eips, err := bc.getEipByIP(ctx, ip)
+		if err != nil {
+			return "", err
+		}
+		if len(eips) == 0 {
+			return "", fmt.Errorf("EIP %s not exist", ip)
+		}
+		return eips[0].Status, nil
+	})
+}
@@ -0,0 +1,74 @@
+package cloud_provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestWaitForState(t *testing.T) {
+	backoff := wait.Backoff{Duration: time.Millisecond, Factor: 2, Steps: 3}
+
+	// reach the expected state
+	count := 0
+	err := waitForState(context.Background(), backoff, "BLB", "lb-1", "available", func() (string, error) {
+		count++
+		if count < 3 {
+			return "creating", nil
+		}
+		return "available", nil
+	})
+	if err != nil {
+		t.Fatalf("waitForState failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 polls, got %d", count)
+	}
+
+	// backoff exhausted
+	err = waitForState(context.Background(), backoff, "BLB", "lb-1", "available", func() (string, error) {
+		return "creating", nil
+	})
+	if !IsWaitTimeoutError(err) {
+		t.Fatalf("expected WaitTimeoutError, got %v", err)
+	}
+	if !IsWaitTimeoutError(fmt.Errorf("wrapped: %w", err)) {
+		t.Errorf("expected wrapped WaitTimeoutError to be detected")
+	}
+	if err.(*WaitTimeoutError).Actual != "creating" || err.(*WaitTimeoutError).RetryAfter() <= 0 {
+		t.Errorf("unexpected WaitTimeoutError %+v", err)
+	}
+
+	// error of getState is returned as is
+	err = waitForState(context.Background(), backoff, "EIP", "1.1.1.1", "available", func() (string, error) {
+		return "", fmt.Errorf("not exist")
+	})
+	if err == nil || IsWaitTimeoutError(err) {
+		t.Errorf("expected error of getState, got %v", err)
+	}
+
+	longBackoff := wait.Backoff{Duration: time.Hour, Factor: 1, Steps: 3}
+
+	// deadline exceeded
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = waitForState(ctx, longBackoff, "EIP", "1.1.1.1", "available", func() (string, error) {
+		return "binding", nil
+	})
+	if !IsWaitTimeoutError(err) {
+		t.Errorf("expected WaitTimeoutError on deadline, got %v", err)
+	}
+
+	// canceled
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	err = waitForState(ctx, longBackoff, "EIP", "1.1.1.1", "available", func() (string, error) {
+		return "binding", nil
+	})
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
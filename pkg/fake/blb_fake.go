@@ -7,25 +7,40 @@ import (
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/util"
+	tempblb "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
 )
 
 // FakeClient implement of vpc.Interface
 type BlbFakeClient struct {
 	LoadBalancerMap  map[string]blb.LoadBalancer
-	TCPListenerMap   map[string][]blb.TCPListener
-	UDPListenerMap   map[string][]blb.UDPListener
-	HTTPListenerMap  map[string][]blb.HTTPListener
+	TCPListenerMap   map[string][]tempblb.TCPListener
+	UDPListenerMap   map[string][]tempblb.UDPListener
+	HTTPListenerMap  map[string][]tempblb.HTTPListener
+	HTTPSListenerMap map[string][]tempblb.HTTPSListener
 	BackendServerMap map[string][]blb.BackendServer
+	// LoadBalancerId | SecurityGroupIds
+	SecurityGroupMap map[string][]string
+	// LoadBalancerId/ListenerPort/ListenerType | BackendIPs
+	BackendIPMap map[string][]tempblb.BackendIP
+	// ClientToken | LoadBalancerId
+	ClientTokenMap map[string]string
+	// LoadBalancerId | VpcID
+	VpcIDMap map[string]string
 }
 
 // NewFakeClient for VPC fake client
 func NewBlbFakeClient() *BlbFakeClient {
 	return &BlbFakeClient{
 		LoadBalancerMap:  map[string]blb.LoadBalancer{},
-		TCPListenerMap:   map[string][]blb.TCPListener{},
-		UDPListenerMap:   map[string][]blb.UDPListener{},
-		HTTPListenerMap:  map[string][]blb.HTTPListener{},
+		TCPListenerMap:   map[string][]tempblb.TCPListener{},
+		UDPListenerMap:   map[string][]tempblb.UDPListener{},
+		HTTPListenerMap:  map[string][]tempblb.HTTPListener{},
+		HTTPSListenerMap: map[string][]tempblb.HTTPSListener{},
 		BackendServerMap: map[string][]blb.BackendServer{},
+		SecurityGroupMap: map[string][]string{},
+		BackendIPMap:     map[string][]tempblb.BackendIP{},
+		ClientTokenMap:   map[string]string{},
+		VpcIDMap:         map[string]string{},
 	}
 }
 
@@ -35,6 +50,13 @@ func (f *BlbFakeClient) DescribeLoadBalancers(ctx context.Context, args *blb.Des
 		return nil, fmt.Errorf("args is nil")
 	}
 	loadbalancers := []blb.LoadBalancer{}
+	// Return all LoadBalancers
+	if args.LoadBalancerId == "" && args.LoadBalancerName == "" && args.Address == "" {
+		for _, LoadBalancer := range f.LoadBalancerMap {
+			loadbalancers = append(loadbalancers, LoadBalancer)
+		}
+		return loadbalancers, nil
+	}
 	for loadBalancerID, LoadBalancer := range f.LoadBalancerMap {
 		if loadBalancerID != "" && args.LoadBalancerId != "" && loadBalancerID == args.LoadBalancerId ||
 			LoadBalancer.Name != "" && args.LoadBalancerName != "" && LoadBalancer.Name == args.LoadBalancerName ||
@@ -51,6 +73,16 @@ func (f *BlbFakeClient) CreateLoadBalancer(ctx context.Context, args *blb.Create
 	if args == nil {
 		return nil, fmt.Errorf("args is nil")
 	}
+	// requests with the same client token return the same LoadBalancer
+	if lb, ok := f.LoadBalancerMap[f.ClientTokenMap[args.ClientToken]]; ok && args.ClientToken != "" {
+		id := lb.BlbId
+		return &blb.CreateLoadBalancerResponse{
+			Address:        lb.Address,
+			Desc:           lb.Desc,
+			LoadBalancerId: id,
+			Name:           lb.Name,
+		}, nil
+	}
 	resp := &blb.CreateLoadBalancerResponse{
 		Desc:    args.Desc,
 		Name:    args.Name,
@@ -68,6 +100,10 @@ func (f *BlbFakeClient) CreateLoadBalancer(ctx context.Context, args *blb.Create
 			loadbalancer.BlbId = loadbalancerID
 			resp.LoadBalancerId = loadbalancerID
 			f.LoadBalancerMap[loadbalancerID] = loadbalancer
+			f.VpcIDMap[loadbalancerID] = args.VpcID
+			if args.ClientToken != "" {
+				f.ClientTokenMap[args.ClientToken] = loadbalancerID
+			}
 			break
 		}
 	}
@@ -100,12 +136,13 @@ func (f *BlbFakeClient) DeleteLoadBalancer(ctx context.Context, args *blb.Delete
 	return fmt.Errorf("LoadBalancerId does not exist")
 }
 
-// Listenr fake func
+// Listenr fake func, TCP and UDP listeners are kept as temp-blb ones by the listener fake client
 func (f *BlbFakeClient) CreateTCPListener(ctx context.Context, args *blb.CreateTCPListenerArgs, option *bce.SignOption) (err error) {
 	if args == nil {
 		return fmt.Errorf("args is nil")
 	}
-	tcp := blb.TCPListener{
+	tcp := tempblb.CreateTCPListenerArgs{
+		LoadBalancerId:             args.LoadBalancerId,
 		ListenerPort:               args.ListenerPort,
 		BackendPort:                args.BackendPort,
 		Scheduler:                  args.Scheduler,
@@ -114,55 +151,38 @@ func (f *BlbFakeClient) CreateTCPListener(ctx context.Context, args *blb.CreateT
 		UnhealthyThreshold:         args.UnhealthyThreshold,
 		HealthyThreshold:           args.HealthyThreshold,
 	}
-	// check LoadBalancerId if nil
-	argsLb := &blb.DescribeLoadBalancersArgs{
-		LoadBalancerId: args.LoadBalancerId,
-	}
-	lbs, err := f.DescribeLoadBalancers(ctx, argsLb, nil)
-	if err != nil || len(lbs) == 0 {
-		return fmt.Errorf("can not get lb according to args’ BlbID err: %v", err)
-	}
-	f.TCPListenerMap[args.LoadBalancerId] = append(f.TCPListenerMap[args.LoadBalancerId], tcp)
-	return nil
+	return NewBlbListenerFakeClient(f).CreateTCPListener(ctx, &tcp, option)
 }
 func (f *BlbFakeClient) CreateUDPListener(ctx context.Context, args *blb.CreateUDPListenerArgs, option *bce.SignOption) (err error) {
 	if args == nil {
 		return fmt.Errorf("args is nil")
 	}
-	udp := blb.UDPListener{
-		ListenerPort:               args.ListenerPort,
-		BackendPort:                args.BackendPort,
-		Scheduler:                  args.Scheduler,
-		HealthCheckTimeoutInSecond: args.HealthCheckTimeoutInSecond,
-		HealthCheckInterval:        args.HealthCheckInterval,
-		UnhealthyThreshold:         args.UnhealthyThreshold,
-		HealthyThreshold:           args.HealthyThreshold,
-		HealthCheckString:          args.HealthCheckString,
-	}
-	// check LoadBalancerId if nil
-	// check LoadBalancerId if nil
-	argsLb := &blb.DescribeLoadBalancersArgs{
-		LoadBalancerId: args.LoadBalancerId,
-	}
-	lbs, err := f.DescribeLoadBalancers(ctx, argsLb, nil)
-	if err != nil || len(lbs) == 0 {
-		return fmt.Errorf("can not get lb according to args’ BlbID err: %v", err)
-	}
-	f.UDPListenerMap[args.LoadBalancerId] = append(f.UDPListenerMap[args.LoadBalancerId], udp)
-	return nil
+	udp := tempblb.CreateUDPListenerArgs{
+		CreateTCPListenerArgs: tempblb.CreateTCPListenerArgs{
+			LoadBalancerId:             args.LoadBalancerId,
+			ListenerPort:               args.ListenerPort,
+			BackendPort:                args.BackendPort,
+			Scheduler:                  args.Scheduler,
+			HealthCheckTimeoutInSecond: args.HealthCheckTimeoutInSecond,
+			HealthCheckInterval:        args.HealthCheckInterval,
+			UnhealthyThreshold:         args.UnhealthyThreshold,
+			HealthyThreshold:           args.HealthyThreshold,
+		},
+		HealthCheckString: args.HealthCheckString,
+	}
+	return NewBlbListenerFakeClient(f).CreateUDPListener(ctx, &udp, option)
 }
 func (f *BlbFakeClient) CreateHTTPListener(ctx context.Context, args *blb.CreateHTTPListenerArgs, option *bce.SignOption) (err error) {
 	if args == nil {
 		return fmt.Errorf("args is nil")
 	}
-	http := blb.HTTPListener{
+	http := tempblb.HTTPListener{
 		ListenerPort:               args.ListenerPort,
 		BackendPort:                args.BackendPort,
 		Scheduler:                  args.Scheduler,
 		KeepSession:                args.KeepSession,
 		KeepSessionType:            args.KeepSessionType,
 		KeepSessionDuration:        args.KeepSessionDuration,
-		KeepSessionCookieName:      args.KeepSessionCookieName,
 		XForwardFor:                args.XForwardFor,
 		HealthCheckType:            args.HealthCheckType,
 		HealthCheckURI:             args.HealthCheckURI,
@@ -180,88 +200,89 @@ func (f *BlbFakeClient) DescribeTCPListener(ctx context.Context, args *blb.Descr
 	if args == nil {
 		return nil, fmt.Errorf("args is nil")
 	}
+	describeArgs := tempblb.DescribeTCPListenerArgs{
+		LoadBalancerId: args.LoadBalancerId,
+		ListenerPort:   args.ListenerPort,
+	}
+	listeners, err := NewBlbListenerFakeClient(f).DescribeTCPListener(ctx, &describeArgs, option)
+	if err != nil {
+		return nil, err
+	}
 	tcpListeners := []blb.TCPListener{}
-	if _, ok := f.TCPListenerMap[args.LoadBalancerId]; ok {
-		for _, t := range f.TCPListenerMap[args.LoadBalancerId] {
-			tcpListeners = append(tcpListeners, t)
-		}
-		return tcpListeners, nil
+	for _, t := range listeners {
+		tcpListeners = append(tcpListeners, blb.TCPListener{
+			ListenerPort:               t.ListenerPort,
+			BackendPort:                t.BackendPort,
+			Scheduler:                  t.Scheduler,
+			HealthCheckTimeoutInSecond: t.HealthCheckTimeoutInSecond,
+			HealthCheckInterval:        t.HealthCheckInterval,
+			UnhealthyThreshold:         t.UnhealthyThreshold,
+			HealthyThreshold:           t.HealthyThreshold,
+		})
 	}
-	return nil, fmt.Errorf("DescribeTCPListener failed, can not get tcpListeners from args %v", args)
+	return tcpListeners, nil
 }
 func (f *BlbFakeClient) DescribeUDPListener(ctx context.Context, args *blb.DescribeUDPListenerArgs, option *bce.SignOption) ([]blb.UDPListener, error) {
 	if args == nil {
 		return nil, fmt.Errorf("DescribeUDPListeners need args")
 	}
-	if args.LoadBalancerId == "" {
-		return nil, fmt.Errorf("DescribeUDPListeners args need loadbalancerId")
+	describeArgs := tempblb.DescribeUDPListenerArgs{
+		LoadBalancerId: args.LoadBalancerId,
+		ListenerPort:   args.ListenerPort,
 	}
-	udpListenerList, found := f.UDPListenerMap[args.LoadBalancerId]
-	if !found {
-		return nil, fmt.Errorf("Sepcified BLB %s not found", args.LoadBalancerId)
+	listeners, err := NewBlbListenerFakeClient(f).DescribeUDPListener(ctx, &describeArgs, option)
+	if err != nil {
+		return nil, err
 	}
 	result := make([]blb.UDPListener, 0)
-	for _, u := range udpListenerList {
-		if args.ListenerPort != 0 && u.ListenerPort != args.ListenerPort {
-			continue
-		}
-		result = append(result, u)
+	for _, u := range listeners {
+		result = append(result, blb.UDPListener{
+			ListenerPort:               u.ListenerPort,
+			BackendPort:                u.BackendPort,
+			Scheduler:                  u.Scheduler,
+			HealthCheckTimeoutInSecond: u.HealthCheckTimeoutInSecond,
+			HealthCheckInterval:        u.HealthCheckInterval,
+			UnhealthyThreshold:         u.UnhealthyThreshold,
+			HealthyThreshold:           u.HealthyThreshold,
+			HealthCheckString:          u.HealthCheckString,
+		})
 	}
 	return result, nil
 }
 func (f *BlbFakeClient) UpdateTCPListener(ctx context.Context, args *blb.UpdateTCPListenerArgs, option *bce.SignOption) error {
-	if args == nil || args.LoadBalancerId == "" || args.ListenerPort == 0 {
+	if args == nil {
 		return fmt.Errorf("UpdateTCPListener need args")
 	}
-	rawTcpList, found := f.TCPListenerMap[args.LoadBalancerId]
-	if !found {
-		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
-	}
-	tcpList := make([]blb.TCPListener, 0)
-	for _, t := range rawTcpList {
-		if t.ListenerPort != args.ListenerPort {
-			tcpList = append(tcpList, t)
-		}
-	}
-	newTcpListner := blb.TCPListener{
+	tcp := tempblb.UpdateTCPListenerArgs{
+		LoadBalancerId:             args.LoadBalancerId,
 		ListenerPort:               args.ListenerPort,
-		HealthCheckInterval:        args.HealthCheckInterval,
-		HealthCheckTimeoutInSecond: args.HealthCheckTimeoutInSecond,
-		HealthyThreshold:           args.HealthyThreshold,
-		UnhealthyThreshold:         args.UnhealthyThreshold,
 		BackendPort:                args.BackendPort,
 		Scheduler:                  args.Scheduler,
-	}
-	tcpList = append(tcpList, newTcpListner)
-	return nil
-}
-func (f *BlbFakeClient) UpdateUDPListener(ctx context.Context, args *blb.UpdateUDPListenerArgs, option *bce.SignOption) error {
-	err := validateUpdateUDPListenerArgs(args)
-	if err != nil {
-		return err
-	}
-	rawUdpList, found := f.UDPListenerMap[args.LoadBalancerId]
-	if !found {
-		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
-	}
-	udpList := make([]blb.UDPListener, 0)
-	for _, u := range rawUdpList {
-		if u.ListenerPort != args.ListenerPort {
-			udpList = append(udpList, u)
-		}
-	}
-	newUdpListener := blb.UDPListener{
-		ListenerPort:               args.ListenerPort,
-		HealthCheckInterval:        args.HealthCheckInterval,
-		HealthCheckString:          args.HealthCheckString,
 		HealthCheckTimeoutInSecond: args.HealthCheckTimeoutInSecond,
-		HealthyThreshold:           args.HealthyThreshold,
+		HealthCheckInterval:        args.HealthCheckInterval,
 		UnhealthyThreshold:         args.UnhealthyThreshold,
-		BackendPort:                args.BackendPort,
-		Scheduler:                  args.Scheduler,
+		HealthyThreshold:           args.HealthyThreshold,
 	}
-	udpList = append(udpList, newUdpListener)
-	return nil
+	return NewBlbListenerFakeClient(f).UpdateTCPListener(ctx, &tcp, option)
+}
+func (f *BlbFakeClient) UpdateUDPListener(ctx context.Context, args *blb.UpdateUDPListenerArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("UpdateUDPListener need args")
+	}
+	udp := tempblb.UpdateUDPListenerArgs{
+		UpdateTCPListenerArgs: tempblb.UpdateTCPListenerArgs{
+			LoadBalancerId:             args.LoadBalancerId,
+			ListenerPort:               args.ListenerPort,
+			BackendPort:                args.BackendPort,
+			Scheduler:                  args.Scheduler,
+			HealthCheckTimeoutInSecond: args.HealthCheckTimeoutInSecond,
+			HealthCheckInterval:        args.HealthCheckInterval,
+			UnhealthyThreshold:         args.UnhealthyThreshold,
+			HealthyThreshold:           args.HealthyThreshold,
+		},
+		HealthCheckString: args.HealthCheckString,
+	}
+	return NewBlbListenerFakeClient(f).UpdateUDPListener(ctx, &udp, option)
 }
 func (f *BlbFakeClient) DeleteListeners(ctx context.Context, args *blb.DeleteListenersArgs, option *bce.SignOption) error {
 	err := validateDeleteListenersArgs(args)
@@ -273,41 +294,67 @@ func (f *BlbFakeClient) DeleteListeners(ctx context.Context, args *blb.DeleteLis
 	for _, p := range args.PortList {
 		listenerToRemove[p] = p
 	}
+	f.removeListeners(args.LoadBalancerId, func(port int, listenerType string) bool {
+		_, in := listenerToRemove[port]
+		return in
+	})
+	return nil
+}
+
+// help func, removes the listeners of BLB which toRemove returns true for, with their backend ips
+func (f *BlbFakeClient) removeListeners(loadBalancerID string, toRemove func(port int, listenerType string) bool) {
 	// tcp
-	rawTcpList, found := f.TCPListenerMap[args.LoadBalancerId]
+	rawTcpList, found := f.TCPListenerMap[loadBalancerID]
 	if found {
-		tcpList := make([]blb.TCPListener, 0)
+		tcpList := make([]tempblb.TCPListener, 0)
 		for _, t := range rawTcpList {
-			if _, in := listenerToRemove[t.ListenerPort]; !in {
-				tcpList = append(tcpList, t)
+			if toRemove(t.ListenerPort, "TCP") {
+				delete(f.BackendIPMap, backendIPKey(loadBalancerID, t.ListenerPort, "TCP"))
+				continue
 			}
+			tcpList = append(tcpList, t)
 		}
-		f.TCPListenerMap[args.LoadBalancerId] = tcpList
+		f.TCPListenerMap[loadBalancerID] = tcpList
 	}
 	// udp
-	rawUdpList, found := f.UDPListenerMap[args.LoadBalancerId]
+	rawUdpList, found := f.UDPListenerMap[loadBalancerID]
 	if found {
-		udpList := make([]blb.UDPListener, 0)
+		udpList := make([]tempblb.UDPListener, 0)
 		for _, u := range rawUdpList {
-			if _, in := listenerToRemove[u.ListenerPort]; !in {
-				udpList = append(udpList, u)
+			if toRemove(u.ListenerPort, "UDP") {
+				delete(f.BackendIPMap, backendIPKey(loadBalancerID, u.ListenerPort, "UDP"))
+				continue
 			}
+			udpList = append(udpList, u)
 		}
-		f.UDPListenerMap[args.LoadBalancerId] = udpList
+		f.UDPListenerMap[loadBalancerID] = udpList
 	}
 	// http
-	rawHttpList, found := f.HTTPListenerMap[args.LoadBalancerId]
+	rawHttpList, found := f.HTTPListenerMap[loadBalancerID]
 	if found {
-		httpList := make([]blb.HTTPListener, 0)
+		httpList := make([]tempblb.HTTPListener, 0)
 		for _, h := range rawHttpList {
-			if _, in := listenerToRemove[h.ListenerPort]; !in {
-				httpList = append(httpList, h)
+			if toRemove(h.ListenerPort, "HTTP") {
+				delete(f.BackendIPMap, backendIPKey(loadBalancerID, h.ListenerPort, "HTTP"))
+				continue
 			}
+			httpList = append(httpList, h)
 		}
-		f.HTTPListenerMap[args.LoadBalancerId] = httpList
+		f.HTTPListenerMap[loadBalancerID] = httpList
+	}
+	// https
+	rawHttpsList, found := f.HTTPSListenerMap[loadBalancerID]
+	if found {
+		httpsList := make([]tempblb.HTTPSListener, 0)
+		for _, h := range rawHttpsList {
+			if toRemove(h.ListenerPort, "HTTPS") {
+				delete(f.BackendIPMap, backendIPKey(loadBalancerID, h.ListenerPort, "HTTPS"))
+				continue
+			}
+			httpsList = append(httpsList, h)
+		}
+		f.HTTPSListenerMap[loadBalancerID] = httpsList
 	}
-	// TODO: https
-	return nil
 }
 
 // backendserver fake func
@@ -392,7 +439,8 @@ func (f *BlbFakeClient) RemoveBackendServers(ctx context.Context, args *blb.Remo
 	f.BackendServerMap[args.LoadBalancerId] = leftRs
 	return nil
 }
-func validateUpdateUDPListenerArgs(args *blb.UpdateUDPListenerArgs) error {
+
+func validateUpdateUDPListenerArgs(args *tempblb.UpdateUDPListenerArgs) error {
 	if args.LoadBalancerId == "" {
 		return fmt.Errorf("UpdateUDPListener need LoadBalancerId")
 	}
@@ -0,0 +1,81 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/blb"
+	tempblb "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
+)
+
+// BlbAddressFakeClient for unit test, BLBs are created in BLBClient with the address specified and described with their VPC
+type BlbAddressFakeClient struct {
+	BLBClient *BlbFakeClient
+}
+
+// NewBlbAddressFakeClient for BLB address fake client
+func NewBlbAddressFakeClient(blbClient *BlbFakeClient) *BlbAddressFakeClient {
+	return &BlbAddressFakeClient{
+		BLBClient: blbClient,
+	}
+}
+
+// CreateLoadBalancer create BLB in BLBClient
+func (f *BlbAddressFakeClient) CreateLoadBalancer(ctx context.Context, args *tempblb.CreateLoadBalancerArgs, option *bce.SignOption) (*tempblb.CreateLoadBalancerResponse, error) {
+	if args == nil {
+		return nil, fmt.Errorf("CreateLoadBalancer failed: args is nil")
+	}
+	if lb, ok := f.BLBClient.LoadBalancerMap[f.BLBClient.ClientTokenMap[args.ClientToken]]; !ok || args.ClientToken == "" {
+		for _, lb := range f.BLBClient.LoadBalancerMap {
+			if args.Address != "" && lb.Address == args.Address && f.BLBClient.VpcIDMap[lb.BlbId] == args.VpcID {
+				return nil, fmt.Errorf("CreateLoadBalancer failed: address %s is used by %s", args.Address, lb.BlbId)
+			}
+		}
+	} else if lb.Address != args.Address {
+		return nil, fmt.Errorf("CreateLoadBalancer failed: client token is used by %s", lb.BlbId)
+	}
+	resp, err := f.BLBClient.CreateLoadBalancer(ctx, &blb.CreateLoadBalancerArgs{
+		Name:        args.Name,
+		Desc:        args.Desc,
+		VpcID:       args.VpcID,
+		SubnetID:    args.SubnetID,
+		AllocateVIP: args.AllocateVIP,
+		ClientToken: args.ClientToken,
+	}, option)
+	if err != nil {
+		return nil, err
+	}
+	lb := f.BLBClient.LoadBalancerMap[resp.LoadBalancerId]
+	lb.Address = args.Address
+	f.BLBClient.LoadBalancerMap[resp.LoadBalancerId] = lb
+	return &tempblb.CreateLoadBalancerResponse{
+		Address:        args.Address,
+		Desc:           resp.Desc,
+		LoadBalancerId: resp.LoadBalancerId,
+		Name:           resp.Name,
+	}, nil
+}
+
+// DescribeLoadBalancers describe BLBs in BLBClient with the VPC they are created in
+func (f *BlbAddressFakeClient) DescribeLoadBalancers(ctx context.Context, args *tempblb.DescribeLoadBalancersArgs, option *bce.SignOption) ([]tempblb.LoadBalancer, error) {
+	if args == nil {
+		return nil, fmt.Errorf("DescribeLoadBalancers failed: args is nil")
+	}
+	var result []tempblb.LoadBalancer
+	for id, lb := range f.BLBClient.LoadBalancerMap {
+		if args.Address != "" && lb.Address != args.Address {
+			continue
+		}
+		result = append(result, tempblb.LoadBalancer{
+			BlbId:    lb.BlbId,
+			Name:     lb.Name,
+			Status:   lb.Status,
+			Desc:     lb.Desc,
+			Address:  lb.Address,
+			PublicIp: lb.PublicIp,
+			VpcID:    f.BLBClient.VpcIDMap[id],
+		})
+	}
+	return result, nil
+}
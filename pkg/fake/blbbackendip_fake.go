@@ -0,0 +1,82 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	tempblb "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
+)
+
+// BlbBackendIPFakeClient for unit test, backend ips of listeners are kept in BLBClient
+type BlbBackendIPFakeClient struct {
+	BLBClient *BlbFakeClient
+}
+
+// NewBlbBackendIPFakeClient for BLB backend ip fake client
+func NewBlbBackendIPFakeClient(blbClient *BlbFakeClient) *BlbBackendIPFakeClient {
+	return &BlbBackendIPFakeClient{
+		BLBClient: blbClient,
+	}
+}
+
+// help func
+func backendIPKey(loadBalancerID string, listenerPort int, listenerType string) string {
+	return fmt.Sprintf("%s/%d/%s", loadBalancerID, listenerPort, listenerType)
+}
+
+// DescribeBackendIPs fake func
+func (f *BlbBackendIPFakeClient) DescribeBackendIPs(ctx context.Context, args *tempblb.DescribeBackendIPsArgs, option *bce.SignOption) ([]tempblb.BackendIP, error) {
+	if args == nil || args.LoadBalancerId == "" || args.ListenerPort == 0 || args.ListenerType == "" {
+		return nil, fmt.Errorf("DescribeBackendIPs need args")
+	}
+	if _, found := f.BLBClient.LoadBalancerMap[args.LoadBalancerId]; !found {
+		return nil, fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	result := make([]tempblb.BackendIP, 0)
+	result = append(result, f.BLBClient.BackendIPMap[backendIPKey(args.LoadBalancerId, args.ListenerPort, args.ListenerType)]...)
+	return result, nil
+}
+
+// AddBackendIPs fake func
+func (f *BlbBackendIPFakeClient) AddBackendIPs(ctx context.Context, args *tempblb.BackendIPsArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" || args.ListenerPort == 0 || args.ListenerType == "" || len(args.BackendIPList) == 0 {
+		return fmt.Errorf("AddBackendIPs need args")
+	}
+	if _, found := f.BLBClient.LoadBalancerMap[args.LoadBalancerId]; !found {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	key := backendIPKey(args.LoadBalancerId, args.ListenerPort, args.ListenerType)
+	for _, ip := range args.BackendIPList {
+		for _, existing := range f.BLBClient.BackendIPMap[key] {
+			if existing.Ip == ip.Ip && existing.Port == ip.Port {
+				return fmt.Errorf("BackendIP %s:%d already exists", ip.Ip, ip.Port)
+			}
+		}
+		f.BLBClient.BackendIPMap[key] = append(f.BLBClient.BackendIPMap[key], ip)
+	}
+	return nil
+}
+
+// RemoveBackendIPs fake func
+func (f *BlbBackendIPFakeClient) RemoveBackendIPs(ctx context.Context, args *tempblb.BackendIPsArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" || args.ListenerPort == 0 || args.ListenerType == "" || len(args.BackendIPList) == 0 {
+		return fmt.Errorf("RemoveBackendIPs need args")
+	}
+	if _, found := f.BLBClient.LoadBalancerMap[args.LoadBalancerId]; !found {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	key := backendIPKey(args.LoadBalancerId, args.ListenerPort, args.ListenerType)
+	toRemove := make(map[string]bool)
+	for _, ip := range args.BackendIPList {
+		toRemove[fmt.Sprintf("%s:%d", ip.Ip, ip.Port)] = true
+	}
+	left := make([]tempblb.BackendIP, 0)
+	for _, ip := range f.BLBClient.BackendIPMap[key] {
+		if !toRemove[fmt.Sprintf("%s:%d", ip.Ip, ip.Port)] {
+			left = append(left, ip)
+		}
+	}
+	f.BLBClient.BackendIPMap[key] = left
+	return nil
+}
@@ -0,0 +1,337 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	tempblb "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
+)
+
+// BlbListenerFakeClient for unit test, listeners are kept in BLBClient
+type BlbListenerFakeClient struct {
+	BLBClient *BlbFakeClient
+}
+
+// NewBlbListenerFakeClient for BLB listener fake client
+func NewBlbListenerFakeClient(blbClient *BlbFakeClient) *BlbListenerFakeClient {
+	return &BlbListenerFakeClient{
+		BLBClient: blbClient,
+	}
+}
+
+// CreateTCPListener fake func
+func (f *BlbListenerFakeClient) CreateTCPListener(ctx context.Context, args *tempblb.CreateTCPListenerArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("args is nil")
+	}
+	if args.ListenerPort == 0 || args.BackendPort == 0 {
+		return fmt.Errorf("CreateTCPListener need ListenerPort and BackendPort")
+	}
+	if _, found := f.BLBClient.LoadBalancerMap[args.LoadBalancerId]; !found {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	f.BLBClient.TCPListenerMap[args.LoadBalancerId] = append(f.BLBClient.TCPListenerMap[args.LoadBalancerId], tcpListener(args))
+	return nil
+}
+
+// CreateUDPListener fake func
+func (f *BlbListenerFakeClient) CreateUDPListener(ctx context.Context, args *tempblb.CreateUDPListenerArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("args is nil")
+	}
+	if args.ListenerPort == 0 || args.BackendPort == 0 {
+		return fmt.Errorf("CreateUDPListener need ListenerPort and BackendPort")
+	}
+	if _, found := f.BLBClient.LoadBalancerMap[args.LoadBalancerId]; !found {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	udp := tempblb.UDPListener{
+		TCPListener:       tcpListener(&args.CreateTCPListenerArgs),
+		HealthCheckString: args.HealthCheckString,
+	}
+	f.BLBClient.UDPListenerMap[args.LoadBalancerId] = append(f.BLBClient.UDPListenerMap[args.LoadBalancerId], udp)
+	return nil
+}
+
+// CreateHTTPListener fake func
+func (f *BlbListenerFakeClient) CreateHTTPListener(ctx context.Context, args *tempblb.CreateHTTPListenerArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("args is nil")
+	}
+	if _, found := f.BLBClient.LoadBalancerMap[args.LoadBalancerId]; !found {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	f.BLBClient.HTTPListenerMap[args.LoadBalancerId] = append(f.BLBClient.HTTPListenerMap[args.LoadBalancerId], httpListener(args))
+	return nil
+}
+
+// CreateHTTPSListener fake func
+func (f *BlbListenerFakeClient) CreateHTTPSListener(ctx context.Context, args *tempblb.CreateHTTPSListenerArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("args is nil")
+	}
+	if len(args.CertIds) == 0 {
+		return fmt.Errorf("CreateHTTPSListener need CertIds")
+	}
+	if _, found := f.BLBClient.LoadBalancerMap[args.LoadBalancerId]; !found {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	https := tempblb.HTTPSListener{
+		HTTPListener: httpListener(&args.CreateHTTPListenerArgs),
+		CertIds:      args.CertIds,
+	}
+	f.BLBClient.HTTPSListenerMap[args.LoadBalancerId] = append(f.BLBClient.HTTPSListenerMap[args.LoadBalancerId], https)
+	return nil
+}
+
+// DescribeTCPListener fake func
+func (f *BlbListenerFakeClient) DescribeTCPListener(ctx context.Context, args *tempblb.DescribeTCPListenerArgs, option *bce.SignOption) ([]tempblb.TCPListener, error) {
+	if args == nil || args.LoadBalancerId == "" {
+		return nil, fmt.Errorf("DescribeTCPListener need args")
+	}
+	if _, found := f.BLBClient.LoadBalancerMap[args.LoadBalancerId]; !found {
+		return nil, fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	result := make([]tempblb.TCPListener, 0)
+	for _, t := range f.BLBClient.TCPListenerMap[args.LoadBalancerId] {
+		if args.ListenerPort != 0 && t.ListenerPort != args.ListenerPort {
+			continue
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+// DescribeUDPListener fake func
+func (f *BlbListenerFakeClient) DescribeUDPListener(ctx context.Context, args *tempblb.DescribeUDPListenerArgs, option *bce.SignOption) ([]tempblb.UDPListener, error) {
+	if args == nil || args.LoadBalancerId == "" {
+		return nil, fmt.Errorf("DescribeUDPListener need args")
+	}
+	if _, found := f.BLBClient.LoadBalancerMap[args.LoadBalancerId]; !found {
+		return nil, fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	result := make([]tempblb.UDPListener, 0)
+	for _, u := range f.BLBClient.UDPListenerMap[args.LoadBalancerId] {
+		if args.ListenerPort != 0 && u.ListenerPort != args.ListenerPort {
+			continue
+		}
+		result = append(result, u)
+	}
+	return result, nil
+}
+
+// DescribeHTTPListener fake func
+func (f *BlbListenerFakeClient) DescribeHTTPListener(ctx context.Context, args *tempblb.DescribeHTTPListenerArgs, option *bce.SignOption) ([]tempblb.HTTPListener, error) {
+	if args == nil || args.LoadBalancerId == "" {
+		return nil, fmt.Errorf("DescribeHTTPListener need args")
+	}
+	if _, found := f.BLBClient.LoadBalancerMap[args.LoadBalancerId]; !found {
+		return nil, fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	result := make([]tempblb.HTTPListener, 0)
+	for _, h := range f.BLBClient.HTTPListenerMap[args.LoadBalancerId] {
+		if args.ListenerPort != 0 && h.ListenerPort != args.ListenerPort {
+			continue
+		}
+		result = append(result, h)
+	}
+	return result, nil
+}
+
+// DescribeHTTPSListener fake func
+func (f *BlbListenerFakeClient) DescribeHTTPSListener(ctx context.Context, args *tempblb.DescribeHTTPSListenerArgs, option *bce.SignOption) ([]tempblb.HTTPSListener, error) {
+	if args == nil || args.LoadBalancerId == "" {
+		return nil, fmt.Errorf("DescribeHTTPSListener need args")
+	}
+	if _, found := f.BLBClient.LoadBalancerMap[args.LoadBalancerId]; !found {
+		return nil, fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	result := make([]tempblb.HTTPSListener, 0)
+	for _, h := range f.BLBClient.HTTPSListenerMap[args.LoadBalancerId] {
+		if args.ListenerPort != 0 && h.ListenerPort != args.ListenerPort {
+			continue
+		}
+		result = append(result, h)
+	}
+	return result, nil
+}
+
+// UpdateTCPListener fake func
+func (f *BlbListenerFakeClient) UpdateTCPListener(ctx context.Context, args *tempblb.UpdateTCPListenerArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" || args.ListenerPort == 0 {
+		return fmt.Errorf("UpdateTCPListener need args")
+	}
+	if _, found := f.BLBClient.LoadBalancerMap[args.LoadBalancerId]; !found {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	tcpList := f.BLBClient.TCPListenerMap[args.LoadBalancerId]
+	for i := range tcpList {
+		if tcpList[i].ListenerPort == args.ListenerPort {
+			updateTCPListener(&tcpList[i], args)
+			return nil
+		}
+	}
+	// listeners not found are added as the SDK fake did
+	tcp := tempblb.TCPListener{ListenerPort: args.ListenerPort}
+	updateTCPListener(&tcp, args)
+	f.BLBClient.TCPListenerMap[args.LoadBalancerId] = append(tcpList, tcp)
+	return nil
+}
+
+// UpdateUDPListener fake func
+func (f *BlbListenerFakeClient) UpdateUDPListener(ctx context.Context, args *tempblb.UpdateUDPListenerArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("UpdateUDPListener need args")
+	}
+	if err := validateUpdateUDPListenerArgs(args); err != nil {
+		return err
+	}
+	if _, found := f.BLBClient.LoadBalancerMap[args.LoadBalancerId]; !found {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	udpList := f.BLBClient.UDPListenerMap[args.LoadBalancerId]
+	for i := range udpList {
+		if udpList[i].ListenerPort != args.ListenerPort {
+			continue
+		}
+		updateTCPListener(&udpList[i].TCPListener, &args.UpdateTCPListenerArgs)
+		udpList[i].HealthCheckString = args.HealthCheckString
+		return nil
+	}
+	// listeners not found are added as the SDK fake did
+	udp := tempblb.UDPListener{
+		TCPListener:       tempblb.TCPListener{ListenerPort: args.ListenerPort},
+		HealthCheckString: args.HealthCheckString,
+	}
+	updateTCPListener(&udp.TCPListener, &args.UpdateTCPListenerArgs)
+	f.BLBClient.UDPListenerMap[args.LoadBalancerId] = append(udpList, udp)
+	return nil
+}
+
+// UpdateHTTPListener fake func
+func (f *BlbListenerFakeClient) UpdateHTTPListener(ctx context.Context, args *tempblb.UpdateHTTPListenerArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" || args.ListenerPort == 0 {
+		return fmt.Errorf("UpdateHTTPListener need args")
+	}
+	if _, found := f.BLBClient.LoadBalancerMap[args.LoadBalancerId]; !found {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	httpList := f.BLBClient.HTTPListenerMap[args.LoadBalancerId]
+	for i := range httpList {
+		if httpList[i].ListenerPort == args.ListenerPort {
+			updateHTTPListener(&httpList[i], args)
+			return nil
+		}
+	}
+	return fmt.Errorf("HTTP listener %d of BLB %s not found", args.ListenerPort, args.LoadBalancerId)
+}
+
+// UpdateHTTPSListener fake func
+func (f *BlbListenerFakeClient) UpdateHTTPSListener(ctx context.Context, args *tempblb.UpdateHTTPSListenerArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" || args.ListenerPort == 0 {
+		return fmt.Errorf("UpdateHTTPSListener need args")
+	}
+	if _, found := f.BLBClient.LoadBalancerMap[args.LoadBalancerId]; !found {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	httpsList := f.BLBClient.HTTPSListenerMap[args.LoadBalancerId]
+	for i := range httpsList {
+		if httpsList[i].ListenerPort != args.ListenerPort {
+			continue
+		}
+		updateHTTPListener(&httpsList[i].HTTPListener, &args.UpdateHTTPListenerArgs)
+		if len(args.CertIds) > 0 {
+			httpsList[i].CertIds = args.CertIds
+		}
+		return nil
+	}
+	return fmt.Errorf("HTTPS listener %d of BLB %s not found", args.ListenerPort, args.LoadBalancerId)
+}
+
+// DeleteListeners fake func
+func (f *BlbListenerFakeClient) DeleteListeners(ctx context.Context, args *tempblb.DeleteListenersArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" {
+		return fmt.Errorf("DeleteListenersArgs need LoadBalancerId")
+	}
+	if args.PortList == nil && args.PortTypeList == nil {
+		return fmt.Errorf("DeleteListenersArgs need PortList or PortTypeList")
+	}
+	ports := make(map[int]bool, len(args.PortList))
+	for _, p := range args.PortList {
+		ports[p] = true
+	}
+	portTypes := make(map[tempblb.PortTypeModel]bool, len(args.PortTypeList))
+	for _, pt := range args.PortTypeList {
+		portTypes[pt] = true
+	}
+	f.BLBClient.removeListeners(args.LoadBalancerId, func(port int, listenerType string) bool {
+		return ports[port] || portTypes[tempblb.PortTypeModel{Port: port, Type: listenerType}]
+	})
+	return nil
+}
+
+func tcpListener(args *tempblb.CreateTCPListenerArgs) tempblb.TCPListener {
+	return tempblb.TCPListener{
+		ListenerPort:               args.ListenerPort,
+		BackendPort:                args.BackendPort,
+		Scheduler:                  args.Scheduler,
+		HealthCheckType:            args.HealthCheckType,
+		HealthCheckPort:            args.HealthCheckPort,
+		HealthCheckURI:             args.HealthCheckURI,
+		HealthCheckTimeoutInSecond: args.HealthCheckTimeoutInSecond,
+		HealthCheckInterval:        args.HealthCheckInterval,
+		UnhealthyThreshold:         args.UnhealthyThreshold,
+		HealthyThreshold:           args.HealthyThreshold,
+	}
+}
+
+func updateTCPListener(listener *tempblb.TCPListener, args *tempblb.UpdateTCPListenerArgs) {
+	listener.BackendPort = args.BackendPort
+	listener.Scheduler = args.Scheduler
+	listener.HealthCheckTimeoutInSecond = args.HealthCheckTimeoutInSecond
+	listener.HealthCheckInterval = args.HealthCheckInterval
+	listener.UnhealthyThreshold = args.UnhealthyThreshold
+	listener.HealthyThreshold = args.HealthyThreshold
+	listener.HealthCheckType = args.HealthCheckType
+	listener.HealthCheckPort = args.HealthCheckPort
+	listener.HealthCheckURI = args.HealthCheckURI
+}
+
+func httpListener(args *tempblb.CreateHTTPListenerArgs) tempblb.HTTPListener {
+	return tempblb.HTTPListener{
+		ListenerPort:               args.ListenerPort,
+		BackendPort:                args.BackendPort,
+		Scheduler:                  args.Scheduler,
+		KeepSession:                args.KeepSession,
+		KeepSessionType:            args.KeepSessionType,
+		KeepSessionDuration:        args.KeepSessionDuration,
+		KeepSessionCookieName:      args.KeepSessionCookieName,
+		XForwardFor:                args.XForwardFor,
+		HealthCheckType:            args.HealthCheckType,
+		HealthCheckPort:            args.HealthCheckPort,
+		HealthCheckURI:             args.HealthCheckURI,
+		HealthCheckTimeoutInSecond: args.HealthCheckTimeoutInSecond,
+		HealthCheckInterval:        args.HealthCheckInterval,
+		UnhealthyThreshold:         args.UnhealthyThreshold,
+		HealthyThreshold:           args.HealthyThreshold,
+		HealthCheckNormalStatus:    args.HealthCheckNormalStatus,
+		ServerTimeout:              args.ServerTimeout,
+		RedirectPort:               args.RedirectPort,
+	}
+}
+
+func updateHTTPListener(listener *tempblb.HTTPListener, args *tempblb.UpdateHTTPListenerArgs) {
+	listener.BackendPort = args.BackendPort
+	listener.Scheduler = args.Scheduler
+	listener.KeepSession = args.KeepSession
+	listener.KeepSessionType = args.KeepSessionType
+	listener.KeepSessionDuration = args.KeepSessionDuration
+	listener.XForwardFor = args.XForwardFor
+	listener.HealthCheckTimeoutInSecond = args.HealthCheckTimeoutInSecond
+	listener.UnhealthyThreshold = args.UnhealthyThreshold
+	listener.HealthyThreshold = args.HealthyThreshold
+	listener.HealthCheckType = args.HealthCheckType
+	listener.HealthCheckPort = args.HealthCheckPort
+	listener.HealthCheckURI = args.HealthCheckURI
+}
@@ -0,0 +1,80 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	tempblb "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-blb"
+)
+
+// BlbSecurityGroupFakeClient for unit test, security groups bound to BLBs are kept in BLBClient
+type BlbSecurityGroupFakeClient struct {
+	BLBClient *BlbFakeClient
+}
+
+// NewBlbSecurityGroupFakeClient for BLB security group fake client
+func NewBlbSecurityGroupFakeClient(blbClient *BlbFakeClient) *BlbSecurityGroupFakeClient {
+	return &BlbSecurityGroupFakeClient{
+		BLBClient: blbClient,
+	}
+}
+
+// DescribeSecurityGroups fake func
+func (f *BlbSecurityGroupFakeClient) DescribeSecurityGroups(ctx context.Context, args *tempblb.DescribeSecurityGroupsArgs, option *bce.SignOption) ([]tempblb.SecurityGroup, error) {
+	if args == nil || args.LoadBalancerId == "" {
+		return nil, fmt.Errorf("DescribeSecurityGroups need args")
+	}
+	if _, found := f.BLBClient.LoadBalancerMap[args.LoadBalancerId]; !found {
+		return nil, fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	result := make([]tempblb.SecurityGroup, 0)
+	for _, id := range f.BLBClient.SecurityGroupMap[args.LoadBalancerId] {
+		result = append(result, tempblb.SecurityGroup{
+			SecurityGroupId: id,
+		})
+	}
+	return result, nil
+}
+
+// BindSecurityGroups fake func
+func (f *BlbSecurityGroupFakeClient) BindSecurityGroups(ctx context.Context, args *tempblb.SecurityGroupsArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" || len(args.SecurityGroupIds) == 0 {
+		return fmt.Errorf("BindSecurityGroups need args")
+	}
+	if _, found := f.BLBClient.LoadBalancerMap[args.LoadBalancerId]; !found {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	bound := make(map[string]bool)
+	for _, id := range f.BLBClient.SecurityGroupMap[args.LoadBalancerId] {
+		bound[id] = true
+	}
+	for _, id := range args.SecurityGroupIds {
+		if !bound[id] {
+			f.BLBClient.SecurityGroupMap[args.LoadBalancerId] = append(f.BLBClient.SecurityGroupMap[args.LoadBalancerId], id)
+		}
+	}
+	return nil
+}
+
+// UnbindSecurityGroups fake func
+func (f *BlbSecurityGroupFakeClient) UnbindSecurityGroups(ctx context.Context, args *tempblb.SecurityGroupsArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" || len(args.SecurityGroupIds) == 0 {
+		return fmt.Errorf("UnbindSecurityGroups need args")
+	}
+	if _, found := f.BLBClient.LoadBalancerMap[args.LoadBalancerId]; !found {
+		return fmt.Errorf("Specified BLB %s not found", args.LoadBalancerId)
+	}
+	toUnbind := make(map[string]bool)
+	for _, id := range args.SecurityGroupIds {
+		toUnbind[id] = true
+	}
+	left := make([]string, 0)
+	for _, id := range f.BLBClient.SecurityGroupMap[args.LoadBalancerId] {
+		if !toUnbind[id] {
+			left = append(left, id)
+		}
+	}
+	f.BLBClient.SecurityGroupMap[args.LoadBalancerId] = left
+	return nil
+}
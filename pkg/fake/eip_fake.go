@@ -13,12 +13,15 @@ import (
 // FakeClient for unit test
 type EipFakeClient struct {
 	EIPMap map[string]*eip.EIP
+	// ClientToken | EIP
+	ClientTokenMap map[string]string
 }
 
 // NewFakeClient for EIP fake client
 func NewEipFakeClient() *EipFakeClient {
 	return &EipFakeClient{
-		EIPMap: map[string]*eip.EIP{},
+		EIPMap:         map[string]*eip.EIP{},
+		ClientTokenMap: map[string]string{},
 	}
 }
 
@@ -27,16 +30,27 @@ func (f *EipFakeClient) CreateEIP(ctx context.Context, args *eip.CreateEIPArgs,
 	if args == nil {
 		return "", fmt.Errorf("CreateEIP faile: args is nil")
 	}
+	// requests with the same client token return the same EIP
+	if e, ok := f.EIPMap[f.ClientTokenMap[args.ClientToken]]; ok && args.ClientToken != "" {
+		return e.EIP, nil
+	}
 	eip := &eip.EIP{
 		Name:            args.Name,
 		Status:          eip.EIPAvailable,
 		BandwidthInMbps: args.BandwidthInMbps,
 	}
+	if args.Billing != nil {
+		eip.PaymentTiming = args.Billing.PaymentTiming
+		eip.BillingMethod = args.Billing.BillingMethod
+	}
 	for {
 		ip := generateRandomEIP()
 		if _, ok := f.EIPMap[ip]; !ok {
 			eip.EIP = ip
 			f.EIPMap[ip] = eip
+			if args.ClientToken != "" {
+				f.ClientTokenMap[args.ClientToken] = ip
+			}
 			break
 		}
 	}
@@ -0,0 +1,159 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/util"
+	tempeip "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-eip"
+)
+
+// EipGroupFakeClient for unit test, EIPs moved in or out are updated in EIPClient
+type EipGroupFakeClient struct {
+	EIPClient *EipFakeClient
+	GroupMap  map[string]*tempeip.EIPGroup
+	// ClientToken | EIP group id
+	ClientTokenMap map[string]string
+}
+
+// NewEipGroupFakeClient for EIP group fake client
+func NewEipGroupFakeClient(eipClient *EipFakeClient) *EipGroupFakeClient {
+	return &EipGroupFakeClient{
+		EIPClient:      eipClient,
+		GroupMap:       map[string]*tempeip.EIPGroup{},
+		ClientTokenMap: map[string]string{},
+	}
+}
+
+// CreateEIPGroup create EIP group without EIPs
+func (f *EipGroupFakeClient) CreateEIPGroup(ctx context.Context, args *tempeip.CreateEIPGroupArgs, option *bce.SignOption) (string, error) {
+	if args == nil || args.Billing == nil {
+		return "", fmt.Errorf("CreateEIPGroup failed: args or billing is nil")
+	}
+	// requests with the same client token return the same EIP group
+	if id, ok := f.ClientTokenMap[args.ClientToken]; ok && args.ClientToken != "" {
+		return id, nil
+	}
+	group := &tempeip.EIPGroup{
+		Name:            args.Name,
+		Status:          "available",
+		BandwidthInMbps: args.BandwidthInMbps,
+		PaymentTiming:   args.Billing.PaymentTiming,
+		BillingMethod:   args.Billing.BillingMethod,
+	}
+	for {
+		id := util.GenerateBCEShortID("eg")
+		if _, ok := f.GroupMap[id]; !ok {
+			group.ID = id
+			f.GroupMap[id] = group
+			break
+		}
+	}
+	if args.ClientToken != "" {
+		f.ClientTokenMap[args.ClientToken] = group.ID
+	}
+	return group.ID, nil
+}
+
+// GetEIPGroup get EIP group by id
+func (f *EipGroupFakeClient) GetEIPGroup(ctx context.Context, id string, option *bce.SignOption) (*tempeip.EIPGroup, error) {
+	group, ok := f.GroupMap[id]
+	if !ok {
+		return nil, fmt.Errorf("EIP group %s not exist: NoSuchObject", id)
+	}
+	return group, nil
+}
+
+// ListEIPGroups list EIP groups by name
+func (f *EipGroupFakeClient) ListEIPGroups(ctx context.Context, args *tempeip.ListEIPGroupsArgs, option *bce.SignOption) ([]*tempeip.EIPGroup, error) {
+	result := []*tempeip.EIPGroup{}
+	for _, group := range f.GroupMap {
+		if args != nil && args.Name != "" && group.Name != args.Name {
+			continue
+		}
+		result = append(result, group)
+	}
+	return result, nil
+}
+
+// MoveInEIPs move standalone EIPs into EIP group, they use bandwidth of the group
+func (f *EipGroupFakeClient) MoveInEIPs(ctx context.Context, id string, args *tempeip.MoveInEIPsArgs, option *bce.SignOption) error {
+	group, ok := f.GroupMap[id]
+	if !ok {
+		return fmt.Errorf("EIP group %s not exist", id)
+	}
+	for _, ip := range args.EIPs {
+		e, ok := f.EIPClient.EIPMap[ip]
+		if !ok {
+			return fmt.Errorf("EIP %s not exist", ip)
+		}
+		if groupID := f.groupOf(ip); groupID != "" {
+			return fmt.Errorf("EIP %s is already in EIP group %s", ip, groupID)
+		}
+		e.BandwidthInMbps = group.BandwidthInMbps
+		e.PaymentTiming = group.PaymentTiming
+		e.BillingMethod = group.BillingMethod
+		group.EIPs = append(group.EIPs, &tempeip.GroupEIP{
+			Name:         e.Name,
+			EIP:          e.EIP,
+			Status:       e.Status,
+			InstanceType: string(e.InstanceType),
+			InstanceID:   e.InstanceID,
+		})
+	}
+	return nil
+}
+
+// MoveOutEIPs move EIPs out of EIP group with their own bandwidth and billing
+func (f *EipGroupFakeClient) MoveOutEIPs(ctx context.Context, id string, args *tempeip.MoveOutEIPsArgs, option *bce.SignOption) error {
+	group, ok := f.GroupMap[id]
+	if !ok {
+		return fmt.Errorf("EIP group %s not exist", id)
+	}
+	for _, moveOut := range args.MoveOutEIPs {
+		if f.groupOf(moveOut.EIP) != id {
+			return fmt.Errorf("EIP %s is not in EIP group %s", moveOut.EIP, id)
+		}
+		if moveOut.Billing == nil {
+			return fmt.Errorf("MoveOutEIPs failed: billing of %s is nil", moveOut.EIP)
+		}
+		var eips []*tempeip.GroupEIP
+		for _, e := range group.EIPs {
+			if e.EIP != moveOut.EIP {
+				eips = append(eips, e)
+			}
+		}
+		group.EIPs = eips
+		if e, ok := f.EIPClient.EIPMap[moveOut.EIP]; ok {
+			e.BandwidthInMbps = moveOut.BandwidthInMbps
+			e.PaymentTiming = moveOut.Billing.PaymentTiming
+			e.BillingMethod = moveOut.Billing.BillingMethod
+		}
+	}
+	return nil
+}
+
+// DeleteEIPGroup delete EIP group without EIPs
+func (f *EipGroupFakeClient) DeleteEIPGroup(ctx context.Context, id string, option *bce.SignOption) error {
+	group, ok := f.GroupMap[id]
+	if !ok {
+		return fmt.Errorf("EIP group %s not exist", id)
+	}
+	if len(group.EIPs) != 0 {
+		return fmt.Errorf("EIP group %s still has %d EIPs", id, len(group.EIPs))
+	}
+	delete(f.GroupMap, id)
+	return nil
+}
+
+func (f *EipGroupFakeClient) groupOf(ip string) string {
+	for id, group := range f.GroupMap {
+		for _, e := range group.EIPs {
+			if e.EIP == ip {
+				return id
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/eip"
+	tempeip "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-eip"
+)
+
+// EipOptionFakeClient for unit test, EIPs are created in EIPClient with tags kept by TagClient
+type EipOptionFakeClient struct {
+	EIPClient *EipFakeClient
+	TagClient *EipTagFakeClient
+	// EIP | route type, EIPs not in it are BGP
+	RouteTypeMap map[string]string
+	// EIP | auto-renew args, EIPs not in it are not renewed automatically
+	AutoRenewMap map[string]*tempeip.StartEIPAutoRenewArgs
+}
+
+// NewEipOptionFakeClient for EIP option fake client
+func NewEipOptionFakeClient(eipClient *EipFakeClient, tagClient *EipTagFakeClient) *EipOptionFakeClient {
+	return &EipOptionFakeClient{
+		EIPClient:    eipClient,
+		TagClient:    tagClient,
+		RouteTypeMap: map[string]string{},
+		AutoRenewMap: map[string]*tempeip.StartEIPAutoRenewArgs{},
+	}
+}
+
+// CreateEIP create EIP in EIPClient
+func (f *EipOptionFakeClient) CreateEIP(ctx context.Context, args *tempeip.CreateEIPArgs, option *bce.SignOption) (string, error) {
+	if args == nil || args.Billing == nil {
+		return "", fmt.Errorf("CreateEIP failed: args or billing is nil")
+	}
+	eipArgs := &eip.CreateEIPArgs{
+		Name:            args.Name,
+		BandwidthInMbps: args.BandwidthInMbps,
+		Billing: &eip.Billing{
+			PaymentTiming: args.Billing.PaymentTiming,
+			BillingMethod: args.Billing.BillingMethod,
+		},
+		ClientToken: args.ClientToken,
+	}
+	ip, err := f.EIPClient.CreateEIP(ctx, eipArgs, option)
+	if err != nil {
+		return "", err
+	}
+	if args.RouteType != "" {
+		f.RouteTypeMap[ip] = args.RouteType
+	}
+	if args.AutoRenewTime != 0 {
+		f.AutoRenewMap[ip] = &tempeip.StartEIPAutoRenewArgs{
+			AutoRenewTimeUnit: args.AutoRenewTimeUnit,
+			AutoRenewTime:     args.AutoRenewTime,
+		}
+	}
+	if len(args.Tags) != 0 {
+		err = f.TagClient.BindEIPTags(ctx, ip, &tempeip.EIPTagsArgs{ChangeTags: args.Tags}, option)
+		if err != nil {
+			return "", err
+		}
+	}
+	return ip, nil
+}
+
+// GetEIP get EIP in EIPClient with its route type, auto-renew and tags
+func (f *EipOptionFakeClient) GetEIP(ctx context.Context, ip string, option *bce.SignOption) (*tempeip.EIP, error) {
+	e, ok := f.EIPClient.EIPMap[ip]
+	if !ok {
+		return nil, nil
+	}
+	result := &tempeip.EIP{
+		Name:            e.Name,
+		EIP:             e.EIP,
+		Status:          e.Status,
+		RouteType:       "BGP",
+		BandwidthInMbps: e.BandwidthInMbps,
+		PaymentTiming:   e.PaymentTiming,
+		BillingMethod:   e.BillingMethod,
+	}
+	if routeType, ok := f.RouteTypeMap[ip]; ok {
+		result.RouteType = routeType
+	}
+	if renew, ok := f.AutoRenewMap[ip]; ok {
+		result.AutoRenew = true
+		result.AutoRenewTime = renew.AutoRenewTime
+		result.AutoRenewTimeUnit = renew.AutoRenewTimeUnit
+	}
+	for k, v := range f.TagClient.TagMap[ip] {
+		result.Tags = append(result.Tags, tempeip.Tag{TagKey: k, TagValue: v})
+	}
+	return result, nil
+}
+
+// StartEIPAutoRenew start auto-renew of Prepaid EIP
+func (f *EipOptionFakeClient) StartEIPAutoRenew(ctx context.Context, ip string, args *tempeip.StartEIPAutoRenewArgs, option *bce.SignOption) error {
+	e, ok := f.EIPClient.EIPMap[ip]
+	if !ok {
+		return fmt.Errorf("EIP %s not exist", ip)
+	}
+	if e.PaymentTiming != eip.PAYMENTTIMING_PREPAID {
+		return fmt.Errorf("EIP %s is not Prepaid", ip)
+	}
+	f.AutoRenewMap[ip] = args
+	return nil
+}
+
+// StopEIPAutoRenew stop auto-renew of Prepaid EIP
+func (f *EipOptionFakeClient) StopEIPAutoRenew(ctx context.Context, ip string, option *bce.SignOption) error {
+	if _, ok := f.EIPClient.EIPMap[ip]; !ok {
+		return fmt.Errorf("EIP %s not exist", ip)
+	}
+	delete(f.AutoRenewMap, ip)
+	return nil
+}
+
+// ChangeEIPBilling changes payment timing and billing method of EIP in place, Prepaid EIP can not be changed
+func (f *EipOptionFakeClient) ChangeEIPBilling(ctx context.Context, ip string, args *tempeip.ChangeEIPBillingArgs, option *bce.SignOption) error {
+	if args == nil || args.Billing == nil {
+		return fmt.Errorf("ChangeEIPBilling failed: billing is nil")
+	}
+	e, ok := f.EIPClient.EIPMap[ip]
+	if !ok {
+		return fmt.Errorf("EIP %s not exist", ip)
+	}
+	if e.PaymentTiming == eip.PAYMENTTIMING_PREPAID {
+		return fmt.Errorf("billing of Prepaid EIP %s can not be changed", ip)
+	}
+	e.PaymentTiming = args.Billing.PaymentTiming
+	e.BillingMethod = args.Billing.BillingMethod
+	return nil
+}
@@ -0,0 +1,76 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	tempeip "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-eip"
+)
+
+// EipTagFakeClient for unit test, tags of EIPs in EIPClient are kept by it
+type EipTagFakeClient struct {
+	EIPClient *EipFakeClient
+	// EIP | tag key | tag value
+	TagMap map[string]map[string]string
+}
+
+// NewEipTagFakeClient for EIP tag fake client
+func NewEipTagFakeClient(eipClient *EipFakeClient) *EipTagFakeClient {
+	return &EipTagFakeClient{
+		EIPClient: eipClient,
+		TagMap:    map[string]map[string]string{},
+	}
+}
+
+// BindEIPTags bind tags to EIP, the value of a bound key is replaced
+func (f *EipTagFakeClient) BindEIPTags(ctx context.Context, ip string, args *tempeip.EIPTagsArgs, option *bce.SignOption) error {
+	if _, ok := f.EIPClient.EIPMap[ip]; !ok {
+		return fmt.Errorf("EIP %s not exist", ip)
+	}
+	if f.TagMap[ip] == nil {
+		f.TagMap[ip] = map[string]string{}
+	}
+	for _, tag := range args.ChangeTags {
+		f.TagMap[ip][tag.TagKey] = tag.TagValue
+	}
+	return nil
+}
+
+// UnbindEIPTags unbind tags from EIP
+func (f *EipTagFakeClient) UnbindEIPTags(ctx context.Context, ip string, args *tempeip.EIPTagsArgs, option *bce.SignOption) error {
+	if _, ok := f.EIPClient.EIPMap[ip]; !ok {
+		return fmt.Errorf("EIP %s not exist", ip)
+	}
+	for _, tag := range args.ChangeTags {
+		delete(f.TagMap[ip], tag.TagKey)
+	}
+	return nil
+}
+
+// ListEIPsByTag list existing EIPs with the tag
+func (f *EipTagFakeClient) ListEIPsByTag(ctx context.Context, args *tempeip.ListEIPsByTagArgs, option *bce.SignOption) ([]*tempeip.TaggedEIP, error) {
+	result := []*tempeip.TaggedEIP{}
+	for ip, tags := range f.TagMap {
+		e, ok := f.EIPClient.EIPMap[ip]
+		if !ok {
+			continue
+		}
+		value, ok := tags[args.TagKey]
+		if !ok || (args.TagValue != "" && value != args.TagValue) {
+			continue
+		}
+		taggedEIP := &tempeip.TaggedEIP{
+			Name:         e.Name,
+			EIP:          e.EIP,
+			Status:       e.Status,
+			InstanceType: string(e.InstanceType),
+			InstanceID:   e.InstanceID,
+		}
+		for k, v := range tags {
+			taggedEIP.Tags = append(taggedEIP.Tags, tempeip.Tag{TagKey: k, TagValue: v})
+		}
+		result = append(result, taggedEIP)
+	}
+	return result, nil
+}
@@ -7,6 +7,7 @@ import (
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/util"
 	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/vpc"
+	tempvpc "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-vpc"
 )
 
 // FakeClient implement of vpc.Interface
@@ -19,15 +20,18 @@ type VpcFakeClient struct {
 	RouteRuleMap map[string]vpc.RouteRule
 	//  RuleTableID | VpcID
 	VpcRuleTableMap map[string]string
+	// securityGroupID
+	SecurityGroupMap map[string]*tempvpc.SecurityGroup
 }
 
 // NewFakeClient for VPC fake client
 func NewVpcFakeClient() *VpcFakeClient {
 	return &VpcFakeClient{
-		VPCMap:          map[string]*vpc.VPC{},
-		SubnetMap:       map[string]*vpc.Subnet{},
-		RouteRuleMap:    map[string]vpc.RouteRule{},
-		VpcRuleTableMap: map[string]string{},
+		VPCMap:           map[string]*vpc.VPC{},
+		SubnetMap:        map[string]*vpc.Subnet{},
+		RouteRuleMap:     map[string]vpc.RouteRule{},
+		VpcRuleTableMap:  map[string]string{},
+		SecurityGroupMap: map[string]*tempvpc.SecurityGroup{},
 	}
 }
 
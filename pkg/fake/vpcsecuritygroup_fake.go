@@ -0,0 +1,131 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/util"
+	tempvpc "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/temp-vpc"
+)
+
+// VpcSecurityGroupFakeClient for unit test, security groups are kept in VPCClient
+type VpcSecurityGroupFakeClient struct {
+	VPCClient *VpcFakeClient
+	// ClientToken | securityGroupID
+	ClientTokenMap map[string]string
+}
+
+// NewVpcSecurityGroupFakeClient for security group fake client
+func NewVpcSecurityGroupFakeClient(vpcClient *VpcFakeClient) *VpcSecurityGroupFakeClient {
+	return &VpcSecurityGroupFakeClient{
+		VPCClient:      vpcClient,
+		ClientTokenMap: map[string]string{},
+	}
+}
+
+// CreateSecurityGroup to create security group under VPC
+func (f *VpcSecurityGroupFakeClient) CreateSecurityGroup(ctx context.Context, args *tempvpc.CreateSecurityGroupArgs, option *bce.SignOption) (string, error) {
+	if args == nil || args.Name == "" {
+		return "", fmt.Errorf("CreateSecurityGroup need args")
+	}
+	if sgID, ok := f.ClientTokenMap[args.ClientToken]; ok && args.ClientToken != "" {
+		if _, exist := f.VPCClient.SecurityGroupMap[sgID]; exist {
+			return sgID, nil
+		}
+	}
+	sg := &tempvpc.SecurityGroup{
+		Name:  args.Name,
+		VpcID: args.VpcID,
+		Desc:  args.Desc,
+	}
+	for {
+		sgID := util.GenerateBCEShortID("g")
+		if _, ok := f.VPCClient.SecurityGroupMap[sgID]; !ok {
+			sg.ID = sgID
+			f.VPCClient.SecurityGroupMap[sgID] = sg
+			if args.ClientToken != "" {
+				f.ClientTokenMap[args.ClientToken] = sgID
+			}
+			break
+		}
+	}
+	for _, rule := range args.Rules {
+		addSecurityGroupRule(sg, rule)
+	}
+	return sg.ID, nil
+}
+
+// ListSecurityGroup to list security groups under VPC
+func (f *VpcSecurityGroupFakeClient) ListSecurityGroup(ctx context.Context, args *tempvpc.ListSecurityGroupArgs, option *bce.SignOption) ([]tempvpc.SecurityGroup, error) {
+	if args == nil {
+		return nil, fmt.Errorf("ListSecurityGroup failed: args is nil")
+	}
+	sgs := []tempvpc.SecurityGroup{}
+	for _, sg := range f.VPCClient.SecurityGroupMap {
+		if args.VpcID != "" && sg.VpcID != args.VpcID {
+			continue
+		}
+		result := *sg
+		result.Rules = append([]tempvpc.SecurityGroupRule{}, sg.Rules...)
+		sgs = append(sgs, result)
+	}
+	return sgs, nil
+}
+
+// GetSecurityGroup to get security group by id
+func (f *VpcSecurityGroupFakeClient) GetSecurityGroup(ctx context.Context, securityGroupID string, option *bce.SignOption) (*tempvpc.SecurityGroup, error) {
+	sg, ok := f.VPCClient.SecurityGroupMap[securityGroupID]
+	if !ok {
+		return nil, fmt.Errorf("GetSecurityGroup %s not exist", securityGroupID)
+	}
+	result := *sg
+	result.Rules = append([]tempvpc.SecurityGroupRule{}, sg.Rules...)
+	return &result, nil
+}
+
+// DeleteSecurityGroup to delete security group
+func (f *VpcSecurityGroupFakeClient) DeleteSecurityGroup(ctx context.Context, securityGroupID string, option *bce.SignOption) error {
+	if _, ok := f.VPCClient.SecurityGroupMap[securityGroupID]; !ok {
+		return fmt.Errorf("DeleteSecurityGroup %s not exist", securityGroupID)
+	}
+	delete(f.VPCClient.SecurityGroupMap, securityGroupID)
+	return nil
+}
+
+// AuthorizeSecurityGroupRule to add a rule to security group
+func (f *VpcSecurityGroupFakeClient) AuthorizeSecurityGroupRule(ctx context.Context, args *tempvpc.SecurityGroupRuleArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("AuthorizeSecurityGroupRule failed: args is nil")
+	}
+	sg, ok := f.VPCClient.SecurityGroupMap[args.SecurityGroupID]
+	if !ok {
+		return fmt.Errorf("security group %s not exist", args.SecurityGroupID)
+	}
+	addSecurityGroupRule(sg, args.Rule)
+	return nil
+}
+
+// RevokeSecurityGroupRule to remove a rule from security group
+func (f *VpcSecurityGroupFakeClient) RevokeSecurityGroupRule(ctx context.Context, args *tempvpc.SecurityGroupRuleArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("RevokeSecurityGroupRule failed: args is nil")
+	}
+	sg, ok := f.VPCClient.SecurityGroupMap[args.SecurityGroupID]
+	if !ok {
+		return fmt.Errorf("security group %s not exist", args.SecurityGroupID)
+	}
+	for i, rule := range sg.Rules {
+		if rule.SecurityGroupRuleID == args.Rule.SecurityGroupRuleID {
+			sg.Rules = append(sg.Rules[:i], sg.Rules[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("security group rule %s not exist", args.Rule.SecurityGroupRuleID)
+}
+
+// help func
+func addSecurityGroupRule(sg *tempvpc.SecurityGroup, rule tempvpc.SecurityGroupRule) {
+	rule.SecurityGroupRuleID = util.GenerateBCEShortID("r")
+	sg.Rules = append(sg.Rules, rule)
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+)
+
+const (
+	// ModeReportOnly only reports orphaned resources
+	ModeReportOnly = "report-only"
+	// ModeDryRun reports the orphaned resources which would be deleted after the grace period
+	ModeDryRun = "dry-run"
+	// ModeDelete deletes orphaned resources after the grace period
+	ModeDelete = "delete"
+)
+
+// Resource is a cloud load balancer resource created for a service
+type Resource struct {
+	// Kind is the kind of resource, such as BLB or EIP
+	Kind string
+	ID   string
+	Name string
+	// ServiceNamespace and ServiceName are of the service the resource was created for, empty if unknown
+	ServiceNamespace string
+	ServiceName      string
+}
+
+func (r Resource) String() string {
+	return fmt.Sprintf("%s %s(%s)", r.Kind, r.ID, r.Name)
+}
+
+// Config is the config of the garbage collector
+type Config struct {
+	// Mode is one of ModeReportOnly, ModeDryRun and ModeDelete
+	Mode string
+	// GracePeriod is how long a resource stays orphaned before it is deleted
+	GracePeriod time.Duration
+	// Period is the interval of collecting
+	Period time.Duration
+}
+
+// Cloud is implemented by cloud providers which support collecting orphaned load balancer resources
+type Cloud interface {
+	// LoadBalancerGCConfig returns the config of the garbage collector
+	LoadBalancerGCConfig() Config
+	// ListOrphanedLoadBalancerResources returns the resources created by the cluster which are used by none of services
+	ListOrphanedLoadBalancerResources(ctx context.Context, services []*v1.Service) ([]Resource, error)
+	// DeleteLoadBalancerResource deletes the resource
+	DeleteLoadBalancerResource(ctx context.Context, resource Resource) error
+}
+
+// GarbageCollector deletes the load balancer resources whose services are gone
+type GarbageCollector struct {
+	cloud               Cloud
+	config              Config
+	kubeClient          clientset.Interface
+	serviceLister       corelisters.ServiceLister
+	serviceListerSynced cache.InformerSynced
+	broadcaster         record.EventBroadcaster
+	recorder            record.EventRecorder
+	// orphaned resources found, keyed by kind/id
+	orphans map[string]*orphan
+}
+
+type orphan struct {
+	// since is when the resource is first found orphaned
+	since time.Time
+	// dryRunReported is whether the deletion has been reported in dry-run mode
+	dryRunReported bool
+}
+
+// New returns a GarbageCollector
+func New(cloud Cloud, kubeClient clientset.Interface, serviceInformer coreinformers.ServiceInformer) (*GarbageCollector, error) {
+	config := cloud.LoadBalancerGCConfig()
+	switch config.Mode {
+	case ModeReportOnly, ModeDryRun, ModeDelete:
+	default:
+		return nil, fmt.Errorf("load balancer gc mode must be in [%s, %s, %s], got %q", ModeReportOnly, ModeDryRun, ModeDelete, config.Mode)
+	}
+	if config.Period <= 0 {
+		return nil, fmt.Errorf("load balancer gc period must be positive, got %v", config.Period)
+	}
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartLogging(klog.Infof)
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "loadbalancer-gc-controller"})
+
+	return &GarbageCollector{
+		cloud:               cloud,
+		config:              config,
+		kubeClient:          kubeClient,
+		serviceLister:       serviceInformer.Lister(),
+		serviceListerSynced: serviceInformer.Informer().HasSynced,
+		broadcaster:         eventBroadcaster,
+		recorder:            recorder,
+		orphans:             make(map[string]*orphan),
+	}, nil
+}
+
+// Run collects orphaned resources periodically until stopCh is closed
+func (gc *GarbageCollector) Run(stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+
+	klog.Infof("Starting load balancer gc controller in %s mode", gc.config.Mode)
+	defer klog.Info("Shutting down load balancer gc controller")
+
+	// every resource looks orphaned before services are synced
+	if !cache.WaitForNamedCacheSync("loadbalancer-gc", stopCh, gc.serviceListerSynced) {
+		return
+	}
+
+	if gc.broadcaster != nil {
+		gc.broadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: gc.kubeClient.CoreV1().Events("")})
+	}
+
+	go wait.NonSlidingUntil(func() {
+		if err := gc.collect(time.Now()); err != nil {
+			klog.Errorf("Couldn't collect orphaned load balancer resources: %v", err)
+		}
+	}, gc.config.Period, stopCh)
+
+	<-stopCh
+}
+
+func (gc *GarbageCollector) collect(now time.Time) error {
+	services, err := gc.serviceLister.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("error listing services: %v", err)
+	}
+	ctx := context.TODO()
+	orphans, err := gc.cloud.ListOrphanedLoadBalancerResources(ctx, services)
+	if err != nil {
+		return fmt.Errorf("error listing orphaned load balancer resources: %v", err)
+	}
+
+	found := make(map[string]*orphan, len(orphans))
+	for _, resource := range orphans {
+		key := resource.Kind + "/" + resource.ID
+		o, ok := gc.orphans[key]
+		if !ok {
+			o = &orphan{since: now}
+			klog.Infof("Found orphaned %v of service %s/%s", resource, resource.ServiceNamespace, resource.ServiceName)
+			gc.event(resource, v1.EventTypeWarning, "OrphanedLoadBalancerResource",
+				fmt.Sprintf("Found orphaned %v, mode is %s", resource, gc.config.Mode))
+		}
+		found[key] = o
+
+		if gc.config.Mode == ModeReportOnly || now.Sub(o.since) < gc.config.GracePeriod {
+			continue
+		}
+		if gc.config.Mode == ModeDryRun {
+			if !o.dryRunReported {
+				o.dryRunReported = true
+				klog.Infof("Dry run: would delete orphaned %v", resource)
+				gc.event(resource, v1.EventTypeNormal, "DryRunDeleteOrphanedLoadBalancerResource",
+					fmt.Sprintf("Would delete orphaned %v", resource))
+			}
+			continue
+		}
+
+		klog.Infof("Deleting orphaned %v", resource)
+		if err := gc.cloud.DeleteLoadBalancerResource(ctx, resource); err != nil {
+			klog.Errorf("Failed to delete orphaned %v: %v", resource, err)
+			gc.event(resource, v1.EventTypeWarning, "DeleteOrphanedLoadBalancerResourceFailed",
+				fmt.Sprintf("Error deleting orphaned %v: %v", resource, err))
+			continue
+		}
+		delete(found, key)
+		gc.event(resource, v1.EventTypeNormal, "DeletedOrphanedLoadBalancerResource",
+			fmt.Sprintf("Deleted orphaned %v", resource))
+	}
+	// resources not orphaned any more are forgotten
+	gc.orphans = found
+	return nil
+}
+
+// event records an event on the service the resource was created for, it is only logged if the service is unknown
+func (gc *GarbageCollector) event(resource Resource, eventType, reason, message string) {
+	if resource.ServiceNamespace == "" || resource.ServiceName == "" {
+		return
+	}
+	ref := &v1.ObjectReference{
+		Kind:      "Service",
+		Namespace: resource.ServiceNamespace,
+		Name:      resource.ServiceName,
+	}
+	gc.recorder.Event(ref, eventType, reason, message)
+}
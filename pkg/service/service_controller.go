@@ -18,6 +18,7 @@ package service
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
 	"sync"
 	"time"
@@ -26,6 +27,7 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -43,6 +45,8 @@ import (
 	"k8s.io/klog"
 	v1helper "k8s.io/kubernetes/pkg/apis/core/v1/helper"
 	"k8s.io/kubernetes/pkg/util/metrics"
+
+	cloud_provider "icode.baidu.com/baidu/jpaas-caas/cloud-provider-baiducloud/pkg/cloud-provider"
 )
 
 const (
@@ -256,10 +260,24 @@ func (s *ServiceController) processNextWorkItem() bool {
 	}
 
 	runtime.HandleError(fmt.Errorf("error processing service %v (will retry): %v", key, err))
+	// the cloud provider is waiting for a resource to be ready, retry after the given delay
+	// instead of backing off exponentially
+	var retryErr retryAfterError
+	if goerrors.As(err, &retryErr) {
+		s.queue.Forget(key)
+		s.queue.AddAfter(key, retryErr.RetryAfter())
+		return true
+	}
 	s.queue.AddRateLimited(key)
 	return true
 }
 
+// retryAfterError is implemented by errors of the cloud provider which tells when to retry
+type retryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
 func (s *ServiceController) init() error {
 	if s.cloud == nil {
 		return fmt.Errorf("WARNING: no cloud provider provided, services of type LoadBalancer will fail")
@@ -338,7 +356,7 @@ func (s *ServiceController) syncLoadBalancerIfNeeded(service *v1.Service, key st
 			klog.V(2).Infof("Deleting existing load balancer for service %s", key)
 			s.eventRecorder.Event(service, v1.EventTypeNormal, "DeletingLoadBalancer", "Deleting load balancer")
 			if err := s.balancer.EnsureLoadBalancerDeleted(context.TODO(), s.clusterName, service); err != nil {
-				return op, fmt.Errorf("failed to delete load balancer: %v", err)
+				return op, fmt.Errorf("failed to delete load balancer: %w", err)
 			}
 		}
 		// Always try to remove finalizer when load balancer is deleted.
@@ -373,7 +391,7 @@ func (s *ServiceController) syncLoadBalancerIfNeeded(service *v1.Service, key st
 				klog.V(4).Infof("LoadBalancer for service %s implemented by a different controller %s, Ignoring error", key, s.cloud.ProviderName())
 				return op, nil
 			}
-			return op, fmt.Errorf("failed to ensure load balancer: %v", err)
+			return op, fmt.Errorf("failed to ensure load balancer: %w", err)
 		}
 		s.eventRecorder.Event(service, v1.EventTypeNormal, "EnsuredLoadBalancer", "Ensured load balancer")
 	}
@@ -553,33 +571,20 @@ func (s *ServiceController) needsUpdate(oldService *v1.Service, newService *v1.S
 	return false
 }
 
-func getPortsForLB(service *v1.Service) ([]*v1.ServicePort, error) {
-	var protocol v1.Protocol
-
+func getPortsForLB(service *v1.Service) []*v1.ServicePort {
 	ports := []*v1.ServicePort{}
 	for i := range service.Spec.Ports {
 		sp := &service.Spec.Ports[i]
-		// The check on protocol was removed here.  The cloud provider itself is now responsible for all protocol validation
+		// The checks on protocol were removed here, mixed protocol (e.g. 53/TCP and 53/UDP) is supported by BLB.
+		// The cloud provider itself is now responsible for all protocol validation
 		ports = append(ports, sp)
-		if protocol == "" {
-			protocol = sp.Protocol
-		} else if protocol != sp.Protocol && wantsLoadBalancer(service) {
-			// TODO:  Convert error messages to use event recorder
-			return nil, fmt.Errorf("mixed protocol external load balancers are not supported")
-		}
 	}
-	return ports, nil
+	return ports
 }
 
 func portsEqualForLB(x, y *v1.Service) bool {
-	xPorts, err := getPortsForLB(x)
-	if err != nil {
-		return false
-	}
-	yPorts, err := getPortsForLB(y)
-	if err != nil {
-		return false
-	}
+	xPorts := getPortsForLB(x)
+	yPorts := getPortsForLB(y)
 	return portSlicesEqualForLB(xPorts, yPorts)
 }
 
@@ -629,11 +634,48 @@ func nodeNames(nodes []*v1.Node) sets.String {
 	return ret
 }
 
-func nodeSlicesEqualForLB(x, y []*v1.Node) bool {
+// nodeSlicesEqualForLB also compares the labels selecting backends and rs weight annotation of nodes,
+// as the cloud provider chooses backends of load balancers by them and weights them by the annotation
+func nodeSlicesEqualForLB(x, y []*v1.Node, labelKeys sets.String) bool {
 	if len(x) != len(y) {
 		return false
 	}
-	return nodeNames(x).Equal(nodeNames(y))
+	if !nodeNames(x).Equal(nodeNames(y)) {
+		return false
+	}
+	nodes := make(map[string]*v1.Node, len(x))
+	for _, node := range x {
+		nodes[node.Name] = node
+	}
+	for _, node := range y {
+		for key := range labelKeys {
+			oldValue, oldOk := nodes[node.Name].Labels[key]
+			newValue, newOk := node.Labels[key]
+			if oldOk != newOk || oldValue != newValue {
+				return false
+			}
+		}
+		if nodes[node.Name].Annotations[cloud_provider.NodeAnnotationBLBRsWeight] != node.Annotations[cloud_provider.NodeAnnotationBLBRsWeight] {
+			return false
+		}
+	}
+	return true
+}
+
+// backendLabelKeys returns the node labels by which the cloud provider chooses backends of services
+func backendLabelKeys(services []*v1.Service) sets.String {
+	keys := sets.NewString(labelNodeRoleMaster, labelNodeRoleExcludeBalancer, labelAlphaNodeRoleExcludeBalancer)
+	for _, service := range services {
+		selector, err := labels.Parse(service.Annotations[cloud_provider.ServiceAnnotationLoadBalancerNodeSelector])
+		if err != nil {
+			continue
+		}
+		requirements, _ := selector.Requirements()
+		for _, requirement := range requirements {
+			keys.Insert(requirement.Key())
+		}
+	}
+	return keys
 }
 
 func getNodeConditionPredicate() corelisters.NodeConditionPredicate {
@@ -685,7 +727,7 @@ func (s *ServiceController) nodeSyncLoop() {
 		runtime.HandleError(fmt.Errorf("Failed to retrieve current set of nodes from node lister: %v", err))
 		return
 	}
-	if nodeSlicesEqualForLB(newHosts, s.knownHosts) {
+	if nodeSlicesEqualForLB(newHosts, s.knownHosts, backendLabelKeys(s.cache.allServices())) {
 		// The set of nodes in the cluster hasn't changed, but we can retry
 		// updating any services that we failed to update last time around.
 		s.servicesToUpdate = s.updateLoadBalancerHosts(s.servicesToUpdate, newHosts)
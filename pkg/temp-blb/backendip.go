@@ -0,0 +1,155 @@
+package temp_blb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+// IPGroupName returns the name of the App BLB ip group holding the backend ips of a listener
+func IPGroupName(listenerType string, listenerPort int) string {
+	return fmt.Sprintf("cce-%s-%d", strings.ToLower(listenerType), listenerPort)
+}
+
+// DescribeBackendIPs describes the ips registered to the listener of BLB, empty if its ip group not exists
+func (c *Client) DescribeBackendIPs(ctx context.Context, args *DescribeBackendIPsArgs, option *bce.SignOption) ([]BackendIP, error) {
+	if args == nil || args.LoadBalancerId == "" || args.ListenerPort == 0 || args.ListenerType == "" {
+		return nil, fmt.Errorf("DescribeBackendIPs failed: loadBalancerId, listenerPort or listenerType is empty")
+	}
+	group, err := c.getIPGroup(ctx, args.LoadBalancerId, IPGroupName(args.ListenerType, args.ListenerPort), option)
+	if err != nil || group == nil {
+		return nil, err
+	}
+	return c.describeIPGroupMembers(ctx, args.LoadBalancerId, group.Id, option)
+}
+
+// AddBackendIPs registers ips to the listener of BLB, the ip group of listener is created with them if it not exists
+func (c *Client) AddBackendIPs(ctx context.Context, args *BackendIPsArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" || args.ListenerPort == 0 || args.ListenerType == "" || len(args.BackendIPList) == 0 {
+		return fmt.Errorf("AddBackendIPs failed: loadBalancerId, listenerPort, listenerType or backendIpList is empty")
+	}
+	name := IPGroupName(args.ListenerType, args.ListenerPort)
+	group, err := c.getIPGroup(ctx, args.LoadBalancerId, name, option)
+	if err != nil {
+		return err
+	}
+	if group == nil {
+		createArgs := CreateIPGroupArgs{
+			Name:       name,
+			MemberList: args.BackendIPList,
+		}
+		return c.updateAppBLB(ctx, "POST", "v1/appblb/"+args.LoadBalancerId+"/ipgroup", nil, createArgs, option)
+	}
+	memberArgs := IPGroupMembersArgs{
+		IPGroupId:  group.Id,
+		MemberList: args.BackendIPList,
+	}
+	return c.updateAppBLB(ctx, "POST", "v1/appblb/"+args.LoadBalancerId+"/ipgroup/backendmember", nil, memberArgs, option)
+}
+
+// RemoveBackendIPs deregisters ips from the listener of BLB, ips not registered are skipped
+func (c *Client) RemoveBackendIPs(ctx context.Context, args *BackendIPsArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" || args.ListenerPort == 0 || args.ListenerType == "" || len(args.BackendIPList) == 0 {
+		return fmt.Errorf("RemoveBackendIPs failed: loadBalancerId, listenerPort, listenerType or backendIpList is empty")
+	}
+	group, err := c.getIPGroup(ctx, args.LoadBalancerId, IPGroupName(args.ListenerType, args.ListenerPort), option)
+	if err != nil || group == nil {
+		return err
+	}
+	members, err := c.describeIPGroupMembers(ctx, args.LoadBalancerId, group.Id, option)
+	if err != nil {
+		return err
+	}
+	memberIDs := make(map[string]string, len(members))
+	for _, m := range members {
+		memberIDs[fmt.Sprintf("%s:%d", m.Ip, m.Port)] = m.MemberId
+	}
+	deleteArgs := DeleteIPGroupMembersArgs{IPGroupId: group.Id}
+	for _, ip := range args.BackendIPList {
+		if id, ok := memberIDs[fmt.Sprintf("%s:%d", ip.Ip, ip.Port)]; ok {
+			deleteArgs.MemberIdList = append(deleteArgs.MemberIdList, id)
+		}
+	}
+	if len(deleteArgs.MemberIdList) == 0 {
+		return nil
+	}
+	return c.updateAppBLB(ctx, "PUT", "v1/appblb/"+args.LoadBalancerId+"/ipgroup/backendmember", map[string]string{"delete": ""}, deleteArgs, option)
+}
+
+// getIPGroup returns the ip group of BLB named name, nil if it not exists
+func (c *Client) getIPGroup(ctx context.Context, lbID, name string, option *bce.SignOption) (*IPGroup, error) {
+	params := map[string]string{
+		"name":         name,
+		"exactlyMatch": "true",
+	}
+	var listResp DescribeIPGroupsResponse
+	err := c.describeAppBLB(ctx, "v1/appblb/"+lbID+"/ipgroup", params, &listResp, option)
+	if err != nil {
+		return nil, err
+	}
+	for i := range listResp.AppIPGroupList {
+		if listResp.AppIPGroupList[i].Name == name {
+			return &listResp.AppIPGroupList[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// describeIPGroupMembers returns all members of the ip group
+func (c *Client) describeIPGroupMembers(ctx context.Context, lbID, groupID string, option *bce.SignOption) ([]BackendIP, error) {
+	var result []BackendIP
+	marker := ""
+	for {
+		params := map[string]string{"ipGroupId": groupID}
+		if marker != "" {
+			params["marker"] = marker
+		}
+		var listResp DescribeIPGroupMembersResponse
+		err := c.describeAppBLB(ctx, "v1/appblb/"+lbID+"/ipgroup/backendmember", params, &listResp, option)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, listResp.MemberList...)
+		if !listResp.IsTruncated || listResp.NextMarker == "" {
+			return result, nil
+		}
+		marker = listResp.NextMarker
+	}
+}
+
+func (c *Client) describeAppBLB(ctx context.Context, path string, params map[string]string, listResp interface{}, option *bce.SignOption) error {
+	req, err := bce.NewRequest("GET", c.GetURL(path, params), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.SendRequest(ctx, req, option)
+	if err != nil {
+		return err
+	}
+	bodyContent, err := resp.GetBodyContent()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bodyContent, listResp)
+}
+
+func (c *Client) updateAppBLB(ctx context.Context, method, path string, params map[string]string, args interface{}, option *bce.SignOption) error {
+	if params == nil {
+		params = map[string]string{}
+	}
+	params["clientToken"] = c.GenerateClientToken()
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	req, err := bce.NewRequest(method, c.GetURL(path, params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return err
+	}
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
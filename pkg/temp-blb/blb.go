@@ -0,0 +1,85 @@
+package temp_blb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+// CreateLoadBalancer creates a BLB with the private address specified in args
+func (c *Client) CreateLoadBalancer(ctx context.Context, args *CreateLoadBalancerArgs, option *bce.SignOption) (*CreateLoadBalancerResponse, error) {
+	if args == nil {
+		return nil, fmt.Errorf("CreateLoadBalancer failed: args is nil")
+	}
+	params := map[string]string{
+		"clientToken": args.ClientToken,
+	}
+	if args.ClientToken == "" {
+		params["clientToken"] = c.GenerateClientToken()
+	}
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	req, err := bce.NewRequest("POST", c.GetURL("v1/blb", params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.SendRequest(ctx, req, option)
+	if err != nil {
+		return nil, err
+	}
+	bodyContent, err := resp.GetBodyContent()
+	if err != nil {
+		return nil, err
+	}
+	var createResp CreateLoadBalancerResponse
+	err = json.Unmarshal(bodyContent, &createResp)
+	if err != nil {
+		return nil, err
+	}
+	return &createResp, nil
+}
+
+// DescribeLoadBalancers describes BLBs with their VPC and subnet, all pages are returned
+func (c *Client) DescribeLoadBalancers(ctx context.Context, args *DescribeLoadBalancersArgs, option *bce.SignOption) ([]LoadBalancer, error) {
+	if args == nil {
+		return nil, fmt.Errorf("DescribeLoadBalancers failed: args is nil")
+	}
+	var result []LoadBalancer
+	marker := ""
+	for {
+		params := map[string]string{}
+		if args.Address != "" {
+			params["address"] = args.Address
+		}
+		if marker != "" {
+			params["marker"] = marker
+		}
+		req, err := bce.NewRequest("GET", c.GetURL("v1/blb", params), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.SendRequest(ctx, req, option)
+		if err != nil {
+			return nil, err
+		}
+		bodyContent, err := resp.GetBodyContent()
+		if err != nil {
+			return nil, err
+		}
+		var listResp DescribeLoadBalancersResponse
+		err = json.Unmarshal(bodyContent, &listResp)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, listResp.BLBList...)
+		if !listResp.IsTruncated || listResp.NextMarker == "" {
+			return result, nil
+		}
+		marker = listResp.NextMarker
+	}
+}
@@ -0,0 +1,49 @@
+package temp_blb
+
+import (
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+// Endpoint contains all endpoints of Baidu Cloud BLB.
+var Endpoint = map[string]string{
+	"bj":  "blb.bj.baidubce.com",
+	"gz":  "blb.gz.baidubce.com",
+	"su":  "blb.su.baidubce.com",
+	"hkg": "blb.hkg.baidubce.com",
+	"fwh": "blb.fwh.baidubce.com",
+	"bd":  "blb.bd.baidubce.com",
+}
+
+// Config contains all options for BLB Client.
+type Config struct {
+	*bce.Config
+}
+
+// NewConfig config of BLB Client
+func NewConfig(config *bce.Config) *Config {
+	return &Config{config}
+}
+
+// Client is the client of Baidu Cloud BLB API not supported by bce-sdk-go yet.
+type Client struct {
+	*bce.Client
+}
+
+// NewClient client of BLB
+func NewClient(config *Config) *Client {
+	bceClient := bce.NewClient(config.Config)
+	return &Client{bceClient}
+}
+
+// GetURL generates the full URL of http request for Baidu Cloud BLB API.
+func (c *Client) GetURL(objectKey string, params map[string]string) string {
+	host := c.Endpoint
+
+	if host == "" {
+		host = Endpoint[c.GetRegion()]
+	}
+
+	uriPath := objectKey
+
+	return c.Client.GetURL(host, uriPath, params)
+}
@@ -0,0 +1,219 @@
+package temp_blb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+// CreateTCPListener creates a TCP listener with health check of the port specified in args
+func (c *Client) CreateTCPListener(ctx context.Context, args *CreateTCPListenerArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("CreateTCPListener failed: args is nil")
+	}
+	return c.createListener(ctx, args.LoadBalancerId, "TCPlistener", args, option)
+}
+
+// CreateUDPListener creates a UDP listener with health check of the port specified in args
+func (c *Client) CreateUDPListener(ctx context.Context, args *CreateUDPListenerArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("CreateUDPListener failed: args is nil")
+	}
+	if args.HealthCheckString == "" {
+		return fmt.Errorf("CreateUDPListener failed: healthCheckString is empty")
+	}
+	return c.createListener(ctx, args.LoadBalancerId, "UDPlistener", args, option)
+}
+
+// CreateHTTPListener creates an HTTP listener with health check of the port specified in args
+func (c *Client) CreateHTTPListener(ctx context.Context, args *CreateHTTPListenerArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("CreateHTTPListener failed: args is nil")
+	}
+	return c.createListener(ctx, args.LoadBalancerId, "HTTPlistener", args, option)
+}
+
+// CreateHTTPSListener creates an HTTPS listener with the certificates specified in args
+func (c *Client) CreateHTTPSListener(ctx context.Context, args *CreateHTTPSListenerArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("CreateHTTPSListener failed: args is nil")
+	}
+	if len(args.CertIds) == 0 {
+		return fmt.Errorf("CreateHTTPSListener failed: certIds is empty")
+	}
+	return c.createListener(ctx, args.LoadBalancerId, "HTTPSlistener", args, option)
+}
+
+// DescribeTCPListener describes TCP listeners of BLB, all of them if ListenerPort is 0
+func (c *Client) DescribeTCPListener(ctx context.Context, args *DescribeTCPListenerArgs, option *bce.SignOption) ([]TCPListener, error) {
+	if args == nil {
+		return nil, fmt.Errorf("DescribeTCPListener failed: args is nil")
+	}
+	var listResp DescribeTCPListenerResponse
+	err := c.describeListeners(ctx, args.LoadBalancerId, "TCPlistener", args.ListenerPort, &listResp, option)
+	if err != nil {
+		return nil, err
+	}
+	return listResp.ListenerList, nil
+}
+
+// DescribeUDPListener describes UDP listeners of BLB, all of them if ListenerPort is 0
+func (c *Client) DescribeUDPListener(ctx context.Context, args *DescribeUDPListenerArgs, option *bce.SignOption) ([]UDPListener, error) {
+	if args == nil {
+		return nil, fmt.Errorf("DescribeUDPListener failed: args is nil")
+	}
+	var listResp DescribeUDPListenerResponse
+	err := c.describeListeners(ctx, args.LoadBalancerId, "UDPlistener", args.ListenerPort, &listResp, option)
+	if err != nil {
+		return nil, err
+	}
+	return listResp.ListenerList, nil
+}
+
+// DescribeHTTPListener describes HTTP listeners of BLB, all of them if ListenerPort is 0
+func (c *Client) DescribeHTTPListener(ctx context.Context, args *DescribeHTTPListenerArgs, option *bce.SignOption) ([]HTTPListener, error) {
+	if args == nil {
+		return nil, fmt.Errorf("DescribeHTTPListener failed: args is nil")
+	}
+	var listResp DescribeHTTPListenerResponse
+	err := c.describeListeners(ctx, args.LoadBalancerId, "HTTPlistener", args.ListenerPort, &listResp, option)
+	if err != nil {
+		return nil, err
+	}
+	return listResp.ListenerList, nil
+}
+
+// DescribeHTTPSListener describes HTTPS listeners of BLB, all of them if ListenerPort is 0
+func (c *Client) DescribeHTTPSListener(ctx context.Context, args *DescribeHTTPSListenerArgs, option *bce.SignOption) ([]HTTPSListener, error) {
+	if args == nil {
+		return nil, fmt.Errorf("DescribeHTTPSListener failed: args is nil")
+	}
+	var listResp DescribeHTTPSListenerResponse
+	err := c.describeListeners(ctx, args.LoadBalancerId, "HTTPSlistener", args.ListenerPort, &listResp, option)
+	if err != nil {
+		return nil, err
+	}
+	return listResp.ListenerList, nil
+}
+
+// UpdateTCPListener updates the TCP listener on ListenerPort
+func (c *Client) UpdateTCPListener(ctx context.Context, args *UpdateTCPListenerArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("UpdateTCPListener failed: args is nil")
+	}
+	return c.updateListener(ctx, args.LoadBalancerId, "TCPlistener", args.ListenerPort, args, option)
+}
+
+// UpdateUDPListener updates the UDP listener on ListenerPort
+func (c *Client) UpdateUDPListener(ctx context.Context, args *UpdateUDPListenerArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("UpdateUDPListener failed: args is nil")
+	}
+	return c.updateListener(ctx, args.LoadBalancerId, "UDPlistener", args.ListenerPort, args, option)
+}
+
+// UpdateHTTPListener updates the HTTP listener on ListenerPort
+func (c *Client) UpdateHTTPListener(ctx context.Context, args *UpdateHTTPListenerArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("UpdateHTTPListener failed: args is nil")
+	}
+	return c.updateListener(ctx, args.LoadBalancerId, "HTTPlistener", args.ListenerPort, args, option)
+}
+
+// UpdateHTTPSListener updates the HTTPS listener on ListenerPort
+func (c *Client) UpdateHTTPSListener(ctx context.Context, args *UpdateHTTPSListenerArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("UpdateHTTPSListener failed: args is nil")
+	}
+	return c.updateListener(ctx, args.LoadBalancerId, "HTTPSlistener", args.ListenerPort, args, option)
+}
+
+// DeleteListeners deletes listeners of BLB by port, or by port and type
+func (c *Client) DeleteListeners(ctx context.Context, args *DeleteListenersArgs, option *bce.SignOption) error {
+	if args == nil || args.LoadBalancerId == "" {
+		return fmt.Errorf("DeleteListeners failed: args or loadBalancerId is empty")
+	}
+	if len(args.PortList) == 0 && len(args.PortTypeList) == 0 {
+		return fmt.Errorf("DeleteListeners failed: portList and portTypeList are empty")
+	}
+	params := map[string]string{
+		"batchdelete": "",
+		"clientToken": c.GenerateClientToken(),
+	}
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	req, err := bce.NewRequest("PUT", c.GetURL("v1/blb/"+args.LoadBalancerId+"/listener", params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return err
+	}
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
+
+func (c *Client) createListener(ctx context.Context, lbID, listener string, args interface{}, option *bce.SignOption) error {
+	if lbID == "" {
+		return fmt.Errorf("create %s failed: loadBalancerId is empty", listener)
+	}
+	params := map[string]string{
+		"clientToken": c.GenerateClientToken(),
+	}
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	req, err := bce.NewRequest("POST", c.GetURL("v1/blb/"+lbID+"/"+listener, params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return err
+	}
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
+
+func (c *Client) describeListeners(ctx context.Context, lbID, listener string, port int, listResp interface{}, option *bce.SignOption) error {
+	if lbID == "" {
+		return fmt.Errorf("describe %s failed: loadBalancerId is empty", listener)
+	}
+	params := map[string]string{}
+	if port != 0 {
+		params["listenerPort"] = strconv.Itoa(port)
+	}
+	req, err := bce.NewRequest("GET", c.GetURL("v1/blb/"+lbID+"/"+listener, params), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.SendRequest(ctx, req, option)
+	if err != nil {
+		return err
+	}
+	bodyContent, err := resp.GetBodyContent()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bodyContent, listResp)
+}
+
+func (c *Client) updateListener(ctx context.Context, lbID, listener string, port int, args interface{}, option *bce.SignOption) error {
+	if lbID == "" || port == 0 {
+		return fmt.Errorf("update %s failed: loadBalancerId or listenerPort is empty", listener)
+	}
+	params := map[string]string{
+		"listenerPort": strconv.Itoa(port),
+		"clientToken":  c.GenerateClientToken(),
+	}
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	req, err := bce.NewRequest("PUT", c.GetURL("v1/blb/"+lbID+"/"+listener, params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return err
+	}
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
@@ -0,0 +1,71 @@
+package temp_blb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+// DescribeSecurityGroups describes the security groups bound to BLB
+func (c *Client) DescribeSecurityGroups(ctx context.Context, args *DescribeSecurityGroupsArgs, option *bce.SignOption) ([]SecurityGroup, error) {
+	if args == nil || args.LoadBalancerId == "" {
+		return nil, fmt.Errorf("DescribeSecurityGroups failed: args or loadBalancerId is empty")
+	}
+	req, err := bce.NewRequest("GET", c.GetURL("v1/blb/"+args.LoadBalancerId+"/securitygroup", nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.SendRequest(ctx, req, option)
+	if err != nil {
+		return nil, err
+	}
+	bodyContent, err := resp.GetBodyContent()
+	if err != nil {
+		return nil, err
+	}
+	var describeResp DescribeSecurityGroupsResponse
+	err = json.Unmarshal(bodyContent, &describeResp)
+	if err != nil {
+		return nil, err
+	}
+	return describeResp.BlbSecurityGroups, nil
+}
+
+// BindSecurityGroups binds security groups to BLB
+func (c *Client) BindSecurityGroups(ctx context.Context, args *SecurityGroupsArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("BindSecurityGroups failed: args is nil")
+	}
+	return c.updateSecurityGroups(ctx, "bind", args, option)
+}
+
+// UnbindSecurityGroups unbinds security groups from BLB
+func (c *Client) UnbindSecurityGroups(ctx context.Context, args *SecurityGroupsArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("UnbindSecurityGroups failed: args is nil")
+	}
+	return c.updateSecurityGroups(ctx, "unbind", args, option)
+}
+
+func (c *Client) updateSecurityGroups(ctx context.Context, action string, args *SecurityGroupsArgs, option *bce.SignOption) error {
+	if args.LoadBalancerId == "" || len(args.SecurityGroupIds) == 0 {
+		return fmt.Errorf("%s security groups failed: loadBalancerId or securityGroupIds is empty", action)
+	}
+	params := map[string]string{
+		action:        "",
+		"clientToken": c.GenerateClientToken(),
+	}
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	req, err := bce.NewRequest("PUT", c.GetURL("v1/blb/"+args.LoadBalancerId+"/securitygroup", params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return err
+	}
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
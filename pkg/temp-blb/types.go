@@ -0,0 +1,422 @@
+package temp_blb
+
+import (
+	"context"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+// Interface defines the interface of BLB Client.
+type Interface interface {
+	CreateLoadBalancer(ctx context.Context, args *CreateLoadBalancerArgs, option *bce.SignOption) (*CreateLoadBalancerResponse, error)
+
+	DescribeLoadBalancers(ctx context.Context, args *DescribeLoadBalancersArgs, option *bce.SignOption) ([]LoadBalancer, error)
+}
+
+// CreateLoadBalancerArgs createLoadBalancer's args, Address is the private address of BLB in the subnet
+type CreateLoadBalancerArgs struct {
+	Name        string `json:"name,omitempty"`
+	Desc        string `json:"desc,omitempty"`
+	VpcID       string `json:"vpcId"`
+	SubnetID    string `json:"subnetId"`
+	Address     string `json:"address,omitempty"`
+	AllocateVIP bool   `json:"allocateVip,omitempty"`
+	ClientToken string `json:"-"`
+}
+
+// CreateLoadBalancerResponse createLoadBalancer's response
+type CreateLoadBalancerResponse struct {
+	Address        string `json:"address"`
+	Desc           string `json:"desc"`
+	LoadBalancerId string `json:"blbId"`
+	Name           string `json:"name"`
+}
+
+// DescribeLoadBalancersArgs describeLoadBalancers's args, BLBs of the address are returned if Address is set
+type DescribeLoadBalancersArgs struct {
+	Address string
+}
+
+// LoadBalancer is a BLB with the VPC and subnet not returned by bce-sdk-go
+type LoadBalancer struct {
+	BlbId    string `json:"blbId"`
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Desc     string `json:"desc"`
+	Address  string `json:"address"`
+	PublicIp string `json:"publicIp"`
+	VpcID    string `json:"vpcId"`
+	SubnetID string `json:"subnetId"`
+}
+
+// DescribeLoadBalancersResponse describeLoadBalancers's response
+type DescribeLoadBalancersResponse struct {
+	Marker      string         `json:"marker"`
+	IsTruncated bool           `json:"isTruncated"`
+	NextMarker  string         `json:"nextMarker"`
+	MaxKeys     int            `json:"maxKeys"`
+	BLBList     []LoadBalancer `json:"blbList"`
+}
+
+// ListenerInterface defines the interface of BLB listener Client.
+// Listeners check health of the port specified rather than the backend port,
+// and listeners can be deleted by port and type.
+type ListenerInterface interface {
+	CreateTCPListener(ctx context.Context, args *CreateTCPListenerArgs, option *bce.SignOption) error
+
+	CreateUDPListener(ctx context.Context, args *CreateUDPListenerArgs, option *bce.SignOption) error
+
+	CreateHTTPListener(ctx context.Context, args *CreateHTTPListenerArgs, option *bce.SignOption) error
+
+	CreateHTTPSListener(ctx context.Context, args *CreateHTTPSListenerArgs, option *bce.SignOption) error
+
+	DescribeTCPListener(ctx context.Context, args *DescribeTCPListenerArgs, option *bce.SignOption) ([]TCPListener, error)
+
+	DescribeUDPListener(ctx context.Context, args *DescribeUDPListenerArgs, option *bce.SignOption) ([]UDPListener, error)
+
+	DescribeHTTPListener(ctx context.Context, args *DescribeHTTPListenerArgs, option *bce.SignOption) ([]HTTPListener, error)
+
+	DescribeHTTPSListener(ctx context.Context, args *DescribeHTTPSListenerArgs, option *bce.SignOption) ([]HTTPSListener, error)
+
+	UpdateTCPListener(ctx context.Context, args *UpdateTCPListenerArgs, option *bce.SignOption) error
+
+	UpdateUDPListener(ctx context.Context, args *UpdateUDPListenerArgs, option *bce.SignOption) error
+
+	UpdateHTTPListener(ctx context.Context, args *UpdateHTTPListenerArgs, option *bce.SignOption) error
+
+	UpdateHTTPSListener(ctx context.Context, args *UpdateHTTPSListenerArgs, option *bce.SignOption) error
+
+	DeleteListeners(ctx context.Context, args *DeleteListenersArgs, option *bce.SignOption) error
+}
+
+// TCPListener is the TCP listener of BLB
+type TCPListener struct {
+	ListenerPort               int    `json:"listenerPort"`
+	BackendPort                int    `json:"backendPort"`
+	Scheduler                  string `json:"scheduler"`
+	HealthCheckType            string `json:"healthCheckType"`
+	HealthCheckPort            int    `json:"healthCheckPort"`
+	HealthCheckURI             string `json:"healthCheckURI"`
+	HealthCheckTimeoutInSecond int    `json:"healthCheckTimeoutInSecond"`
+	HealthCheckInterval        int    `json:"healthCheckInterval"`
+	UnhealthyThreshold         int    `json:"unhealthyThreshold"`
+	HealthyThreshold           int    `json:"healthyThreshold"`
+}
+
+// UDPListener is the UDP listener of BLB
+type UDPListener struct {
+	TCPListener
+	HealthCheckString string `json:"healthCheckString"`
+}
+
+// CreateTCPListenerArgs createTCPListener's args, HealthCheckPort 0 means the backend port
+type CreateTCPListenerArgs struct {
+	LoadBalancerId             string `json:"-"`
+	ListenerPort               int    `json:"listenerPort"`
+	BackendPort                int    `json:"backendPort"`
+	Scheduler                  string `json:"scheduler"`
+	HealthCheckType            string `json:"healthCheckType,omitempty"`
+	HealthCheckPort            int    `json:"healthCheckPort,omitempty"`
+	HealthCheckURI             string `json:"healthCheckURI,omitempty"`
+	HealthCheckTimeoutInSecond int    `json:"healthCheckTimeoutInSecond,omitempty"`
+	HealthCheckInterval        int    `json:"healthCheckInterval,omitempty"`
+	UnhealthyThreshold         int    `json:"unhealthyThreshold,omitempty"`
+	HealthyThreshold           int    `json:"healthyThreshold,omitempty"`
+}
+
+// CreateUDPListenerArgs createUDPListener's args, HealthCheckString is required
+type CreateUDPListenerArgs struct {
+	CreateTCPListenerArgs
+	HealthCheckString string `json:"healthCheckString"`
+}
+
+// UpdateTCPListenerArgs updateTCPListener's args, ListenerPort is the listener to update
+type UpdateTCPListenerArgs struct {
+	LoadBalancerId             string `json:"-"`
+	ListenerPort               int    `json:"-"`
+	BackendPort                int    `json:"backendPort,omitempty"`
+	Scheduler                  string `json:"scheduler,omitempty"`
+	HealthCheckType            string `json:"healthCheckType,omitempty"`
+	HealthCheckPort            int    `json:"healthCheckPort"`
+	HealthCheckURI             string `json:"healthCheckURI,omitempty"`
+	HealthCheckTimeoutInSecond int    `json:"healthCheckTimeoutInSecond,omitempty"`
+	HealthCheckInterval        int    `json:"healthCheckInterval,omitempty"`
+	UnhealthyThreshold         int    `json:"unhealthyThreshold,omitempty"`
+	HealthyThreshold           int    `json:"healthyThreshold,omitempty"`
+}
+
+// UpdateUDPListenerArgs updateUDPListener's args, ListenerPort is the listener to update
+type UpdateUDPListenerArgs struct {
+	UpdateTCPListenerArgs
+	HealthCheckString string `json:"healthCheckString,omitempty"`
+}
+
+// DescribeTCPListenerArgs describeTCPListener's args
+type DescribeTCPListenerArgs struct {
+	LoadBalancerId string
+	ListenerPort   int
+}
+
+// DescribeUDPListenerArgs describeUDPListener's args
+type DescribeUDPListenerArgs struct {
+	LoadBalancerId string
+	ListenerPort   int
+}
+
+// DescribeTCPListenerResponse describeTCPListener's response
+type DescribeTCPListenerResponse struct {
+	ListenerList []TCPListener `json:"listenerList"`
+	Marker       string        `json:"marker"`
+	IsTruncated  bool          `json:"isTruncated"`
+	NextMarker   string        `json:"nextMarker"`
+	MaxKeys      int           `json:"maxKeys"`
+}
+
+// DescribeUDPListenerResponse describeUDPListener's response
+type DescribeUDPListenerResponse struct {
+	ListenerList []UDPListener `json:"listenerList"`
+	Marker       string        `json:"marker"`
+	IsTruncated  bool          `json:"isTruncated"`
+	NextMarker   string        `json:"nextMarker"`
+	MaxKeys      int           `json:"maxKeys"`
+}
+
+// HTTPListener is the HTTP listener of BLB
+type HTTPListener struct {
+	ListenerPort               int    `json:"listenerPort"`
+	BackendPort                int    `json:"backendPort"`
+	Scheduler                  string `json:"scheduler"`
+	KeepSession                bool   `json:"keepSession"`
+	KeepSessionType            string `json:"keepSessionType"`
+	KeepSessionDuration        int    `json:"keepSessionDuration"`
+	KeepSessionCookieName      string `json:"keepSessionCookieName"`
+	XForwardFor                bool   `json:"xForwardFor"`
+	HealthCheckType            string `json:"healthCheckType"`
+	HealthCheckPort            int    `json:"healthCheckPort"`
+	HealthCheckURI             string `json:"healthCheckURI"`
+	HealthCheckTimeoutInSecond int    `json:"healthCheckTimeoutInSecond"`
+	HealthCheckInterval        int    `json:"healthCheckInterval"`
+	UnhealthyThreshold         int    `json:"unhealthyThreshold"`
+	HealthyThreshold           int    `json:"healthyThreshold"`
+	HealthCheckNormalStatus    string `json:"healthCheckNormalStatus"`
+	ServerTimeout              int    `json:"serverTimeout"`
+	RedirectPort               int    `json:"redirectPort"`
+}
+
+// HTTPSListener is the HTTPS listener of BLB
+type HTTPSListener struct {
+	HTTPListener
+	CertIds []string `json:"certIds"`
+}
+
+// CreateHTTPListenerArgs createHTTPListener's args, HealthCheckPort 0 means the backend port
+type CreateHTTPListenerArgs struct {
+	LoadBalancerId             string `json:"-"`
+	ListenerPort               int    `json:"listenerPort"`
+	BackendPort                int    `json:"backendPort"`
+	Scheduler                  string `json:"scheduler"`
+	KeepSession                bool   `json:"keepSession,omitempty"`
+	KeepSessionType            string `json:"keepSessionType,omitempty"`
+	KeepSessionDuration        int    `json:"keepSessionDuration,omitempty"`
+	KeepSessionCookieName      string `json:"keepSessionCookieName,omitempty"`
+	XForwardFor                bool   `json:"xForwardFor,omitempty"`
+	HealthCheckType            string `json:"healthCheckType,omitempty"`
+	HealthCheckPort            int    `json:"healthCheckPort,omitempty"`
+	HealthCheckURI             string `json:"healthCheckURI,omitempty"`
+	HealthCheckTimeoutInSecond int    `json:"healthCheckTimeoutInSecond,omitempty"`
+	HealthCheckInterval        int    `json:"healthCheckInterval,omitempty"`
+	UnhealthyThreshold         int    `json:"unhealthyThreshold,omitempty"`
+	HealthyThreshold           int    `json:"healthyThreshold,omitempty"`
+	HealthCheckNormalStatus    string `json:"healthCheckNormalStatus,omitempty"`
+	ServerTimeout              int    `json:"serverTimeout,omitempty"`
+	RedirectPort               int    `json:"redirectPort,omitempty"`
+}
+
+// CreateHTTPSListenerArgs createHTTPSListener's args, CertIds is required
+type CreateHTTPSListenerArgs struct {
+	CreateHTTPListenerArgs
+	CertIds []string `json:"certIds"`
+}
+
+// UpdateHTTPListenerArgs updateHTTPListener's args, ListenerPort is the listener to update
+type UpdateHTTPListenerArgs struct {
+	LoadBalancerId             string `json:"-"`
+	ListenerPort               int    `json:"-"`
+	BackendPort                int    `json:"backendPort,omitempty"`
+	Scheduler                  string `json:"scheduler,omitempty"`
+	KeepSession                bool   `json:"keepSession"`
+	KeepSessionType            string `json:"keepSessionType,omitempty"`
+	KeepSessionDuration        int    `json:"keepSessionDuration,omitempty"`
+	KeepSessionCookieName      string `json:"keepSessionCookieName,omitempty"`
+	XForwardFor                bool   `json:"xForwardFor"`
+	HealthCheckType            string `json:"healthCheckType,omitempty"`
+	HealthCheckPort            int    `json:"healthCheckPort"`
+	HealthCheckURI             string `json:"healthCheckURI,omitempty"`
+	HealthCheckTimeoutInSecond int    `json:"healthCheckTimeoutInSecond,omitempty"`
+	HealthCheckInterval        int    `json:"healthCheckInterval,omitempty"`
+	UnhealthyThreshold         int    `json:"unhealthyThreshold,omitempty"`
+	HealthyThreshold           int    `json:"healthyThreshold,omitempty"`
+	HealthCheckNormalStatus    string `json:"healthCheckNormalStatus,omitempty"`
+	ServerTimeout              int    `json:"serverTimeout,omitempty"`
+	RedirectPort               int    `json:"redirectPort,omitempty"`
+}
+
+// UpdateHTTPSListenerArgs updateHTTPSListener's args, certificates are kept if CertIds is empty
+type UpdateHTTPSListenerArgs struct {
+	UpdateHTTPListenerArgs
+	CertIds []string `json:"certIds,omitempty"`
+}
+
+// DescribeHTTPListenerArgs describeHTTPListener's args
+type DescribeHTTPListenerArgs struct {
+	LoadBalancerId string
+	ListenerPort   int
+}
+
+// DescribeHTTPSListenerArgs describeHTTPSListener's args
+type DescribeHTTPSListenerArgs struct {
+	LoadBalancerId string
+	ListenerPort   int
+}
+
+// DescribeHTTPListenerResponse describeHTTPListener's response
+type DescribeHTTPListenerResponse struct {
+	ListenerList []HTTPListener `json:"listenerList"`
+	Marker       string         `json:"marker"`
+	IsTruncated  bool           `json:"isTruncated"`
+	NextMarker   string         `json:"nextMarker"`
+	MaxKeys      int            `json:"maxKeys"`
+}
+
+// DescribeHTTPSListenerResponse describeHTTPSListener's response
+type DescribeHTTPSListenerResponse struct {
+	ListenerList []HTTPSListener `json:"listenerList"`
+	Marker       string          `json:"marker"`
+	IsTruncated  bool            `json:"isTruncated"`
+	NextMarker   string          `json:"nextMarker"`
+	MaxKeys      int             `json:"maxKeys"`
+}
+
+// DeleteListenersArgs deleteListeners's args, listeners on PortList are deleted whatever their types are,
+// and listeners in PortTypeList are deleted by both port and type
+type DeleteListenersArgs struct {
+	LoadBalancerId string          `json:"-"`
+	PortList       []int           `json:"portList,omitempty"`
+	PortTypeList   []PortTypeModel `json:"portTypeList,omitempty"`
+}
+
+// PortTypeModel is the port and type of a listener
+type PortTypeModel struct {
+	Port int    `json:"port"`
+	Type string `json:"type"`
+}
+
+// SecurityGroupInterface defines the interface of BLB security group Client.
+type SecurityGroupInterface interface {
+	DescribeSecurityGroups(ctx context.Context, args *DescribeSecurityGroupsArgs, option *bce.SignOption) ([]SecurityGroup, error)
+
+	BindSecurityGroups(ctx context.Context, args *SecurityGroupsArgs, option *bce.SignOption) error
+
+	UnbindSecurityGroups(ctx context.Context, args *SecurityGroupsArgs, option *bce.SignOption) error
+}
+
+// SecurityGroup is a security group bound to BLB
+type SecurityGroup struct {
+	SecurityGroupId   string `json:"securityGroupId"`
+	SecurityGroupName string `json:"securityGroupName"`
+	VpcName           string `json:"vpcName"`
+}
+
+// DescribeSecurityGroupsArgs describeSecurityGroups's args
+type DescribeSecurityGroupsArgs struct {
+	LoadBalancerId string
+}
+
+// DescribeSecurityGroupsResponse describeSecurityGroups's response
+type DescribeSecurityGroupsResponse struct {
+	BlbSecurityGroups []SecurityGroup `json:"blbSecurityGroups"`
+}
+
+// SecurityGroupsArgs bindSecurityGroups's and unbindSecurityGroups's args
+type SecurityGroupsArgs struct {
+	LoadBalancerId   string   `json:"-"`
+	SecurityGroupIds []string `json:"securityGroupIds"`
+}
+
+// BackendIPInterface defines the interface of BLB backend ip Client.
+// Backend ips are registered to each listener, such as pods in VPC-native pod networking.
+// The backend ips of a listener are the members of an App BLB ip group named after the listener.
+type BackendIPInterface interface {
+	DescribeBackendIPs(ctx context.Context, args *DescribeBackendIPsArgs, option *bce.SignOption) ([]BackendIP, error)
+
+	AddBackendIPs(ctx context.Context, args *BackendIPsArgs, option *bce.SignOption) error
+
+	RemoveBackendIPs(ctx context.Context, args *BackendIPsArgs, option *bce.SignOption) error
+}
+
+// BackendIP is an ip and port registered to a listener of BLB, MemberId is the id of the ip group member
+type BackendIP struct {
+	MemberId string `json:"memberId,omitempty"`
+	Ip       string `json:"ip"`
+	Port     int    `json:"port"`
+	Weight   int    `json:"weight"`
+}
+
+// DescribeBackendIPsArgs describeBackendIPs's args
+type DescribeBackendIPsArgs struct {
+	LoadBalancerId string
+	ListenerPort   int
+	ListenerType   string
+}
+
+// BackendIPsArgs addBackendIPs's and removeBackendIPs's args
+type BackendIPsArgs struct {
+	LoadBalancerId string
+	ListenerPort   int
+	ListenerType   string
+	BackendIPList  []BackendIP
+}
+
+// IPGroup is an App BLB ip group
+type IPGroup struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	Desc string `json:"desc"`
+}
+
+// CreateIPGroupArgs createIpGroup's args
+type CreateIPGroupArgs struct {
+	Name       string      `json:"name"`
+	Desc       string      `json:"desc,omitempty"`
+	MemberList []BackendIP `json:"memberList,omitempty"`
+}
+
+// DescribeIPGroupsResponse describeIpGroups's response
+type DescribeIPGroupsResponse struct {
+	Marker         string    `json:"marker"`
+	IsTruncated    bool      `json:"isTruncated"`
+	NextMarker     string    `json:"nextMarker"`
+	MaxKeys        int       `json:"maxKeys"`
+	AppIPGroupList []IPGroup `json:"appIpGroupList"`
+}
+
+// IPGroupMembersArgs createIpGroupMember's args
+type IPGroupMembersArgs struct {
+	IPGroupId  string      `json:"ipGroupId"`
+	MemberList []BackendIP `json:"memberList"`
+}
+
+// DeleteIPGroupMembersArgs deleteIpGroupMember's args
+type DeleteIPGroupMembersArgs struct {
+	IPGroupId    string   `json:"ipGroupId"`
+	MemberIdList []string `json:"memberIdList"`
+}
+
+// DescribeIPGroupMembersResponse describeIpGroupMember's response
+type DescribeIPGroupMembersResponse struct {
+	Marker      string      `json:"marker"`
+	IsTruncated bool        `json:"isTruncated"`
+	NextMarker  string      `json:"nextMarker"`
+	MaxKeys     int         `json:"maxKeys"`
+	MemberList  []BackendIP `json:"memberList"`
+}
@@ -0,0 +1,49 @@
+package temp_eip
+
+import (
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+// Endpoint contains all endpoints of Baidu Cloud EIP.
+var Endpoint = map[string]string{
+	"bj":  "eip.bj.baidubce.com",
+	"gz":  "eip.gz.baidubce.com",
+	"su":  "eip.su.baidubce.com",
+	"hkg": "eip.hkg.baidubce.com",
+	"fwh": "eip.fwh.baidubce.com",
+	"bd":  "eip.bd.baidubce.com",
+}
+
+// Config contains all options for EIP group Client.
+type Config struct {
+	*bce.Config
+}
+
+// NewConfig config of EIP group Client
+func NewConfig(config *bce.Config) *Config {
+	return &Config{config}
+}
+
+// Client is the client of Baidu Cloud EIP group, EIP tag and EIP option API, which are not supported by bce-sdk-go yet.
+type Client struct {
+	*bce.Client
+}
+
+// NewClient client of EIP group
+func NewClient(config *Config) *Client {
+	bceClient := bce.NewClient(config.Config)
+	return &Client{bceClient}
+}
+
+// GetURL generates the full URL of http request for Baidu Cloud EIP API.
+func (c *Client) GetURL(objectKey string, params map[string]string) string {
+	host := c.Endpoint
+
+	if host == "" {
+		host = Endpoint[c.GetRegion()]
+	}
+
+	uriPath := objectKey
+
+	return c.Client.GetURL(host, uriPath, params)
+}
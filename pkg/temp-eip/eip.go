@@ -0,0 +1,123 @@
+package temp_eip
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+const (
+	// AutoRenewTimeUnitMonth is the time unit of auto-renew in months
+	AutoRenewTimeUnitMonth = "month"
+	// AutoRenewTimeUnitYear is the time unit of auto-renew in years
+	AutoRenewTimeUnitYear = "year"
+)
+
+// CreateEIP creates an EIP with route type, auto-renew and tags, and returns its address
+func (c *Client) CreateEIP(ctx context.Context, args *CreateEIPArgs, option *bce.SignOption) (string, error) {
+	if args == nil || args.Billing == nil {
+		return "", fmt.Errorf("CreateEIP failed: args or billing is nil")
+	}
+	params := map[string]string{
+		"clientToken": args.ClientToken,
+	}
+	if args.ClientToken == "" {
+		params["clientToken"] = c.GenerateClientToken()
+	}
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	req, err := bce.NewRequest("POST", c.GetURL("v1/eip", params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.SendRequest(ctx, req, option)
+	if err != nil {
+		return "", err
+	}
+	bodyContent, err := resp.GetBodyContent()
+	if err != nil {
+		return "", err
+	}
+	var createResp CreateEIPResponse
+	err = json.Unmarshal(bodyContent, &createResp)
+	if err != nil {
+		return "", err
+	}
+	return createResp.EIP, nil
+}
+
+// GetEIP gets an EIP with its route type and tags, nil if it does not exist
+func (c *Client) GetEIP(ctx context.Context, ip string, option *bce.SignOption) (*EIP, error) {
+	if ip == "" {
+		return nil, fmt.Errorf("GetEIP failed: ip is empty")
+	}
+	req, err := bce.NewRequest("GET", c.GetURL("v1/eip", map[string]string{"eip": ip}), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.SendRequest(ctx, req, option)
+	if err != nil {
+		return nil, err
+	}
+	bodyContent, err := resp.GetBodyContent()
+	if err != nil {
+		return nil, err
+	}
+	listResp := new(ListEIPsResponse)
+	err = json.Unmarshal(bodyContent, listResp)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range listResp.EIPList {
+		if e.EIP == ip {
+			return e, nil
+		}
+	}
+	return nil, nil
+}
+
+// StartEIPAutoRenew renews Prepaid EIP automatically before it expires
+func (c *Client) StartEIPAutoRenew(ctx context.Context, ip string, args *StartEIPAutoRenewArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("StartEIPAutoRenew failed: args is nil")
+	}
+	return c.updateEIP(ctx, ip, "startAutoRenew", args, option)
+}
+
+// StopEIPAutoRenew stops renewing Prepaid EIP automatically
+func (c *Client) StopEIPAutoRenew(ctx context.Context, ip string, option *bce.SignOption) error {
+	return c.updateEIP(ctx, ip, "stopAutoRenew", struct{}{}, option)
+}
+
+// ChangeEIPBilling changes payment timing and billing method of Postpaid EIP in place, the address is kept
+func (c *Client) ChangeEIPBilling(ctx context.Context, ip string, args *ChangeEIPBillingArgs, option *bce.SignOption) error {
+	if args == nil || args.Billing == nil {
+		return fmt.Errorf("ChangeEIPBilling failed: args or billing is nil")
+	}
+	return c.updateEIP(ctx, ip, "changeBilling", args, option)
+}
+
+func (c *Client) updateEIP(ctx context.Context, ip, action string, args interface{}, option *bce.SignOption) error {
+	if ip == "" {
+		return fmt.Errorf("%s of EIP failed: ip is empty", action)
+	}
+	params := map[string]string{
+		action:        "",
+		"clientToken": c.GenerateClientToken(),
+	}
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	req, err := bce.NewRequest("PUT", c.GetURL("v1/eip/"+ip, params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return err
+	}
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
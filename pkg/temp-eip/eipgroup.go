@@ -0,0 +1,161 @@
+package temp_eip
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+// CreateEIPGroup creates an EIP group and returns its id
+func (c *Client) CreateEIPGroup(ctx context.Context, args *CreateEIPGroupArgs, option *bce.SignOption) (string, error) {
+	if args == nil {
+		return "", fmt.Errorf("CreateEIPGroup failed: args is nil")
+	}
+	params := map[string]string{
+		"clientToken": args.ClientToken,
+	}
+	if args.ClientToken == "" {
+		params["clientToken"] = c.GenerateClientToken()
+	}
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	req, err := bce.NewRequest("POST", c.GetURL("v1/eipgroup", params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.SendRequest(ctx, req, option)
+	if err != nil {
+		return "", err
+	}
+	bodyContent, err := resp.GetBodyContent()
+	if err != nil {
+		return "", err
+	}
+	var createResp CreateEIPGroupResponse
+	err = json.Unmarshal(bodyContent, &createResp)
+	if err != nil {
+		return "", err
+	}
+	return createResp.ID, nil
+}
+
+// GetEIPGroup gets an EIP group with the EIPs in it
+func (c *Client) GetEIPGroup(ctx context.Context, id string, option *bce.SignOption) (*EIPGroup, error) {
+	if id == "" {
+		return nil, fmt.Errorf("GetEIPGroup failed: id is empty")
+	}
+	req, err := bce.NewRequest("GET", c.GetURL("v1/eipgroup/"+id, nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.SendRequest(ctx, req, option)
+	if err != nil {
+		return nil, err
+	}
+	bodyContent, err := resp.GetBodyContent()
+	if err != nil {
+		return nil, err
+	}
+	group := new(EIPGroup)
+	err = json.Unmarshal(bodyContent, group)
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// ListEIPGroups lists all EIP groups, filtered by name if it is set
+func (c *Client) ListEIPGroups(ctx context.Context, args *ListEIPGroupsArgs, option *bce.SignOption) ([]*EIPGroup, error) {
+	var groups []*EIPGroup
+	marker := ""
+	for {
+		params := map[string]string{
+			"maxKeys": "1000",
+		}
+		if marker != "" {
+			params["marker"] = marker
+		}
+		if args != nil && args.Name != "" {
+			params["name"] = args.Name
+		}
+		req, err := bce.NewRequest("GET", c.GetURL("v1/eipgroup", params), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.SendRequest(ctx, req, option)
+		if err != nil {
+			return nil, err
+		}
+		bodyContent, err := resp.GetBodyContent()
+		if err != nil {
+			return nil, err
+		}
+		listResp := new(ListEIPGroupsResponse)
+		err = json.Unmarshal(bodyContent, listResp)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, listResp.EIPGroups...)
+		if !listResp.IsTruncated || listResp.NextMarker == "" {
+			return groups, nil
+		}
+		marker = listResp.NextMarker
+	}
+}
+
+// MoveInEIPs moves standalone EIPs into an EIP group, they share the bandwidth of the group afterwards
+func (c *Client) MoveInEIPs(ctx context.Context, id string, args *MoveInEIPsArgs, option *bce.SignOption) error {
+	if id == "" || args == nil {
+		return fmt.Errorf("MoveInEIPs failed: id is empty or args is nil")
+	}
+	return c.updateEIPGroup(ctx, id, "move_in", args.ClientToken, args, option)
+}
+
+// MoveOutEIPs moves EIPs out of an EIP group with the bandwidth and billing of each one
+func (c *Client) MoveOutEIPs(ctx context.Context, id string, args *MoveOutEIPsArgs, option *bce.SignOption) error {
+	if id == "" || args == nil {
+		return fmt.Errorf("MoveOutEIPs failed: id is empty or args is nil")
+	}
+	return c.updateEIPGroup(ctx, id, "move_out", args.ClientToken, args, option)
+}
+
+// DeleteEIPGroup releases an EIP group without EIPs in it
+func (c *Client) DeleteEIPGroup(ctx context.Context, id string, option *bce.SignOption) error {
+	if id == "" {
+		return fmt.Errorf("DeleteEIPGroup failed: id is empty")
+	}
+	params := map[string]string{
+		"clientToken": c.GenerateClientToken(),
+	}
+	req, err := bce.NewRequest("DELETE", c.GetURL("v1/eipgroup/"+id, params), nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
+
+func (c *Client) updateEIPGroup(ctx context.Context, id, action, clientToken string, args interface{}, option *bce.SignOption) error {
+	params := map[string]string{
+		action:        "",
+		"clientToken": clientToken,
+	}
+	if clientToken == "" {
+		params["clientToken"] = c.GenerateClientToken()
+	}
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	req, err := bce.NewRequest("PUT", c.GetURL("v1/eipgroup/"+id, params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return err
+	}
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
@@ -0,0 +1,83 @@
+package temp_eip
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+// BindEIPTags attaches tags to EIP
+func (c *Client) BindEIPTags(ctx context.Context, ip string, args *EIPTagsArgs, option *bce.SignOption) error {
+	return c.changeEIPTags(ctx, ip, "bind", args, option)
+}
+
+// UnbindEIPTags detaches tags from EIP
+func (c *Client) UnbindEIPTags(ctx context.Context, ip string, args *EIPTagsArgs, option *bce.SignOption) error {
+	return c.changeEIPTags(ctx, ip, "unbind", args, option)
+}
+
+func (c *Client) changeEIPTags(ctx context.Context, ip, action string, args *EIPTagsArgs, option *bce.SignOption) error {
+	if ip == "" || args == nil {
+		return fmt.Errorf("%s tags of EIP failed: ip is empty or args is nil", action)
+	}
+	params := map[string]string{
+		action:        "",
+		"clientToken": c.GenerateClientToken(),
+	}
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	req, err := bce.NewRequest("PUT", c.GetURL("v1/eip/"+ip+"/tag", params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return err
+	}
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
+
+// ListEIPsByTag lists EIPs with the tag
+func (c *Client) ListEIPsByTag(ctx context.Context, args *ListEIPsByTagArgs, option *bce.SignOption) ([]*TaggedEIP, error) {
+	if args == nil || args.TagKey == "" {
+		return nil, fmt.Errorf("ListEIPsByTag failed: tag key is empty")
+	}
+	var eips []*TaggedEIP
+	marker := ""
+	for {
+		params := map[string]string{
+			"tagKey":  args.TagKey,
+			"maxKeys": "1000",
+		}
+		if args.TagValue != "" {
+			params["tagValue"] = args.TagValue
+		}
+		if marker != "" {
+			params["marker"] = marker
+		}
+		req, err := bce.NewRequest("GET", c.GetURL("v1/eip", params), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.SendRequest(ctx, req, option)
+		if err != nil {
+			return nil, err
+		}
+		bodyContent, err := resp.GetBodyContent()
+		if err != nil {
+			return nil, err
+		}
+		listResp := new(ListEIPsByTagResponse)
+		err = json.Unmarshal(bodyContent, listResp)
+		if err != nil {
+			return nil, err
+		}
+		eips = append(eips, listResp.EIPList...)
+		if !listResp.IsTruncated || listResp.NextMarker == "" {
+			return eips, nil
+		}
+		marker = listResp.NextMarker
+	}
+}
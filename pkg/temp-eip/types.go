@@ -0,0 +1,212 @@
+package temp_eip
+
+import (
+	"context"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+// Interface defines the interface of EIP group Client.
+// An EIP group shares its bandwidth among the EIPs in it.
+type Interface interface {
+	CreateEIPGroup(ctx context.Context, args *CreateEIPGroupArgs, option *bce.SignOption) (string, error)
+
+	GetEIPGroup(ctx context.Context, id string, option *bce.SignOption) (*EIPGroup, error)
+
+	ListEIPGroups(ctx context.Context, args *ListEIPGroupsArgs, option *bce.SignOption) ([]*EIPGroup, error)
+
+	MoveInEIPs(ctx context.Context, id string, args *MoveInEIPsArgs, option *bce.SignOption) error
+
+	MoveOutEIPs(ctx context.Context, id string, args *MoveOutEIPsArgs, option *bce.SignOption) error
+
+	DeleteEIPGroup(ctx context.Context, id string, option *bce.SignOption) error
+}
+
+// Reservation of Prepaid EIP group
+type Reservation struct {
+	ReservationLength   int    `json:"reservationLength"`
+	ReservationTimeUnit string `json:"reservationTimeUnit"`
+}
+
+// Billing of EIP group, the same as billing of EIP
+type Billing struct {
+	PaymentTiming string       `json:"paymentTiming"`
+	BillingMethod string       `json:"billingMethod,omitempty"`
+	Reservation   *Reservation `json:"reservation,omitempty"`
+}
+
+// CreateEIPGroupArgs createEIPGroup's args
+type CreateEIPGroupArgs struct {
+	Name            string   `json:"name,omitempty"`
+	EIPCount        int      `json:"eipCount"`
+	BandwidthInMbps int      `json:"bandwidthInMbps"`
+	Billing         *Billing `json:"billing"`
+	ClientToken     string   `json:"-"`
+}
+
+// CreateEIPGroupResponse createEIPGroup's response
+type CreateEIPGroupResponse struct {
+	ID string `json:"id"`
+}
+
+// EIPGroup is a group of EIPs sharing bandwidth
+type EIPGroup struct {
+	ID              string      `json:"id"`
+	Name            string      `json:"name"`
+	Status          string      `json:"status"`
+	BandwidthInMbps int         `json:"bandwidthInMbps"`
+	PaymentTiming   string      `json:"paymentTiming"`
+	BillingMethod   string      `json:"billingMethod"`
+	EIPs            []*GroupEIP `json:"eips"`
+}
+
+// GroupEIP is an EIP in group
+type GroupEIP struct {
+	Name         string `json:"name"`
+	EIP          string `json:"eip"`
+	Status       string `json:"status"`
+	InstanceType string `json:"instanceType"`
+	InstanceID   string `json:"instanceId"`
+}
+
+// ListEIPGroupsArgs listEIPGroups's args
+type ListEIPGroupsArgs struct {
+	Name string
+}
+
+// ListEIPGroupsResponse listEIPGroups's response
+type ListEIPGroupsResponse struct {
+	Marker      string      `json:"marker"`
+	IsTruncated bool        `json:"isTruncated"`
+	NextMarker  string      `json:"nextMarker"`
+	MaxKeys     int         `json:"maxKeys"`
+	EIPGroups   []*EIPGroup `json:"eipgroups"`
+}
+
+// MoveInEIPsArgs moveInEIPs's args
+type MoveInEIPsArgs struct {
+	EIPs        []string `json:"eips"`
+	ClientToken string   `json:"-"`
+}
+
+// MoveOutEIP is an EIP moved out of group with standalone bandwidth and billing
+type MoveOutEIP struct {
+	EIP             string   `json:"eip"`
+	BandwidthInMbps int      `json:"bandwidthInMbps"`
+	Billing         *Billing `json:"billing"`
+}
+
+// MoveOutEIPsArgs moveOutEIPs's args
+type MoveOutEIPsArgs struct {
+	MoveOutEIPs []MoveOutEIP `json:"moveOutEips"`
+	ClientToken string       `json:"-"`
+}
+
+// TagInterface defines the interface of EIP tag Client
+type TagInterface interface {
+	BindEIPTags(ctx context.Context, ip string, args *EIPTagsArgs, option *bce.SignOption) error
+
+	UnbindEIPTags(ctx context.Context, ip string, args *EIPTagsArgs, option *bce.SignOption) error
+
+	ListEIPsByTag(ctx context.Context, args *ListEIPsByTagArgs, option *bce.SignOption) ([]*TaggedEIP, error)
+}
+
+// Tag is a key value pair attached to resource
+type Tag struct {
+	TagKey   string `json:"tagKey"`
+	TagValue string `json:"tagValue"`
+}
+
+// EIPTagsArgs bindEIPTags's and unbindEIPTags's args
+type EIPTagsArgs struct {
+	ChangeTags []Tag `json:"changeTags"`
+}
+
+// ListEIPsByTagArgs listEIPsByTag's args, EIPs with the tag key are listed if TagValue is empty
+type ListEIPsByTagArgs struct {
+	TagKey   string
+	TagValue string
+}
+
+// TaggedEIP is an EIP with its tags
+type TaggedEIP struct {
+	Name         string `json:"name"`
+	EIP          string `json:"eip"`
+	Status       string `json:"status"`
+	InstanceType string `json:"instanceType"`
+	InstanceID   string `json:"instanceId"`
+	Tags         []Tag  `json:"tags"`
+}
+
+// ListEIPsByTagResponse listEIPsByTag's response
+type ListEIPsByTagResponse struct {
+	Marker      string       `json:"marker"`
+	IsTruncated bool         `json:"isTruncated"`
+	NextMarker  string       `json:"nextMarker"`
+	MaxKeys     int          `json:"maxKeys"`
+	EIPList     []*TaggedEIP `json:"eipList"`
+}
+
+// OptionInterface defines the interface of EIP Client for the options of EIP not supported by bce-sdk-go,
+// such as route type, auto-renew and billing changed in place
+type OptionInterface interface {
+	CreateEIP(ctx context.Context, args *CreateEIPArgs, option *bce.SignOption) (string, error)
+
+	GetEIP(ctx context.Context, ip string, option *bce.SignOption) (*EIP, error)
+
+	StartEIPAutoRenew(ctx context.Context, ip string, args *StartEIPAutoRenewArgs, option *bce.SignOption) error
+
+	StopEIPAutoRenew(ctx context.Context, ip string, option *bce.SignOption) error
+
+	ChangeEIPBilling(ctx context.Context, ip string, args *ChangeEIPBillingArgs, option *bce.SignOption) error
+}
+
+// CreateEIPArgs createEIP's args
+type CreateEIPArgs struct {
+	Name            string   `json:"name,omitempty"`
+	BandwidthInMbps int      `json:"bandwidthInMbps"`
+	Billing         *Billing `json:"billing"`
+	// RouteType is the line type of EIP, such as BGP and BGP_S
+	RouteType         string `json:"routeType,omitempty"`
+	AutoRenewTimeUnit string `json:"autoRenewTimeUnit,omitempty"`
+	AutoRenewTime     int    `json:"autoRenewTime,omitempty"`
+	Tags              []Tag  `json:"tags,omitempty"`
+	ClientToken       string `json:"-"`
+}
+
+// CreateEIPResponse createEIP's response
+type CreateEIPResponse struct {
+	EIP string `json:"eip"`
+}
+
+// EIP is an EIP with the options not returned by bce-sdk-go
+type EIP struct {
+	Name            string `json:"name"`
+	EIP             string `json:"eip"`
+	Status          string `json:"status"`
+	RouteType       string `json:"routeType"`
+	BandwidthInMbps int    `json:"bandwidthInMbps"`
+	PaymentTiming   string `json:"paymentTiming"`
+	BillingMethod   string `json:"billingMethod"`
+	// AutoRenew is whether Prepaid EIP is renewed automatically by AutoRenewTime AutoRenewTimeUnit
+	AutoRenew         bool   `json:"autoRenew"`
+	AutoRenewTime     int    `json:"autoRenewTime"`
+	AutoRenewTimeUnit string `json:"autoRenewTimeUnit"`
+	Tags              []Tag  `json:"tags"`
+}
+
+// ListEIPsResponse listEIPs's response
+type ListEIPsResponse struct {
+	EIPList []*EIP `json:"eipList"`
+}
+
+// StartEIPAutoRenewArgs startEIPAutoRenew's args, AutoRenewTime is in [1, 9] months or [1, 3] years
+type StartEIPAutoRenewArgs struct {
+	AutoRenewTimeUnit string `json:"autoRenewTimeUnit"`
+	AutoRenewTime     int    `json:"autoRenewTime"`
+}
+
+// ChangeEIPBillingArgs changeEIPBilling's args, only Postpaid EIP can be changed
+type ChangeEIPBillingArgs struct {
+	Billing *Billing `json:"billing"`
+}
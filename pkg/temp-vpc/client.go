@@ -0,0 +1,49 @@
+package temp_vpc
+
+import (
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+// Endpoint contains all endpoints of Baidu Cloud security group, which is served by BCC.
+var Endpoint = map[string]string{
+	"bj":  "bcc.bj.baidubce.com",
+	"gz":  "bcc.gz.baidubce.com",
+	"su":  "bcc.su.baidubce.com",
+	"hkg": "bcc.hkg.baidubce.com",
+	"fwh": "bcc.fwh.baidubce.com",
+	"bd":  "bcc.bd.baidubce.com",
+}
+
+// Config contains all options for security group Client.
+type Config struct {
+	*bce.Config
+}
+
+// NewConfig config of security group Client
+func NewConfig(config *bce.Config) *Config {
+	return &Config{config}
+}
+
+// Client is the client of Baidu Cloud security group API, which is not supported by bce-sdk-go yet.
+type Client struct {
+	*bce.Client
+}
+
+// NewClient client of security group
+func NewClient(config *Config) *Client {
+	bceClient := bce.NewClient(config.Config)
+	return &Client{bceClient}
+}
+
+// GetURL generates the full URL of http request for Baidu Cloud security group API.
+func (c *Client) GetURL(objectKey string, params map[string]string) string {
+	host := c.Endpoint
+
+	if host == "" {
+		host = Endpoint[c.GetRegion()]
+	}
+
+	uriPath := objectKey
+
+	return c.Client.GetURL(host, uriPath, params)
+}
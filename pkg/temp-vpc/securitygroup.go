@@ -0,0 +1,161 @@
+package temp_vpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+// CreateSecurityGroup creates a security group with rules, and returns its id
+func (c *Client) CreateSecurityGroup(ctx context.Context, args *CreateSecurityGroupArgs, option *bce.SignOption) (string, error) {
+	if args == nil || args.Name == "" {
+		return "", fmt.Errorf("CreateSecurityGroup failed: args or name is empty")
+	}
+	params := map[string]string{
+		"clientToken": args.ClientToken,
+	}
+	if args.ClientToken == "" {
+		params["clientToken"] = c.GenerateClientToken()
+	}
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	req, err := bce.NewRequest("POST", c.GetURL("v2/securityGroup", params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.SendRequest(ctx, req, option)
+	if err != nil {
+		return "", err
+	}
+	bodyContent, err := resp.GetBodyContent()
+	if err != nil {
+		return "", err
+	}
+	var createResp CreateSecurityGroupResponse
+	err = json.Unmarshal(bodyContent, &createResp)
+	if err != nil {
+		return "", err
+	}
+	return createResp.SecurityGroupID, nil
+}
+
+// ListSecurityGroup lists all security groups in VpcID page by page
+func (c *Client) ListSecurityGroup(ctx context.Context, args *ListSecurityGroupArgs, option *bce.SignOption) ([]SecurityGroup, error) {
+	if args == nil {
+		return nil, fmt.Errorf("ListSecurityGroup failed: args is nil")
+	}
+	var sgs []SecurityGroup
+	marker := ""
+	for {
+		params := map[string]string{
+			"maxKeys": "1000",
+		}
+		if args.VpcID != "" {
+			params["vpcId"] = args.VpcID
+		}
+		if marker != "" {
+			params["marker"] = marker
+		}
+		req, err := bce.NewRequest("GET", c.GetURL("v2/securityGroup", params), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.SendRequest(ctx, req, option)
+		if err != nil {
+			return nil, err
+		}
+		bodyContent, err := resp.GetBodyContent()
+		if err != nil {
+			return nil, err
+		}
+		var listResp ListSecurityGroupResponse
+		err = json.Unmarshal(bodyContent, &listResp)
+		if err != nil {
+			return nil, err
+		}
+		sgs = append(sgs, listResp.SecurityGroups...)
+		if !listResp.IsTruncated || listResp.NextMarker == "" {
+			return sgs, nil
+		}
+		marker = listResp.NextMarker
+	}
+}
+
+// GetSecurityGroup gets a security group with its rules by id
+func (c *Client) GetSecurityGroup(ctx context.Context, securityGroupID string, option *bce.SignOption) (*SecurityGroup, error) {
+	if securityGroupID == "" {
+		return nil, fmt.Errorf("GetSecurityGroup failed: securityGroupID is empty")
+	}
+	req, err := bce.NewRequest("GET", c.GetURL("v2/securityGroup/"+securityGroupID, nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.SendRequest(ctx, req, option)
+	if err != nil {
+		return nil, err
+	}
+	bodyContent, err := resp.GetBodyContent()
+	if err != nil {
+		return nil, err
+	}
+	var getResp GetSecurityGroupResponse
+	err = json.Unmarshal(bodyContent, &getResp)
+	if err != nil {
+		return nil, err
+	}
+	return &getResp.SecurityGroup, nil
+}
+
+// DeleteSecurityGroup deletes a security group, which must be bound to no instance
+func (c *Client) DeleteSecurityGroup(ctx context.Context, securityGroupID string, option *bce.SignOption) error {
+	if securityGroupID == "" {
+		return fmt.Errorf("DeleteSecurityGroup failed: securityGroupID is empty")
+	}
+	req, err := bce.NewRequest("DELETE", c.GetURL("v2/securityGroup/"+securityGroupID, nil), nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
+
+// AuthorizeSecurityGroupRule adds a rule to security group
+func (c *Client) AuthorizeSecurityGroupRule(ctx context.Context, args *SecurityGroupRuleArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("AuthorizeSecurityGroupRule failed: args is nil")
+	}
+	return c.updateSecurityGroupRule(ctx, "authorizeRule", args, option)
+}
+
+// RevokeSecurityGroupRule removes a rule from security group
+func (c *Client) RevokeSecurityGroupRule(ctx context.Context, args *SecurityGroupRuleArgs, option *bce.SignOption) error {
+	if args == nil {
+		return fmt.Errorf("RevokeSecurityGroupRule failed: args is nil")
+	}
+	return c.updateSecurityGroupRule(ctx, "revokeRule", args, option)
+}
+
+func (c *Client) updateSecurityGroupRule(ctx context.Context, action string, args *SecurityGroupRuleArgs, option *bce.SignOption) error {
+	if args.SecurityGroupID == "" {
+		return fmt.Errorf("%s of security group failed: securityGroupID is empty", action)
+	}
+	params := map[string]string{
+		action:        "",
+		"clientToken": c.GenerateClientToken(),
+	}
+	postContent, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	req, err := bce.NewRequest("PUT", c.GetURL("v2/securityGroup/"+args.SecurityGroupID, params), bytes.NewBuffer(postContent))
+	if err != nil {
+		return err
+	}
+	_, err = c.SendRequest(ctx, req, option)
+	return err
+}
@@ -0,0 +1,82 @@
+package temp_vpc
+
+import (
+	"context"
+
+	"icode.baidu.com/baidu/jpaas-caas/bce-sdk-go/bce"
+)
+
+// SecurityGroupInterface defines the interface of security group Client.
+type SecurityGroupInterface interface {
+	CreateSecurityGroup(ctx context.Context, args *CreateSecurityGroupArgs, option *bce.SignOption) (string, error)
+
+	ListSecurityGroup(ctx context.Context, args *ListSecurityGroupArgs, option *bce.SignOption) ([]SecurityGroup, error)
+
+	GetSecurityGroup(ctx context.Context, securityGroupID string, option *bce.SignOption) (*SecurityGroup, error)
+
+	DeleteSecurityGroup(ctx context.Context, securityGroupID string, option *bce.SignOption) error
+
+	AuthorizeSecurityGroupRule(ctx context.Context, args *SecurityGroupRuleArgs, option *bce.SignOption) error
+
+	RevokeSecurityGroupRule(ctx context.Context, args *SecurityGroupRuleArgs, option *bce.SignOption) error
+}
+
+// SecurityGroupRule is an ingress or egress rule of security group
+type SecurityGroupRule struct {
+	SecurityGroupRuleID string `json:"securityGroupRuleId,omitempty"`
+	Remark              string `json:"remark,omitempty"`
+	Direction           string `json:"direction"`
+	Ethertype           string `json:"ethertype,omitempty"`
+	PortRange           string `json:"portRange,omitempty"`
+	Protocol            string `json:"protocol,omitempty"`
+	SourceIP            string `json:"sourceIp,omitempty"`
+	DestIP              string `json:"destIp,omitempty"`
+}
+
+// SecurityGroup is a security group with its rules
+type SecurityGroup struct {
+	ID    string              `json:"id"`
+	Name  string              `json:"name"`
+	VpcID string              `json:"vpcId"`
+	Desc  string              `json:"desc"`
+	Rules []SecurityGroupRule `json:"rules"`
+}
+
+// CreateSecurityGroupArgs createSecurityGroup's args
+type CreateSecurityGroupArgs struct {
+	Name        string              `json:"name"`
+	VpcID       string              `json:"vpcId,omitempty"`
+	Desc        string              `json:"desc,omitempty"`
+	Rules       []SecurityGroupRule `json:"rules"`
+	ClientToken string              `json:"-"`
+}
+
+// CreateSecurityGroupResponse createSecurityGroup's response
+type CreateSecurityGroupResponse struct {
+	SecurityGroupID string `json:"securityGroupId"`
+}
+
+// ListSecurityGroupArgs listSecurityGroup's args, security groups of all VPCs are listed if VpcID is empty
+type ListSecurityGroupArgs struct {
+	VpcID string
+}
+
+// ListSecurityGroupResponse listSecurityGroup's response
+type ListSecurityGroupResponse struct {
+	SecurityGroups []SecurityGroup `json:"securityGroups"`
+	Marker         string          `json:"marker"`
+	IsTruncated    bool            `json:"isTruncated"`
+	NextMarker     string          `json:"nextMarker"`
+	MaxKeys        int             `json:"maxKeys"`
+}
+
+// GetSecurityGroupResponse getSecurityGroup's response
+type GetSecurityGroupResponse struct {
+	SecurityGroup SecurityGroup `json:"securityGroup"`
+}
+
+// SecurityGroupRuleArgs authorizeSecurityGroupRule's and revokeSecurityGroupRule's args
+type SecurityGroupRuleArgs struct {
+	SecurityGroupID string            `json:"-"`
+	Rule            SecurityGroupRule `json:"rule"`
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"k8s.io/api/admission/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+const (
+	// ValidatePath is the path of the validating webhook
+	ValidatePath = "/validate"
+	// MutatePath is the path of the mutating webhook
+	MutatePath = "/mutate"
+
+	// max size of an AdmissionReview accepted
+	maxRequestSize = 3 * 1024 * 1024
+)
+
+// Admitter is implemented by cloud providers which check Services at admission
+type Admitter interface {
+	// ValidateService returns why service would fail to get a load balancer, nothing is created,
+	// oldService is nil on create
+	ValidateService(ctx context.Context, service, oldService *v1.Service) error
+	// DefaultService returns annotations of service with defaults written, nil if nothing changes,
+	// oldService is nil on create
+	DefaultService(service, oldService *v1.Service) (map[string]string, error)
+}
+
+// Server serves the validating and mutating webhooks of Services
+type Server struct {
+	admitter Admitter
+	// writeDefaults is whether defaulted annotations are written back by the mutating webhook
+	writeDefaults bool
+}
+
+// NewServer returns a Server
+func NewServer(admitter Admitter, writeDefaults bool) *Server {
+	return &Server{
+		admitter:      admitter,
+		writeDefaults: writeDefaults,
+	}
+}
+
+// Handler returns the http handler of the webhooks
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(ValidatePath, func(w http.ResponseWriter, r *http.Request) {
+		s.serve(w, r, s.validate)
+	})
+	mux.HandleFunc(MutatePath, func(w http.ResponseWriter, r *http.Request) {
+		s.serve(w, r, s.mutate)
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	return mux
+}
+
+// Run serves the webhooks by https on addr until stopCh is closed
+func (s *Server) Run(addr, certFile, keyFile string, stopCh <-chan struct{}) error {
+	server := &http.Server{
+		Addr:    addr,
+		Handler: s.Handler(),
+	}
+	go func() {
+		<-stopCh
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+	klog.Infof("Starting service admission webhook on %s, write defaults: %v", addr, s.writeDefaults)
+	if err := server.ListenAndServeTLS(certFile, keyFile); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+type admitFunc func(request *v1beta1.AdmissionRequest) *v1beta1.AdmissionResponse
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, admit admitFunc) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if contentType := r.Header.Get("Content-Type"); contentType != "application/json" {
+		http.Error(w, fmt.Sprintf("content type %q is not application/json", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("read request failed: %v", err), http.StatusBadRequest)
+		return
+	}
+	review := v1beta1.AdmissionReview{}
+	if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+		http.Error(w, fmt.Sprintf("request is not an AdmissionReview: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// admission.k8s.io/v1 has the same fields, the version of request is kept in response
+	response := admit(review.Request)
+	response.UID = review.Request.UID
+	review.Request = nil
+	review.Response = response
+	data, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("encode response failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (s *Server) validate(request *v1beta1.AdmissionRequest) *v1beta1.AdmissionResponse {
+	if request.Kind.Kind != "Service" || request.Operation == v1beta1.Delete {
+		return allowed()
+	}
+	service, err := decodeService(request.Object.Raw)
+	if err != nil {
+		return denied(metav1.StatusReasonBadRequest, http.StatusBadRequest, err.Error())
+	}
+	var oldService *v1.Service
+	if len(request.OldObject.Raw) != 0 {
+		if oldService, err = decodeService(request.OldObject.Raw); err != nil {
+			return denied(metav1.StatusReasonBadRequest, http.StatusBadRequest, err.Error())
+		}
+	}
+	if err := s.admitter.ValidateService(context.TODO(), service, oldService); err != nil {
+		klog.Infof("reject service %s/%s: %v", request.Namespace, request.Name, err)
+		return denied(metav1.StatusReasonInvalid, http.StatusUnprocessableEntity,
+			fmt.Sprintf("service %s/%s is invalid: %v", request.Namespace, service.Name, err))
+	}
+	return allowed()
+}
+
+// mutate never denies a request, invalid services are denied by validate
+func (s *Server) mutate(request *v1beta1.AdmissionRequest) *v1beta1.AdmissionResponse {
+	if !s.writeDefaults || request.Kind.Kind != "Service" || request.Operation == v1beta1.Delete {
+		return allowed()
+	}
+	service, err := decodeService(request.Object.Raw)
+	if err != nil {
+		klog.Warningf("skip defaulting service %s/%s: %v", request.Namespace, request.Name, err)
+		return allowed()
+	}
+	var oldService *v1.Service
+	if len(request.OldObject.Raw) != 0 {
+		if oldService, err = decodeService(request.OldObject.Raw); err != nil {
+			klog.Warningf("skip defaulting service %s/%s: %v", request.Namespace, request.Name, err)
+			return allowed()
+		}
+	}
+	annotations, err := s.admitter.DefaultService(service, oldService)
+	if err != nil {
+		klog.V(3).Infof("skip defaulting service %s/%s: %v", request.Namespace, request.Name, err)
+		return allowed()
+	}
+	if annotations == nil {
+		return allowed()
+	}
+	// add replaces the annotations if they exist
+	patch, err := json.Marshal([]map[string]interface{}{{
+		"op":    "add",
+		"path":  "/metadata/annotations",
+		"value": annotations,
+	}})
+	if err != nil {
+		klog.Warningf("skip defaulting service %s/%s: %v", request.Namespace, request.Name, err)
+		return allowed()
+	}
+	patchType := v1beta1.PatchTypeJSONPatch
+	response := allowed()
+	response.Patch = patch
+	response.PatchType = &patchType
+	return response
+}
+
+func decodeService(raw []byte) (*v1.Service, error) {
+	service := &v1.Service{}
+	if err := json.Unmarshal(raw, service); err != nil {
+		return nil, fmt.Errorf("decode service failed: %v", err)
+	}
+	return service, nil
+}
+
+func allowed() *v1beta1.AdmissionResponse {
+	return &v1beta1.AdmissionResponse{Allowed: true}
+}
+
+func denied(reason metav1.StatusReason, code int32, message string) *v1beta1.AdmissionResponse {
+	return &v1beta1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  reason,
+			Code:    code,
+			Message: message,
+		},
+	}
+}
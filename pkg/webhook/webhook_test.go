@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/api/admission/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type fakeAdmitter struct{}
+
+func (a *fakeAdmitter) ValidateService(ctx context.Context, service, oldService *v1.Service) error {
+	if oldService != nil && oldService.Annotations["invalid"] == service.Annotations["invalid"] {
+		return nil
+	}
+	if service.Annotations["invalid"] != "" {
+		return fmt.Errorf("annotation invalid is set")
+	}
+	return nil
+}
+
+func (a *fakeAdmitter) DefaultService(service, oldService *v1.Service) (map[string]string, error) {
+	if _, ok := service.Annotations["default"]; ok {
+		return nil, nil
+	}
+	annotations := map[string]string{"default": "true"}
+	for k, v := range service.Annotations {
+		annotations[k] = v
+	}
+	return annotations, nil
+}
+
+// review sends service to handler, it is an update from oldService if oldService is not nil
+func review(t *testing.T, handler http.Handler, path string, service, oldService *v1.Service) *v1beta1.AdmissionResponse {
+	raw, err := json.Marshal(service)
+	if err != nil {
+		t.Fatalf("marshal service failed: %v", err)
+	}
+	operation := v1beta1.Create
+	var oldRaw []byte
+	if oldService != nil {
+		operation = v1beta1.Update
+		if oldRaw, err = json.Marshal(oldService); err != nil {
+			t.Fatalf("marshal old service failed: %v", err)
+		}
+	}
+	body, err := json.Marshal(v1beta1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1beta1", Kind: "AdmissionReview"},
+		Request: &v1beta1.AdmissionRequest{
+			UID:       types.UID("uid"),
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Service"},
+			Namespace: service.Namespace,
+			Name:      service.Name,
+			Operation: operation,
+			Object:    runtime.RawExtension{Raw: raw},
+			OldObject: runtime.RawExtension{Raw: oldRaw},
+		},
+	})
+	if err != nil {
+		t.Fatalf("marshal review failed: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	result := v1beta1.AdmissionReview{}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("unmarshal response failed: %v", err)
+	}
+	if result.Response == nil || result.Response.UID != "uid" {
+		t.Fatalf("unexpected response: %+v", result.Response)
+	}
+	if result.APIVersion != "admission.k8s.io/v1beta1" {
+		t.Errorf("expected apiVersion of request, got %s", result.APIVersion)
+	}
+	return result.Response
+}
+
+func TestValidate(t *testing.T) {
+	handler := NewServer(&fakeAdmitter{}, false).Handler()
+	service := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+	if response := review(t, handler, ValidatePath, service, nil); !response.Allowed {
+		t.Errorf("valid service should be allowed: %+v", response.Result)
+	}
+	service.Annotations = map[string]string{"invalid": "true"}
+	response := review(t, handler, ValidatePath, service, nil)
+	if response.Allowed || response.Result == nil || response.Result.Reason != metav1.StatusReasonInvalid {
+		t.Errorf("invalid service should be denied: %+v", response)
+	}
+	// the old object is passed to admitter on update
+	if response := review(t, handler, ValidatePath, service, service.DeepCopy()); !response.Allowed {
+		t.Errorf("unchanged service should be allowed: %+v", response.Result)
+	}
+}
+
+func TestMutate(t *testing.T) {
+	service := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+	response := review(t, NewServer(&fakeAdmitter{}, false).Handler(), MutatePath, service, nil)
+	if !response.Allowed || response.Patch != nil {
+		t.Errorf("service should not be patched without write defaults: %+v", response)
+	}
+
+	response = review(t, NewServer(&fakeAdmitter{}, true).Handler(), MutatePath, service, nil)
+	if !response.Allowed || response.PatchType == nil || *response.PatchType != v1beta1.PatchTypeJSONPatch {
+		t.Fatalf("service should be patched: %+v", response)
+	}
+	expected := `[{"op":"add","path":"/metadata/annotations","value":{"default":"true"}}]`
+	if string(response.Patch) != expected {
+		t.Errorf("expected patch %s, got %s", expected, response.Patch)
+	}
+
+	service.Annotations = map[string]string{"default": "false"}
+	response = review(t, NewServer(&fakeAdmitter{}, true).Handler(), MutatePath, service, nil)
+	if !response.Allowed || response.Patch != nil {
+		t.Errorf("service should not be patched if nothing changes: %+v", response)
+	}
+}
+
+func TestServeBadRequest(t *testing.T) {
+	handler := NewServer(&fakeAdmitter{}, true).Handler()
+	for _, c := range []struct {
+		method      string
+		contentType string
+		body        string
+		code        int
+	}{
+		{http.MethodGet, "application/json", "{}", http.StatusMethodNotAllowed},
+		{http.MethodPost, "text/plain", "{}", http.StatusUnsupportedMediaType},
+		{http.MethodPost, "application/json", "{}", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(c.method, ValidatePath, bytes.NewReader([]byte(c.body)))
+		req.Header.Set("Content-Type", c.contentType)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != c.code {
+			t.Errorf("%s %s %s: expected status %d, got %d", c.method, c.contentType, c.body, c.code, w.Code)
+		}
+	}
+}